@@ -14,9 +14,11 @@ import (
 	"github.com/a2aproject/a2a-go/a2a"
 	"github.com/a2aproject/a2a-go/a2aclient"
 	"github.com/a2aproject/a2a-go/a2asrv"
+	"github.com/user/research-assistant/internal/agent"
 	"github.com/user/research-assistant/internal/agent/concierge"
 	"github.com/user/research-assistant/internal/config"
 	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/prompts"
 	"github.com/user/research-assistant/internal/pubsub"
 	"github.com/user/research-assistant/internal/storage"
 )
@@ -76,7 +78,7 @@ func main() {
 		PreferredTransport: a2a.TransportProtocol("JSONRPC"),
 		ProtocolVersion:    "0.2.2",
 	}
-	researchStream := func(sctx context.Context, topic string, contextID string) iter.Seq2[a2a.Event, error] {
+	researchStream := func(sctx context.Context, topic string, options map[string]any, contextID string) iter.Seq2[a2a.Event, error] {
 		return func(yield func(a2a.Event, error) bool) {
 			client, err := a2aclient.NewFromCard(sctx, researcherCard)
 			if err != nil {
@@ -84,6 +86,9 @@ func main() {
 				return
 			}
 			msg := a2a.NewMessage(a2a.MessageRoleUser, a2a.TextPart{Text: topic})
+			if len(options) > 0 {
+				msg.Parts = append(msg.Parts, a2a.DataPart{Data: options})
+			}
 			msg.ContextID = contextID
 			params := &a2a.MessageSendParams{Message: msg}
 			for ev, err := range client.SendStreamingMessage(sctx, params) {
@@ -94,6 +99,32 @@ func main() {
 		}
 	}
 
+	cancelResearch := func(cctx context.Context, taskID a2a.TaskID, contextID string) error {
+		client, err := a2aclient.NewFromCard(cctx, researcherCard)
+		if err != nil {
+			return fmt.Errorf("create researcher client: %w", err)
+		}
+		if taskID != "" {
+			_, err = client.CancelTask(cctx, &a2a.TaskIDParams{ID: taskID})
+			return err
+		}
+		// Canceled before the Researcher's first event: find its task by
+		// the context the request was sent under.
+		resp, err := client.ListTasks(cctx, &a2a.ListTasksRequest{ContextID: contextID})
+		if err != nil {
+			return fmt.Errorf("list researcher tasks: %w", err)
+		}
+		for _, task := range resp.Tasks {
+			if task.Status.State.Terminal() {
+				continue
+			}
+			if _, err := client.CancelTask(cctx, &a2a.TaskIDParams{ID: task.ID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	artifactDir := config.GetEnv("ARTIFACTS_DIR", "artifacts")
 	blobStore, err := storage.NewDiskBlobStore(artifactDir)
 	if err != nil {
@@ -101,21 +132,31 @@ func main() {
 	}
 
 	exec := concierge.New(gemini, dbStore, researchStream, ps, blobStore)
+	exec.SetResearchCanceler(cancelResearch)
+	if dir := config.GetEnv("PROMPTS_DIR", ""); dir != "" {
+		registry, err := prompts.Load(dir)
+		if err != nil {
+			log.Fatalf("[CONCIERGE] Failed to load prompts from %s: %v", dir, err)
+		}
+		exec.SetPrompts(registry)
+		log.Printf("[CONCIERGE] Loaded prompts from %s: %v", dir, registry.Versions())
+	}
 	card := &a2a.AgentCard{
 		Name:               "Research Assistant — Concierge",
 		Description:        "User-facing research agent: accepts research topics, coordinates with the Researcher, relays live status updates, and answers follow-up questions grounded in completed research.",
 		URL:                "http://localhost" + addr,
 		Version:            "0.1.0",
 		ProtocolVersion:    "0.2.2",
-		Capabilities:       a2a.AgentCapabilities{Streaming: true},
+		Capabilities:       a2a.AgentCapabilities{Streaming: true, Extensions: []a2a.AgentExtension{agent.ResearchOptionsExtension()}},
 		DefaultInputModes:  []string{"text/plain"},
 		DefaultOutputModes: []string{"text/plain", "application/json"},
 		Skills: []a2a.AgentSkill{
 			{
 				ID:          "research",
 				Name:        "Research Topic",
-				Description: "Kick off research on a topic and receive live status updates.",
-				InputModes:  []string{"text/plain"},
+				Description: "Kick off research on a topic and receive live status updates. Accepts an optional research options DataPart (see the research-options extension).",
+				InputModes:  []string{"text/plain", "application/json"},
+				Examples:    []string{"The future of Go concurrency", agent.ResearchOptionsExample},
 				OutputModes: []string{"application/json"},
 			},
 			{
@@ -109,8 +109,8 @@ func main() {
 		}
 	}()
 
-	searchFn := func(ctx context.Context, query string) ([]pipeline.SearchResult, error) {
-		return []pipeline.SearchResult{{Content: "Mock content for " + query, URL: "http://test.com/" + query}}, nil
+	searchFn := func(ctx context.Context, query string, _ pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
+		return []pipeline.SearchResult{{Content: "Mock content for " + query, URL: "http://test.com/" + query, Title: "Mock result for " + query, Provider: "mock"}}, nil
 	}
 
 	// 2. Start Researcher Agent
@@ -135,7 +135,7 @@ func main() {
 
 	// 3. Start Concierge Agent
 	researcherCard := &a2a.AgentCard{URL: resAddr, PreferredTransport: "JSONRPC", ProtocolVersion: "0.2.2"}
-	researchStream := func(sctx context.Context, topic string, contextID string) iter.Seq2[a2a.Event, error] {
+	researchStream := func(sctx context.Context, topic string, options map[string]any, contextID string) iter.Seq2[a2a.Event, error] {
 		return func(yield func(a2a.Event, error) bool) {
 			log.Printf("[E2E] Concierge calling Researcher for topic %q", topic)
 			client, err := a2aclient.NewFromCard(sctx, researcherCard)
@@ -145,6 +145,9 @@ func main() {
 				return
 			}
 			msg := a2a.NewMessage(a2a.MessageRoleUser, a2a.TextPart{Text: topic})
+			if len(options) > 0 {
+				msg.Parts = append(msg.Parts, a2a.DataPart{Data: options})
+			}
 			msg.ContextID = contextID
 			params := &a2a.MessageSendParams{Message: msg}
 			log.Printf("[E2E] Sending streaming message to Researcher...")
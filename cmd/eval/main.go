@@ -0,0 +1,69 @@
+// Command eval runs a golden set of topics through the research pipeline
+// offline, with fake or replayed providers, scores every report and compares
+// the scores against a saved baseline.
+//
+//	go run ./cmd/eval                      # score eval/golden.json against eval/baseline.json
+//	go run ./cmd/eval -update-baseline     # save the current scores as the baseline
+//
+// The scores are written to stdout as JSON. The command exits with status 1
+// when a metric regressed beyond the tolerance.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/user/research-assistant/internal/eval"
+)
+
+type output struct {
+	*eval.Report
+	Comparison *eval.Comparison `json:"comparison,omitempty"`
+}
+
+func main() {
+	golden := flag.String("golden", "eval/golden.json", "golden set of cases to run")
+	baseline := flag.String("baseline", "eval/baseline.json", "baseline report to compare against; skipped when missing")
+	update := flag.Bool("update-baseline", false, "write the current report to -baseline instead of comparing")
+	tolerance := flag.Float64("tolerance", 0.05, "largest change in a metric's worse direction that is not a regression")
+	flag.Parse()
+
+	set, err := eval.LoadGoldenSet(*golden)
+	if err != nil {
+		log.Fatalf("[EVAL] Failed to load golden set: %v", err)
+	}
+	report, err := eval.Run(context.Background(), set)
+	if err != nil {
+		log.Fatalf("[EVAL] %v", err)
+	}
+	out := output{Report: report}
+
+	if *update {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*baseline, append(data, '\n'), 0o644); err != nil {
+			log.Fatalf("[EVAL] Failed to write baseline: %v", err)
+		}
+		log.Printf("[EVAL] Baseline written to %s", *baseline)
+	} else if base, err := eval.LoadReport(*baseline); err == nil {
+		cmp := eval.Compare(base, report, *tolerance)
+		out.Comparison = &cmp
+	} else if errors.Is(err, os.ErrNotExist) {
+		log.Printf("[EVAL] No baseline at %s, skipping comparison", *baseline)
+	} else {
+		log.Fatalf("[EVAL] Failed to load baseline: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		log.Fatalf("[EVAL] Failed to write report: %v", err)
+	}
+	if out.Comparison != nil && out.Comparison.Regressions > 0 {
+		log.Printf("[EVAL] %d regression(s) against %s", out.Comparison.Regressions, *baseline)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,109 @@
+// Command replay re-runs a recorded research session offline from its
+// cassette and prints the resulting report.
+//
+//	go run ./cmd/replay artifacts/cassette-<session>-<timestamp>.json
+//
+// LLM, search and fetch calls are answered from the cassette; calls it has no
+// entry for fail as they would against a failing service. Session data and
+// artifacts are written to a temporary directory, which is kept and printed
+// so the replayed report.json can be inspected.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/user/research-assistant/internal/cassette"
+	"github.com/user/research-assistant/internal/config"
+	"github.com/user/research-assistant/internal/credibility"
+	"github.com/user/research-assistant/internal/moderation"
+	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/storage"
+)
+
+func main() {
+	config.LoadEnv()
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: replay <cassette.json>")
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(os.Args[1])
+	if err != nil {
+		log.Fatalf("[REPLAY] Failed to read cassette: %v", err)
+	}
+	c, err := cassette.Load(data)
+	if err != nil {
+		log.Fatalf("[REPLAY] %v", err)
+	}
+	log.Printf("[REPLAY] Session %s recorded %s: %d LLM, %d search and %d fetch calls",
+		c.SessionID, c.RecordedAt.Format("2006-01-02 15:04:05"), len(c.LLM), len(c.Search), len(c.Fetch))
+
+	dir, err := os.MkdirTemp("", "replay-")
+	if err != nil {
+		log.Fatalf("[REPLAY] Failed to create output dir: %v", err)
+	}
+	dbStore, err := storage.NewSQLiteStore(filepath.Join(dir, "research.db"))
+	if err != nil {
+		log.Fatalf("[REPLAY] Failed to init SQLite store: %v", err)
+	}
+	defer func(dbStore *storage.SQLiteStore) {
+		err := dbStore.Close()
+		if err != nil {
+
+		}
+	}(dbStore)
+	blobDir := filepath.Join(dir, "artifacts")
+	blobStore, err := storage.NewDiskBlobStore(blobDir)
+	if err != nil {
+		log.Fatalf("[REPLAY] Failed to init blob store: %v", err)
+	}
+
+	player := c.Replay()
+	pl := pipeline.New(player.LLM(), player.Search(), dbStore, blobStore)
+	if len(c.Fetch) > 0 {
+		pl.SetFetcher(player.Fetch())
+	}
+	// The session's options, policies and prompts are restored from the
+	// cassette, so that the replay makes the calls the recorded session
+	// made. Cassettes recorded without them fall back to the Researcher's
+	// environment defaults.
+	if c.Settings == nil {
+		log.Printf("[REPLAY] Cassette has no settings, using the environment")
+		opts := pipeline.DefaultOptions()
+		opts.MaxRounds = config.GetEnvInt("RESEARCH_MAX_ROUNDS", opts.MaxRounds)
+		opts.ConfidenceTarget = config.GetEnvFloat("RESEARCH_CONFIDENCE_TARGET", opts.ConfidenceTarget)
+		pl.SetOptions(opts)
+		policy := moderation.DefaultPolicy()
+		policy.Classify = config.GetEnvBool("MODERATION_CLASSIFIER", true)
+		pl.SetModeration(policy)
+		pl.SetCredibility(credibility.PolicyFromEnv())
+	}
+	if err := c.Configure(pl); err != nil {
+		log.Fatalf("[REPLAY] %v", err)
+	}
+
+	result, err := pl.RunWithOptions(context.Background(), c.SessionID, c.Topic, c.Options, func(status, detail string) {
+		if status == pipeline.StatusReportChunk || status == pipeline.StatusReportRestart || status == pipeline.StatusReportDone {
+			return
+		}
+		log.Printf("[REPLAY] %s %s", status, detail)
+	})
+	if err != nil {
+		log.Fatalf("[REPLAY] Pipeline failed: %v", err)
+	}
+	if len(result.SkippedStages) > 0 {
+		log.Printf("[REPLAY] Skipped stages: %s", strings.Join(result.SkippedStages, ", "))
+	}
+
+	report, err := os.ReadFile(filepath.Join(blobDir, result.ReportMDKey))
+	if err != nil {
+		log.Fatalf("[REPLAY] Failed to read report: %v", err)
+	}
+	log.Printf("[REPLAY] Artifacts written to %s", blobDir)
+	fmt.Println(string(report))
+}
@@ -8,15 +8,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
+	"time"
 
 	"github.com/a2aproject/a2a-go/a2a"
 	"github.com/a2aproject/a2a-go/a2asrv"
+	"github.com/user/research-assistant/internal/agent"
 	"github.com/user/research-assistant/internal/agent/researcher"
+	"github.com/user/research-assistant/internal/cassette"
 	"github.com/user/research-assistant/internal/config"
+	"github.com/user/research-assistant/internal/credibility"
+	"github.com/user/research-assistant/internal/fetch"
 	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/moderation"
 	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/prompts"
 	"github.com/user/research-assistant/internal/pubsub"
 	"github.com/user/research-assistant/internal/search"
 	"github.com/user/research-assistant/internal/storage"
@@ -70,27 +76,123 @@ func main() {
 		log.Fatalf("[RESEARCHER] Failed to init blob store: %v", err)
 	}
 
-	searchFn := func(sctx context.Context, query string) ([]pipeline.SearchResult, error) {
+	searchFn := func(sctx context.Context, query string, opts pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
 		if cseKey == "" || cseCx == "" {
 			return nil, fmt.Errorf("CSE not configured (set CSE_API_KEY and CSE_CX)")
 		}
-		items, err := search.ContentWebSearch(sctx, cseKey, cseCx, query, search.Options{Num: 3})
+		items, err := search.ContentWebSearch(sctx, cseKey, cseCx, query, search.Options{
+			Num:          opts.Num,
+			Language:     opts.Language,
+			IncludeSites: opts.IncludeDomains,
+			ExcludeSites: opts.ExcludeDomains,
+			MaxAge:       opts.MaxAge,
+		})
 		if err != nil {
 			return nil, err
 		}
 		out := make([]pipeline.SearchResult, 0, len(items))
 		for _, r := range items {
-			var sb strings.Builder
-			if r.Snippet != "" {
-				sb.WriteString(r.Snippet)
-			}
-			out = append(out, pipeline.SearchResult{Content: sb.String(), URL: r.Link})
+			out = append(out, pipeline.SearchResult{
+				Content:   r.Snippet,
+				URL:       r.Link,
+				Title:     r.Title,
+				Provider:  "google_cse",
+				Published: fetch.PublishedFromMeta(r.Metatags()),
+			})
 		}
 		return out, nil
 	}
 
-	pl := pipeline.New(gemini, searchFn, dbStore, blobStore)
-	exec := researcher.New(pl, ps)
+	if ttl := config.GetEnvInt("SEARCH_CACHE_TTL_MINUTES", 1440); ttl > 0 {
+		searchFn = pipeline.CachedSearch(searchFn, dbStore, pipeline.CacheOptions{
+			TTL:        time.Duration(ttl) * time.Minute,
+			MaxEntries: config.GetEnvInt("SEARCH_CACHE_MAX_ENTRIES", 10000),
+		})
+	}
+
+	// With cassettes enabled, every session's LLM, search and fetch calls
+	// are recorded and saved to the blob store for offline replay.
+	recordCassettes := config.GetEnvBool("RESEARCH_RECORD_CASSETTES", false)
+	var lm pipeline.LLMClient = gemini
+	if recordCassettes {
+		lm = cassette.LLM(gemini)
+		searchFn = cassette.Search(searchFn)
+	}
+
+	pl := pipeline.New(lm, searchFn, dbStore, blobStore)
+	opts := pipeline.DefaultOptions()
+	opts.MaxRounds = config.GetEnvInt("RESEARCH_MAX_ROUNDS", opts.MaxRounds)
+	opts.ConfidenceTarget = config.GetEnvFloat("RESEARCH_CONFIDENCE_TARGET", opts.ConfidenceTarget)
+	opts.TokenBudget = config.GetEnvInt("RESEARCH_TOKEN_BUDGET", opts.TokenBudget)
+	opts.Deadline = time.Duration(config.GetEnvInt("RESEARCH_DEADLINE_SECONDS", 0)) * time.Second
+	pl.SetOptions(opts)
+	pl.SetPricing(pipeline.Pricing{
+		InputPerMTok:  config.GetEnvFloat("LLM_INPUT_USD_PER_MTOK", 0.30),
+		OutputPerMTok: config.GetEnvFloat("LLM_OUTPUT_USD_PER_MTOK", 2.50),
+	})
+	policy := moderation.DefaultPolicy()
+	if names := config.GetEnvList("MODERATION_CATEGORIES"); len(names) > 0 {
+		categories, err := moderation.Select(names)
+		if err != nil {
+			log.Fatalf("[RESEARCHER] Invalid MODERATION_CATEGORIES: %v", err)
+		}
+		policy.Categories = categories
+	}
+	policy.Allow = config.GetEnvList("MODERATION_ALLOW")
+	policy.Deny = config.GetEnvList("MODERATION_DENY")
+	policy.Classify = config.GetEnvBool("MODERATION_CLASSIFIER", true)
+	pl.SetModeration(policy)
+	pl.SetCredibility(credibility.PolicyFromEnv())
+	if dir := config.GetEnv("PROMPTS_DIR", ""); dir != "" {
+		registry, err := prompts.Load(dir)
+		if err != nil {
+			log.Fatalf("[RESEARCHER] Failed to load prompts from %s: %v", dir, err)
+		}
+		pl.SetPrompts(registry)
+		log.Printf("[RESEARCHER] Loaded prompts from %s: %v", dir, registry.Versions())
+	}
+	if config.GetEnvBool("RESEARCH_FETCH_PAGES", true) {
+		fetchOpts := fetch.Options{
+			Timeout:   time.Duration(config.GetEnvInt("FETCH_TIMEOUT_SECONDS", 10)) * time.Second,
+			MaxBytes:  int64(config.GetEnvInt("FETCH_MAX_BYTES", fetch.DefaultMaxBytes)),
+			UserAgent: "research-assistant/0.1",
+		}
+		var fetchFn pipeline.FetchFunc = func(fctx context.Context, url string) (pipeline.Page, error) {
+			page, err := fetch.Fetch(fctx, url, fetchOpts)
+			if err != nil {
+				return pipeline.Page{}, err
+			}
+			return pipeline.Page{Text: page.Text, Published: page.Published}, nil
+		}
+		if recordCassettes {
+			fetchFn = cassette.Fetch(fetchFn)
+		}
+		pl.SetFetcher(fetchFn)
+	}
+	var runner researcher.PipelineRunner = pl
+	if recordCassettes {
+		runner = cassette.NewRecorder(pl, blobStore)
+	}
+	exec := researcher.New(runner, ps)
+
+	// Sessions a previous process left mid-run resume from their last
+	// checkpoint in the background, or are marked failed. They are listed
+	// before serving so that sessions started by new requests are not
+	// resumed a second time.
+	if config.GetEnvBool("RESEARCH_RESUME_ON_START", true) {
+		if unfinished, err := pl.UnfinishedSessions(); err != nil {
+			log.Printf("[RESEARCHER] Session recovery error: %v", err)
+		} else {
+			go func() {
+				n, err := pl.RecoverSessions(ctx, unfinished)
+				if err != nil {
+					log.Printf("[RESEARCHER] Session recovery error: %v", err)
+					return
+				}
+				log.Printf("[RESEARCHER] Recovered %d interrupted session(s)", n)
+			}()
+		}
+	}
 
 	card := &a2a.AgentCard{
 		Name:               "Research Assistant — Researcher",
@@ -98,15 +200,16 @@ func main() {
 		URL:                "http://localhost" + addr,
 		Version:            "0.1.0",
 		ProtocolVersion:    "0.2.2",
-		Capabilities:       a2a.AgentCapabilities{Streaming: true},
+		Capabilities:       a2a.AgentCapabilities{Streaming: true, Extensions: []a2a.AgentExtension{agent.ResearchOptionsExtension()}},
 		DefaultInputModes:  []string{"text/plain"},
 		DefaultOutputModes: []string{"application/json"},
 		Skills: []a2a.AgentSkill{
 			{
 				ID:          "research",
 				Name:        "Research Topic",
-				Description: "Given a research topic, produces a structured report with key findings, sources, and an executive summary.",
-				InputModes:  []string{"text/plain"},
+				Description: "Given a research topic, produces a structured report with key findings, sources, and an executive summary. Accepts an optional research options DataPart (see the research-options extension).",
+				InputModes:  []string{"text/plain", "application/json"},
+				Examples:    []string{"The future of Go concurrency", agent.ResearchOptionsExample},
 				OutputModes: []string{"application/json"},
 			},
 		},
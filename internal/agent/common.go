@@ -8,6 +8,7 @@ import (
 	"github.com/a2aproject/a2a-go/a2asrv"
 	"github.com/a2aproject/a2a-go/a2asrv/eventqueue"
 	apperrors "github.com/user/research-assistant/internal/errors"
+	"github.com/user/research-assistant/internal/pipeline"
 )
 
 // ExtractText concatenates all text parts from an A2A message into a single string.
@@ -24,6 +25,29 @@ func ExtractText(msg *a2a.Message) string {
 	return strings.TrimSpace(sb.String())
 }
 
+// ExtractData merges the data parts of an A2A message into a single map.
+// Later parts override keys of earlier ones. Returns nil when the message has
+// no data parts.
+func ExtractData(msg *a2a.Message) map[string]any {
+	if msg == nil {
+		return nil
+	}
+	var data map[string]any
+	for _, p := range msg.Parts {
+		dp, ok := p.(a2a.DataPart)
+		if !ok {
+			continue
+		}
+		if data == nil {
+			data = make(map[string]any, len(dp.Data))
+		}
+		for k, v := range dp.Data {
+			data[k] = v
+		}
+	}
+	return data
+}
+
 // WriteStatus sends a status update event to the A2A queue.
 func WriteStatus(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue, state a2a.TaskState, text string, final bool) error {
 	var msg *a2a.Message
@@ -43,13 +67,22 @@ func WriteAppError(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eve
 	msg := a2a.NewMessage(a2a.MessageRoleAgent)
 	msg.Parts = append(msg.Parts, a2a.TextPart{Text: appErr.UserMessage})
 
+	// Only generic map and primitive types are used: the task store copies
+	// data parts with gob, which rejects unregistered named types.
 	data := map[string]any{
 		"kind":   "error_meta",
-		"code":   appErr.Code,
+		"code":   string(appErr.Code),
 		"source": appErr.Source,
 	}
-	if appErr.Recovery != nil {
-		data["recovery"] = appErr.Recovery
+	if r := appErr.Recovery; r != nil {
+		recovery := map[string]any{"type": string(r.Type)}
+		if r.WaitSeconds > 0 {
+			recovery["wait_after"] = r.WaitSeconds
+		}
+		if r.Suggestion != "" {
+			recovery["suggestion"] = r.Suggestion
+		}
+		data["recovery"] = recovery
 	}
 	if len(appErr.Telemetry) > 0 {
 		data["telemetry"] = appErr.Telemetry
@@ -63,3 +96,17 @@ func WriteAppError(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eve
 		Final:     true,
 	})
 }
+
+// ResearchOptionsExtension describes the optional research options DataPart
+// for agent cards. The JSON schema is advertised in Params["schema"].
+func ResearchOptionsExtension() a2a.AgentExtension {
+	return a2a.AgentExtension{
+		URI:         pipeline.OptionsExtensionURI,
+		Description: "Optional DataPart tuning a research run: num_queries, max_sources, depth, deadline_seconds, language, search_languages, output_language, include_domains, exclude_domains, freshness_days, report_format, include_unverified, subjects, criteria and renderers.",
+		Params:      map[string]any{"schema": pipeline.OptionsSchema()},
+	}
+}
+
+// ResearchOptionsExample is an agent card skill example showing a topic sent
+// together with a research options DataPart.
+const ResearchOptionsExample = `{"parts":[{"kind":"text","text":"The future of Go concurrency"},{"kind":"data","data":{"num_queries":5,"depth":2,"language":"en","exclude_domains":["pinterest.com"],"report_format":"bullets"}}]}`
@@ -1,6 +1,18 @@
 package concierge
 
-import "net/http"
+import (
+	"net/http"
+	"path"
+)
+
+// artifactTypes sets the Content-Type of report formats the system MIME table
+// may not know.
+var artifactTypes = map[string]string{
+	".md":   "text/markdown; charset=utf-8",
+	".html": "text/html; charset=utf-8",
+	".epub": "application/epub+zip",
+	".json": "application/json",
+}
 
 // NewArtifactHandler returns an http.Handler that serves artifacts from the given directory with CORS support.
 func NewArtifactHandler(artifactDir string) http.Handler {
@@ -13,6 +25,9 @@ func NewArtifactHandler(artifactDir string) http.Handler {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
+		if ct, ok := artifactTypes[path.Ext(r.URL.Path)]; ok {
+			w.Header().Set("Content-Type", ct)
+		}
 		fileServer.ServeHTTP(w, r)
 	})
 }
@@ -110,3 +110,28 @@ func TestArtifactHandler_CORS(t *testing.T) {
 		})
 	}
 }
+
+func TestArtifactHandler_ContentType(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"report.html", "report.epub"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("content"), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+	server := httptest.NewServer(http.StripPrefix("/artifacts/", NewArtifactHandler(tmpDir)))
+	defer server.Close()
+
+	for name, want := range map[string]string{
+		"report.html": "text/html; charset=utf-8",
+		"report.epub": "application/epub+zip",
+	} {
+		resp, err := http.Get(server.URL + "/artifacts/" + name)
+		if err != nil {
+			t.Fatalf("GET %s: %v", name, err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get("Content-Type"); got != want {
+			t.Errorf("%s: expected Content-Type %q, got %q", name, want, got)
+		}
+	}
+}
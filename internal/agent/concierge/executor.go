@@ -15,6 +15,8 @@ import (
 	"github.com/user/research-assistant/internal/agent"
 	apperrors "github.com/user/research-assistant/internal/errors"
 	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/prompts"
 	"github.com/user/research-assistant/internal/storage"
 )
 
@@ -29,23 +31,40 @@ type ContextStore interface {
 	GetSources(sessionID string) ([]event.SearchSource, error)
 	GetSessionStatus(sessionID string) (status string, errMsg string, err error)
 	GetSessionArtifacts(sessionID string) (reportMDKey, reportJSONKey string, err error)
+	GetSessionRenders(sessionID string) (map[string]string, error)
 	DeleteSession(sessionID string) error
 }
 
 // ResearchStream sends a research topic to the Researcher agent and returns
-// a streaming iterator of A2A events.
-type ResearchStream func(ctx context.Context, topic string, contextID string) iter.Seq2[a2a.Event, error]
+// a streaming iterator of A2A events. options is the validated research
+// options DataPart of the request, or nil when none was sent.
+type ResearchStream func(ctx context.Context, topic string, options map[string]any, contextID string) iter.Seq2[a2a.Event, error]
+
+// CancelResearch asks the Researcher agent to cancel one of its tasks. taskID
+// is empty when the Researcher has not reported its task yet; the unfinished
+// tasks of contextID, which research requests are sent under, are canceled
+// instead.
+type CancelResearch func(ctx context.Context, taskID a2a.TaskID, contextID string) error
+
+// researchTask tracks a research request that is still streaming.
+type researchTask struct {
+	cancel         context.CancelFunc
+	researcherTask a2a.TaskID // the Researcher's task ID, once known
+}
 
 // Executor implements a2asrv.AgentExecutor for the Concierge agent.
 type Executor struct {
-	llm        LLMClient
-	db         ContextStore
-	researcher ResearchStream
-	sub        event.Subscriber
-	blobs      storage.BlobStorage
+	llm            LLMClient
+	db             ContextStore
+	researcher     ResearchStream
+	cancelResearch CancelResearch
+	sub            event.Subscriber
+	blobs          storage.BlobStorage
+	prompts        *prompts.Registry
 
 	mu       sync.RWMutex
 	sessions map[string]string // contextID → researchSessionID
+	tasks    map[a2a.TaskID]*researchTask
 }
 
 // New creates a Concierge Executor.
@@ -56,10 +75,23 @@ func New(llm LLMClient, db ContextStore, researcher ResearchStream, sub event.Su
 		researcher: researcher,
 		sub:        sub,
 		blobs:      blobs,
+		prompts:    prompts.Default(),
 		sessions:   make(map[string]string),
+		tasks:      make(map[a2a.TaskID]*researchTask),
 	}
 }
 
+// SetResearchCanceler sets how Cancel stops the Researcher's side of a
+// research task. Without one, Cancel only stops relaying its events.
+func (e *Executor) SetResearchCanceler(cancel CancelResearch) {
+	e.cancelResearch = cancel
+}
+
+// SetPrompts replaces the prompt templates used for Q&A answers.
+func (e *Executor) SetPrompts(r *prompts.Registry) {
+	e.prompts = r
+}
+
 // SetSession pre-seeds the contextID→sessionID mapping (used in tests and for
 // restoring state after a restart).
 func (e *Executor) SetSession(contextID, sessionID string) {
@@ -92,9 +124,44 @@ func (e *Executor) Execute(ctx context.Context, reqCtx *a2asrv.RequestContext, q
 	return e.handleResearch(ctx, reqCtx, queue)
 }
 
-// Cancel signals cancellation for the given task.
-func (e *Executor) Cancel(_ context.Context, _ *a2asrv.RequestContext, _ eventqueue.Queue) error {
-	return nil
+// Cancel stops a research task: the Researcher is asked to cancel its
+// pipeline, the relay of its events stops, and a canceled event is written.
+func (e *Executor) Cancel(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue) error {
+	e.mu.Lock()
+	task, ok := e.tasks[reqCtx.TaskID]
+	delete(e.tasks, reqCtx.TaskID)
+	e.mu.Unlock()
+
+	if ok {
+		if e.cancelResearch != nil {
+			if err := e.cancelResearch(ctx, task.researcherTask, reqCtx.ContextID); err != nil {
+				log.Printf("[CONCIERGE] %s cancel researcher task %s: %v", reqCtx.ContextID, task.researcherTask, err)
+			}
+		}
+		task.cancel()
+		log.Printf("[CONCIERGE] %s research task %s canceled", reqCtx.ContextID, reqCtx.TaskID)
+	}
+	return agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateCanceled, "cancelled by client", true)
+}
+
+func (e *Executor) trackTask(taskID a2a.TaskID, cancel context.CancelFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tasks[taskID] = &researchTask{cancel: cancel}
+}
+
+func (e *Executor) untrackTask(taskID a2a.TaskID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.tasks, taskID)
+}
+
+func (e *Executor) setResearcherTask(taskID, researcherTask a2a.TaskID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if t, ok := e.tasks[taskID]; ok && t.researcherTask == "" {
+		t.researcherTask = researcherTask
+	}
 }
 
 func (e *Executor) DeleteSession(ctx context.Context, contextID string) error {
@@ -120,6 +187,15 @@ func (e *Executor) DeleteSession(ctx context.Context, contextID string) error {
 			log.Printf("[CONCIERGE] %s DeleteBlob (JSON) error: %v", contextID, err)
 		}
 	}
+	renders, err := e.db.GetSessionRenders(sessionID)
+	if err != nil {
+		log.Printf("[CONCIERGE] %s GetSessionRenders error: %v", contextID, err)
+	}
+	for name, key := range renders {
+		if err := e.blobs.DeleteBlob(key); err != nil {
+			log.Printf("[CONCIERGE] %s DeleteBlob (%s) error: %v", contextID, name, err)
+		}
+	}
 
 	// 3. Delete database records
 	if err := e.db.DeleteSession(sessionID); err != nil {
@@ -144,6 +220,18 @@ func (e *Executor) handleResearch(ctx context.Context, reqCtx *a2asrv.RequestCon
 	if topic == "" {
 		return agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateFailed, "empty research topic", true)
 	}
+	options := agent.ExtractData(reqCtx.Message)
+	if err := pipeline.ValidateOptions(options); err != nil {
+		log.Printf("[CONCIERGE] %s rejected options: %v", reqCtx.ContextID, err)
+		return agent.WriteAppError(ctx, reqCtx, queue, a2a.TaskStateFailed,
+			apperrors.New(apperrors.CodeQueryInvalid, "concierge", "Research options rejected: "+err.Error(), err))
+	}
+
+	// Cancel cancels ctx, which ends the researcher stream and the relay.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	e.trackTask(reqCtx.TaskID, cancel)
+	defer e.untrackTask(reqCtx.TaskID)
 
 	// Start a Redis listener to relay out-of-band events to the A2A stream.
 	// This ensures that even if the streaming Researcher response is buffered,
@@ -160,6 +248,9 @@ func (e *Executor) handleResearch(ctx context.Context, reqCtx *a2asrv.RequestCon
 					log.Printf("[CONCIERGE] %s relaying Redis event to A2A: %s", reqCtx.ContextID, ev.Type)
 					state := a2a.TaskStateWorking
 					final := false
+					if ev.Type == event.TypeCanceled {
+						return
+					}
 					if ev.Type == event.TypeError {
 						state = a2a.TaskStateFailed
 						final = true
@@ -173,8 +264,12 @@ func (e *Executor) handleResearch(ctx context.Context, reqCtx *a2asrv.RequestCon
 		}
 	}
 
-	stream := e.researcher(ctx, topic, reqCtx.ContextID)
+	stream := e.researcher(ctx, topic, options, reqCtx.ContextID)
 	for ev, err := range stream {
+		if err != nil && ctx.Err() != nil {
+			log.Printf("[CONCIERGE] %s researcher stream stopped: %v", reqCtx.ContextID, err)
+			return nil
+		}
 		if err != nil {
 			log.Printf("[CONCIERGE] researcher stream error: %v", err)
 			_ = agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateFailed, err.Error(), true)
@@ -188,11 +283,24 @@ func (e *Executor) handleResearch(ctx context.Context, reqCtx *a2asrv.RequestCon
 		case *a2a.TaskStatusUpdateEvent:
 			status = typed.Status
 			final = typed.Final
+			e.setResearcherTask(reqCtx.TaskID, typed.TaskID)
 		case *a2a.Task:
+			e.setResearcherTask(reqCtx.TaskID, typed.ID)
 			status = typed.Status
 			final = typed.Status.State == a2a.TaskStateCompleted ||
 				typed.Status.State == a2a.TaskStateFailed ||
 				typed.Status.State == a2a.TaskStateCanceled
+		case *a2a.TaskArtifactUpdateEvent:
+			// Streamed report chunks are relayed as they arrive, re-addressed
+			// to this task; they carry no state change.
+			e.setResearcherTask(reqCtx.TaskID, typed.TaskID)
+			relayed := *typed
+			relayed.TaskID = reqCtx.TaskID
+			relayed.ContextID = reqCtx.ContextID
+			if err := queue.Write(ctx, &relayed); err != nil {
+				log.Printf("[CONCIERGE] queue write error: %v", err)
+			}
+			continue
 		default:
 			continue
 		}
@@ -264,10 +372,13 @@ func (e *Executor) handleQA(ctx context.Context, reqCtx *a2asrv.RequestContext,
 			msg = fmt.Sprintf("The research failed: %s", errMsg)
 		}
 		return agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateFailed, msg, true)
-	case "queued", "searching", "structuring", "writing_report":
-		return agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, "I'm still working on the research. Please wait until it's complete before asking questions.", false)
+	case "canceled":
+		return agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateFailed, "The research for this topic was canceled.", true)
+	case "":
+		// Status unknown (lookup failed): fall back to answering from what is stored
 	default:
-		// Fallback for unknown status
+		// Every other status is a stage of a pipeline that is still running.
+		return agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, "I'm still working on the research. Please wait until it's complete before asking questions.", false)
 	}
 
 	question := agent.ExtractText(reqCtx.Message)
@@ -281,7 +392,10 @@ func (e *Executor) handleQA(ctx context.Context, reqCtx *a2asrv.RequestContext,
 		log.Printf("[CONCIERGE] GetSources error: %v", err)
 	}
 
-	prompt := buildQAPrompt(question, findings, sources)
+	prompt, err := buildQAPrompt(e.prompts, question, findings, sources)
+	if err != nil {
+		return agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateFailed, fmt.Sprintf("Prompt error: %v", err), true)
+	}
 	answer, err := e.llm.GenerateContent(ctx, prompt)
 	if err != nil {
 		var appErr *apperrors.AppError
@@ -307,27 +421,23 @@ func (e *Executor) handleQA(ctx context.Context, reqCtx *a2asrv.RequestContext,
 // Helpers
 // ---------------------------------------------------------------------------
 
-func buildQAPrompt(question string, findings []event.StructuredFinding, sources []event.SearchSource) string {
-	var sb strings.Builder
-	sb.WriteString("You are a research assistant. Answer the following question using ONLY the research context below.\n\n")
-	sb.WriteString("Question: ")
-	sb.WriteString(question)
-	sb.WriteString("\n\n")
-
-	if len(findings) > 0 {
-		sb.WriteString("Key Findings:\n")
-		for _, f := range findings {
-			sb.WriteString(fmt.Sprintf("- %s (confidence: %.2f)\n", f.Finding, f.Confidence))
-		}
-		sb.WriteString("\n")
-	}
+// qaSource is a source as the Q&A prompt lists it.
+type qaSource struct {
+	Title, URL, Query, Snippet string
+}
 
-	if len(sources) > 0 {
-		sb.WriteString("Sources:\n")
-		for _, s := range sources {
-			sb.WriteString(fmt.Sprintf("- [%s] %s: %s\n", s.Query, s.URL, s.Snippet))
+func buildQAPrompt(tmpl *prompts.Registry, question string, findings []event.StructuredFinding, sources []event.SearchSource) (string, error) {
+	listed := make([]qaSource, 0, len(sources))
+	for _, s := range sources {
+		title := s.Title
+		if title == "" {
+			title = s.URL
 		}
+		query := s.Query
+		if len(s.Queries) > 0 {
+			query = strings.Join(s.Queries, "; ")
+		}
+		listed = append(listed, qaSource{Title: title, URL: s.URL, Query: query, Snippet: s.Snippet})
 	}
-
-	return sb.String()
+	return tmpl.Render(prompts.QA, map[string]any{"Question": question, "Findings": findings, "Sources": listed})
 }
@@ -34,6 +34,7 @@ type mockContextStore struct {
 	status   string
 	errMsg   string
 	err      error
+	renders  map[string]string
 }
 
 func (m *mockContextStore) GetKeyFindings(_ string) ([]event.StructuredFinding, error) {
@@ -56,6 +57,10 @@ func (m *mockContextStore) GetSessionArtifacts(_ string) (string, string, error)
 	return "report.md", "report.json", nil
 }
 
+func (m *mockContextStore) GetSessionRenders(_ string) (map[string]string, error) {
+	return m.renders, nil
+}
+
 func (m *mockContextStore) DeleteSession(_ string) error {
 	return nil
 }
@@ -74,11 +79,13 @@ func (m *mockBlobStorage) DeleteBlob(key string) error {
 
 // mockResearcher returns a fixed sequence of A2A events, simulating the Researcher agent.
 type mockResearcher struct {
-	events []a2a.Event
-	err    error
+	events  []a2a.Event
+	err     error
+	options map[string]any
 }
 
-func (m *mockResearcher) Stream(_ context.Context, _ string, _ string) iter.Seq2[a2a.Event, error] {
+func (m *mockResearcher) Stream(_ context.Context, _ string, options map[string]any, _ string) iter.Seq2[a2a.Event, error] {
+	m.options = options
 	return func(yield func(a2a.Event, error) bool) {
 		if m.err != nil {
 			yield(nil, m.err)
@@ -212,6 +219,65 @@ func TestConciergeExecutor_RelaysResearcherUpdates(t *testing.T) {
 	}
 }
 
+func reportChunk(id a2a.ArtifactID, text string, append, last bool) *a2a.TaskArtifactUpdateEvent {
+	return &a2a.TaskArtifactUpdateEvent{
+		TaskID:    "researcher-task",
+		ContextID: "researcher-ctx",
+		Artifact:  &a2a.Artifact{ID: id, Name: "report", Parts: a2a.ContentParts{a2a.TextPart{Text: text}}},
+		Append:    append,
+		LastChunk: last,
+	}
+}
+
+// TestConciergeExecutor_RelaysReportChunks verifies that streamed report
+// chunks from the Researcher are relayed as they arrive, re-addressed to the
+// Concierge task, with their append semantics intact.
+func TestConciergeExecutor_RelaysReportChunks(t *testing.T) {
+	researcher := &mockResearcher{
+		events: []a2a.Event{
+			workingStatus("Writing report"),
+			reportChunk("report-1", "Partial ", false, false),
+			reportChunk("report-1", "report", true, false),
+			reportChunk("report-1", "Final report", false, true),
+			completedStatus("session-abc"),
+		},
+	}
+
+	exec := concierge.New(&mockLLM{}, &mockContextStore{}, researcher.Stream, nil, &mockBlobStorage{})
+	q := &recordingQueue{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := exec.Execute(ctx, makeReqCtx("ctx1", "Go concurrency"), q); err != nil {
+		t.Fatalf("Execute returned unexpected error: %v", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var chunks []*a2a.TaskArtifactUpdateEvent
+	for _, e := range q.events {
+		if ev, ok := e.(*a2a.TaskArtifactUpdateEvent); ok {
+			chunks = append(chunks, ev)
+		}
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 relayed report chunks, got %d; all: %v", len(chunks), q.events)
+	}
+	for _, ev := range chunks {
+		if ev.TaskID != "task-ctx1" || ev.ContextID != "ctx1" {
+			t.Errorf("chunk not re-addressed to the Concierge task: %+v", ev)
+		}
+	}
+	if chunks[0].Append || !chunks[1].Append || chunks[2].Append || !chunks[2].LastChunk {
+		t.Errorf("append semantics changed while relaying: %+v %+v %+v", chunks[0], chunks[1], chunks[2])
+	}
+	if _, ok := q.events[len(q.events)-1].(*a2a.TaskStatusUpdateEvent); !ok {
+		t.Error("expected the completed status after the report chunks")
+	}
+}
+
 // TestConciergeExecutor_QAModeLoadsContext verifies that a second message on
 // the same context ID triggers Q&A mode: findings/sources are loaded from DB,
 // Gemini is called with them, and a completed status with the answer is emitted.
@@ -269,6 +335,32 @@ func TestConciergeExecutor_QAModeLoadsContext(t *testing.T) {
 	}
 }
 
+// TestConciergeExecutor_QADuringResearch verifies that a question asked while
+// the pipeline is in any running stage, including optional ones, is answered
+// with a working status instead of reaching the LLM.
+func TestConciergeExecutor_QADuringResearch(t *testing.T) {
+	for _, status := range []string{"queued", "searching", "verifying", "comparing"} {
+		store := &mockContextStore{status: status}
+		lm := &mockLLM{response: "should not be used"}
+		exec := concierge.New(lm, store, (&mockResearcher{}).Stream, nil, &mockBlobStorage{})
+		exec.SetSession("ctx-qa", "session-running")
+
+		q := &recordingQueue{}
+		if err := exec.Execute(context.Background(), makeReqCtx("ctx-qa", "Anything yet?"), q); err != nil {
+			t.Fatalf("%s: Execute returned error: %v", status, err)
+		}
+		q.mu.Lock()
+		if len(q.events) != 1 {
+			t.Fatalf("%s: expected one event, got %v", status, q.events)
+		}
+		s, ok := q.events[0].(*a2a.TaskStatusUpdateEvent)
+		if !ok || s.Status.State != a2a.TaskStateWorking || s.Final {
+			t.Errorf("%s: expected a non-final working status, got %+v", status, q.events[0])
+		}
+		q.mu.Unlock()
+	}
+}
+
 // TestConciergeExecutor_ResearcherFailure verifies that a Researcher failure
 // is relayed to the user as a failed status.
 func TestConciergeExecutor_ResearcherFailure(t *testing.T) {
@@ -295,7 +387,7 @@ func TestConciergeExecutor_ResearcherFailure(t *testing.T) {
 
 func TestConciergeExecutor_DeleteSession(t *testing.T) {
 	blobs := &mockBlobStorage{}
-	store := &mockContextStore{}
+	store := &mockContextStore{renders: map[string]string{"html": "report.html"}}
 	exec := concierge.New(&mockLLM{}, store, nil, nil, blobs)
 
 	const contextID = "ctx-delete"
@@ -311,6 +403,7 @@ func TestConciergeExecutor_DeleteSession(t *testing.T) {
 	// Verify blobs deleted
 	foundMD := false
 	foundJSON := false
+	foundHTML := false
 	for _, k := range blobs.deletedKeys {
 		if k == "report.md" {
 			foundMD = true
@@ -318,14 +411,17 @@ func TestConciergeExecutor_DeleteSession(t *testing.T) {
 		if k == "report.json" {
 			foundJSON = true
 		}
+		if k == "report.html" {
+			foundHTML = true
+		}
 	}
-	if !foundMD || !foundJSON {
-		t.Errorf("expected MD and JSON blobs to be deleted; got: %v", blobs.deletedKeys)
+	if !foundMD || !foundJSON || !foundHTML {
+		t.Errorf("expected MD, JSON and HTML blobs to be deleted; got: %v", blobs.deletedKeys)
 	}
 
 	// Verify in-memory state cleared
 	researcherCalled := false
-	researcher := func(ctx context.Context, topic string, contextID string) iter.Seq2[a2a.Event, error] {
+	researcher := func(ctx context.Context, topic string, _ map[string]any, contextID string) iter.Seq2[a2a.Event, error] {
 		researcherCalled = true
 		return func(yield func(a2a.Event, error) bool) {}
 	}
@@ -521,3 +617,193 @@ func TestHandlerStack_SendMessageStream(t *testing.T) {
 		})
 	}
 }
+
+// TestHandlerStack_StreamsReportChunks verifies that report chunks reach
+// OnSendMessageStream, which the /ws bridge forwards verbatim, and that the
+// stored task ends up with the finished report as its artifact.
+func TestHandlerStack_StreamsReportChunks(t *testing.T) {
+	handler := newHandlerWithResearcher(&mockResearcher{
+		events: []a2a.Event{
+			reportChunk("report-1", "Partial ", false, false),
+			reportChunk("report-1", "report", true, false),
+			reportChunk("report-1", "Final report", false, true),
+			completedStatus("session-001"),
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := drainStream(ctx, handler, newMsgParams("msg-stream", "ctx-stream", "Streamed topic"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var taskID a2a.TaskID
+	var chunks int
+	for _, e := range events {
+		if ev, ok := e.(*a2a.TaskArtifactUpdateEvent); ok {
+			chunks++
+			taskID = ev.TaskID
+		}
+	}
+	if chunks != 3 {
+		t.Fatalf("expected 3 report chunks in the stream, got %d: %v", chunks, events)
+	}
+
+	task, err := handler.OnGetTask(ctx, &a2a.TaskQueryParams{ID: taskID})
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if len(task.Artifacts) != 1 {
+		t.Fatalf("expected one report artifact, got %d", len(task.Artifacts))
+	}
+	var text string
+	for _, p := range task.Artifacts[0].Parts {
+		if tp, ok := p.(a2a.TextPart); ok {
+			text += tp.Text
+		}
+	}
+	if text != "Final report" {
+		t.Errorf("stored report artifact = %q, want the finished report", text)
+	}
+}
+
+// TestConciergeExecutor_ForwardsResearchOptions verifies that a valid options
+// DataPart is forwarded to the Researcher unchanged.
+func TestConciergeExecutor_ForwardsResearchOptions(t *testing.T) {
+	researcher := &mockResearcher{events: []a2a.Event{completedStatus("session-opts")}}
+	exec := concierge.New(&mockLLM{}, &mockContextStore{}, researcher.Stream, nil, &mockBlobStorage{})
+	reqCtx := makeReqCtx("ctx-opts", "Go concurrency")
+	reqCtx.Message.Parts = append(reqCtx.Message.Parts, a2a.DataPart{Data: map[string]any{
+		"max_sources":     float64(5),
+		"include_domains": []any{"go.dev"},
+	}})
+
+	if err := exec.Execute(context.Background(), reqCtx, &recordingQueue{}); err != nil {
+		t.Fatalf("Execute returned unexpected error: %v", err)
+	}
+	if researcher.options["max_sources"] != float64(5) {
+		t.Errorf("expected options forwarded to researcher, got %v", researcher.options)
+	}
+}
+
+// TestConciergeExecutor_RejectsInvalidOptions verifies that invalid options
+// fail the task without contacting the Researcher.
+func TestConciergeExecutor_RejectsInvalidOptions(t *testing.T) {
+	researcher := &mockResearcher{events: []a2a.Event{completedStatus("never")}}
+	exec := concierge.New(&mockLLM{}, &mockContextStore{}, researcher.Stream, nil, &mockBlobStorage{})
+	reqCtx := makeReqCtx("ctx-bad-opts", "Go concurrency")
+	reqCtx.Message.Parts = append(reqCtx.Message.Parts, a2a.DataPart{Data: map[string]any{
+		"report_format": "poem",
+	}})
+	q := &recordingQueue{}
+
+	if err := exec.Execute(context.Background(), reqCtx, q); err != nil {
+		t.Fatalf("Execute returned unexpected error: %v", err)
+	}
+	if researcher.options != nil {
+		t.Errorf("researcher must not be called with invalid options")
+	}
+	if n := countState(q.events, a2a.TaskStateFailed); n != 1 {
+		t.Errorf("expected 1 failed event, got %d", n)
+	}
+}
+
+// TestConciergeExecutor_CancelStopsResearch verifies that Cancel asks the
+// Researcher to cancel its task, stops relaying the research stream, and ends
+// the task as canceled rather than failed.
+func TestConciergeExecutor_CancelStopsResearch(t *testing.T) {
+	relayed := make(chan struct{})
+	stream := func(ctx context.Context, _ string, _ map[string]any, _ string) iter.Seq2[a2a.Event, error] {
+		return func(yield func(a2a.Event, error) bool) {
+			ev := &a2a.TaskStatusUpdateEvent{TaskID: "researcher-task", Status: a2a.TaskStatus{State: a2a.TaskStateWorking}}
+			if !yield(ev, nil) {
+				return
+			}
+			close(relayed)
+			<-ctx.Done()
+			yield(nil, ctx.Err())
+		}
+	}
+	var canceled []a2a.TaskID
+	exec := concierge.New(&mockLLM{}, &mockContextStore{}, stream, nil, &mockBlobStorage{})
+	exec.SetResearchCanceler(func(_ context.Context, taskID a2a.TaskID, _ string) error {
+		canceled = append(canceled, taskID)
+		return nil
+	})
+	q := &recordingQueue{}
+	reqCtx := makeReqCtx("ctx1", "Test Topic")
+
+	done := make(chan error, 1)
+	go func() { done <- exec.Execute(context.Background(), reqCtx, q) }()
+	<-relayed
+
+	if err := exec.Cancel(context.Background(), reqCtx, q); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Execute returned error after cancel: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("research stream did not stop after Cancel")
+	}
+
+	if len(canceled) != 1 || canceled[0] != "researcher-task" {
+		t.Errorf("expected researcher task to be canceled, got %v", canceled)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	last, ok := q.events[len(q.events)-1].(*a2a.TaskStatusUpdateEvent)
+	if !ok || last.Status.State != a2a.TaskStateCanceled || !last.Final {
+		t.Errorf("expected final canceled event, got %+v", q.events[len(q.events)-1])
+	}
+	for _, ev := range q.events {
+		if s, ok := ev.(*a2a.TaskStatusUpdateEvent); ok && s.Status.State == a2a.TaskStateFailed {
+			t.Errorf("canceled research must not report failure: %+v", s)
+		}
+	}
+}
+
+// TestConciergeExecutor_CancelBeforeResearcherTask verifies that a cancel
+// arriving before the Researcher's first event is forwarded by context ID.
+func TestConciergeExecutor_CancelBeforeResearcherTask(t *testing.T) {
+	started := make(chan struct{})
+	stream := func(ctx context.Context, _ string, _ map[string]any, _ string) iter.Seq2[a2a.Event, error] {
+		return func(yield func(a2a.Event, error) bool) {
+			close(started)
+			<-ctx.Done()
+			yield(nil, ctx.Err())
+		}
+	}
+	type cancelCall struct {
+		taskID    a2a.TaskID
+		contextID string
+	}
+	var calls []cancelCall
+	exec := concierge.New(&mockLLM{}, &mockContextStore{}, stream, nil, &mockBlobStorage{})
+	exec.SetResearchCanceler(func(_ context.Context, taskID a2a.TaskID, contextID string) error {
+		calls = append(calls, cancelCall{taskID, contextID})
+		return nil
+	})
+	q := &recordingQueue{}
+	reqCtx := makeReqCtx("ctx1", "Test Topic")
+
+	done := make(chan error, 1)
+	go func() { done <- exec.Execute(context.Background(), reqCtx, q) }()
+	<-started
+
+	if err := exec.Cancel(context.Background(), reqCtx, q); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("research stream did not stop after Cancel")
+	}
+	if len(calls) != 1 || calls[0].taskID != "" || calls[0].contextID != "ctx1" {
+		t.Errorf("expected the cancel forwarded by context ID, got %+v", calls)
+	}
+}
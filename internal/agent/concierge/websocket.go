@@ -100,8 +100,8 @@ func HandleWebSocket(handler a2asrv.RequestHandler, ps event.Subscriber, exec *E
 				continue
 			}
 
-			// We only support message/send, message/stream, or session/delete.
-			if req.Method != "message/send" && req.Method != "message/stream" && req.Method != "session/delete" {
+			// We only support message/send, message/stream, tasks/cancel, or session/delete.
+			if req.Method != "message/send" && req.Method != "message/stream" && req.Method != "tasks/cancel" && req.Method != "session/delete" {
 				_ = writeJSON(wsJSONResponse{
 					JSONRPC: "2.0",
 					ID:      req.ID,
@@ -113,6 +113,42 @@ func HandleWebSocket(handler a2asrv.RequestHandler, ps event.Subscriber, exec *E
 				continue
 			}
 
+			if req.Method == "tasks/cancel" {
+				var params a2a.TaskIDParams
+				if err := json.Unmarshal(req.Params, &params); err != nil || params.ID == "" {
+					_ = writeJSON(wsJSONResponse{
+						JSONRPC: "2.0",
+						ID:      req.ID,
+						Error: map[string]any{
+							"code":    -32602,
+							"message": "id is required for tasks/cancel",
+						},
+					})
+					continue
+				}
+				go func() {
+					ctx, _ := a2asrv.WithCallContext(context.Background(), a2asrv.NewRequestMeta(r.Header))
+					task, err := handler.OnCancelTask(ctx, &params)
+					if err != nil {
+						_ = writeJSON(wsJSONResponse{
+							JSONRPC: "2.0",
+							ID:      req.ID,
+							Error: map[string]any{
+								"code":    -32000,
+								"message": fmt.Sprintf("cancel failed: %v", err),
+							},
+						})
+						return
+					}
+					_ = writeJSON(wsJSONResponse{
+						JSONRPC: "2.0",
+						ID:      req.ID,
+						Result:  task,
+					})
+				}()
+				continue
+			}
+
 			var params wsParams
 			if err := json.Unmarshal(req.Params, &params); err != nil {
 				_ = writeJSON(wsJSONResponse{
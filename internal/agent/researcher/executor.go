@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sync"
 
 	"github.com/a2aproject/a2a-go/a2a"
 	"github.com/a2aproject/a2a-go/a2asrv"
@@ -18,7 +19,7 @@ import (
 
 // PipelineRunner is the interface the executor requires from the pipeline.
 type PipelineRunner interface {
-	RunWithUpdates(ctx context.Context, sessionID, topic string, onUpdate func(status, detail string)) (*pipeline.Result, error)
+	RunWithOptions(ctx context.Context, sessionID, topic string, opts pipeline.Options, onUpdate func(status, detail string)) (*pipeline.Result, error)
 }
 
 // EventPublisher defines how the agent broadcasts transient status events.
@@ -30,25 +31,73 @@ type EventPublisher interface {
 type Executor struct {
 	pipeline PipelineRunner
 	pub      EventPublisher
+
+	mu      sync.Mutex
+	running map[a2a.TaskID]context.CancelFunc // taskID → cancels its pipeline run
 }
 
 // New creates an Executor backed by the given PipelineRunner and EventPublisher.
 func New(pl PipelineRunner, pub EventPublisher) *Executor {
-	return &Executor{pipeline: pl, pub: pub}
+	return &Executor{pipeline: pl, pub: pub, running: make(map[a2a.TaskID]context.CancelFunc)}
+}
+
+func (e *Executor) track(taskID a2a.TaskID, cancel context.CancelFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.running[taskID] = cancel
+}
+
+func (e *Executor) untrack(taskID a2a.TaskID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.running, taskID)
+}
+
+func (e *Executor) cancelRun(taskID a2a.TaskID) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cancel, ok := e.running[taskID]
+	if ok {
+		cancel()
+	}
+	return ok
 }
 
 // Execute runs the research pipeline for the topic extracted from the incoming
-// A2A message, streaming status updates via the queue.
+// A2A message, streaming status updates via the queue. An optional DataPart
+// carries research options (see pipeline.OptionsSchema).
 func (e *Executor) Execute(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue) error {
 	topic := agent.ExtractText(reqCtx.Message)
 	if topic == "" {
 		return agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateFailed, "empty research topic", true)
 	}
+	opts, err := pipeline.ParseOptions(agent.ExtractData(reqCtx.Message))
+	if err != nil {
+		log.Printf("[RESEARCHER] %s rejected options: %v", reqCtx.ContextID, err)
+		return agent.WriteAppError(ctx, reqCtx, queue, a2a.TaskStateFailed,
+			apperrors.New(apperrors.CodeQueryInvalid, "researcher", "Research options rejected: "+err.Error(), err))
+	}
 
 	sessionID := uuid.New().String()
 	log.Printf("[RESEARCHER] %s starting pipeline for topic: %q, session: %s", reqCtx.ContextID, topic, sessionID)
 
-	result, pipeErr := e.pipeline.RunWithUpdates(ctx, sessionID, topic, func(status, detail string) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	e.track(reqCtx.TaskID, cancel)
+	defer e.untrack(reqCtx.TaskID)
+
+	report := &reportArtifact{id: a2a.NewArtifactID()}
+	var failure string
+	result, pipeErr := e.pipeline.RunWithOptions(runCtx, sessionID, topic, opts, func(status, detail string) {
+		switch status {
+		case pipeline.StatusReportRestart, pipeline.StatusReportChunk, pipeline.StatusReportDone:
+			// Streamed report text goes to the report artifact only; it is
+			// neither logged nor published.
+			if err := report.write(ctx, reqCtx, queue, status, detail); err != nil {
+				log.Printf("[RESEARCHER] %s queue write error (%s): %v", reqCtx.ContextID, status, err)
+			}
+			return
+		}
 		log.Printf("[RESEARCHER] %s pipeline update: status=%s, detail=%s", reqCtx.ContextID, status, detail)
 		// Map internal status to event type for PubSub
 		var evType event.ResearchEventType
@@ -57,10 +106,14 @@ func (e *Executor) Execute(ctx context.Context, reqCtx *a2asrv.RequestContext, q
 			evType = event.TypeSearchRequested
 		case "structuring":
 			evType = event.TypeStructuredDataReady
+		case "researching_round":
+			evType = event.TypeResearchRound
 		case "writing_report":
 			evType = event.TypeSummaryRequested
 		case "failed":
 			evType = event.TypeError
+		case "canceled":
+			evType = event.TypeCanceled
 		case "complete":
 			evType = event.TypeSummaryComplete
 		}
@@ -80,35 +133,68 @@ func (e *Executor) Execute(ctx context.Context, reqCtx *a2asrv.RequestContext, q
 			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, msg, false); err != nil {
 				log.Printf("[RESEARCHER] %s queue write error (searching): %v", reqCtx.ContextID, err)
 			}
+		case "search_cached":
+			msg := "Using cached results: " + detail
+			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, msg, false); err != nil {
+				log.Printf("[RESEARCHER] %s queue write error (search_cached): %v", reqCtx.ContextID, err)
+			}
+		case "fetching":
+			msg := "Fetching " + detail
+			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, msg, false); err != nil {
+				log.Printf("[RESEARCHER] %s queue write error (fetching): %v", reqCtx.ContextID, err)
+			}
 		case "structuring":
 			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, "Structuring findings", false); err != nil {
 				log.Printf("[RESEARCHER] %s queue write error (structuring): %v", reqCtx.ContextID, err)
 			}
+		case "verifying":
+			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, "Verifying findings", false); err != nil {
+				log.Printf("[RESEARCHER] %s queue write error (verifying): %v", reqCtx.ContextID, err)
+			}
+		case "comparing":
+			msg := "Comparing " + detail
+			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, msg, false); err != nil {
+				log.Printf("[RESEARCHER] %s queue write error (comparing): %v", reqCtx.ContextID, err)
+			}
+		case "researching_round":
+			msg := "Deep research " + detail
+			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, msg, false); err != nil {
+				log.Printf("[RESEARCHER] %s queue write error (researching_round): %v", reqCtx.ContextID, err)
+			}
 		case "writing_report":
 			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, "Writing report", false); err != nil {
 				log.Printf("[RESEARCHER] %s queue write error (writing_report): %v", reqCtx.ContextID, err)
 			}
-		case "failed":
-			msg := detail
-			if msg == "" {
-				msg = "pipeline failed"
-			}
-			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateFailed, msg, true); err != nil {
-				log.Printf("[RESEARCHER] %s queue write error (failed): %v", reqCtx.ContextID, err)
+		case "writing_section":
+			msg := "Writing section " + detail
+			if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateWorking, msg, false); err != nil {
+				log.Printf("[RESEARCHER] %s queue write error (writing_section): %v", reqCtx.ContextID, err)
 			}
-		case "complete":
-			// Final completed event is emitted after RunWithUpdates returns so we
-			// have access to the full Result. Skip here.
+		case "failed":
+			failure = detail
+		case "complete", "canceled":
+			// Final completed and failed events are emitted after
+			// RunWithOptions returns so we have access to the full Result or
+			// error, and Cancel writes the canceled event. Skip here.
 		}
 	})
 
+	if pipeErr != nil && runCtx.Err() != nil {
+		log.Printf("[RESEARCHER] %s pipeline canceled", reqCtx.ContextID)
+		return nil
+	}
 	if pipeErr != nil {
 		log.Printf("[RESEARCHER] %s pipeline finished with error: %v", reqCtx.ContextID, pipeErr)
 		var appErr *apperrors.AppError
 		if errors.As(pipeErr, &appErr) {
 			return agent.WriteAppError(ctx, reqCtx, queue, a2a.TaskStateFailed, appErr)
 		}
-		// "failed" status already emitted via onUpdate; no error to return.
+		if failure == "" {
+			failure = "pipeline failed"
+		}
+		if err := agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateFailed, failure, true); err != nil {
+			log.Printf("[RESEARCHER] %s queue write error (failed): %v", reqCtx.ContextID, err)
+		}
 		return nil
 	}
 
@@ -117,9 +203,13 @@ func (e *Executor) Execute(ctx context.Context, reqCtx *a2asrv.RequestContext, q
 	return writeFinal(ctx, reqCtx, queue, result)
 }
 
-// Cancel signals the running pipeline to stop. The context passed to Execute
-// is cancelled by the A2A server; this implementation writes a cancelled event.
+// Cancel stops the task's running pipeline, which abandons its outstanding
+// searches and LLM calls and records the session as canceled, then writes a
+// canceled event.
 func (e *Executor) Cancel(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue) error {
+	if e.cancelRun(reqCtx.TaskID) {
+		log.Printf("[RESEARCHER] %s canceling pipeline for task %s", reqCtx.ContextID, reqCtx.TaskID)
+	}
 	return agent.WriteStatus(ctx, reqCtx, queue, a2a.TaskStateCanceled, "cancelled by client", true)
 }
 
@@ -127,18 +217,61 @@ func (e *Executor) Cancel(ctx context.Context, reqCtx *a2asrv.RequestContext, qu
 // Helpers
 // ---------------------------------------------------------------------------
 
+// reportArtifact forwards the report streamed by the pipeline as updates of a
+// single "report" artifact. Chunks are appended to it; a restart or the
+// finished report replaces its content, the latter as the last chunk.
+type reportArtifact struct {
+	id      a2a.ArtifactID
+	started bool
+}
+
+func (r *reportArtifact) write(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue, status, text string) error {
+	ev := &a2a.TaskArtifactUpdateEvent{
+		TaskID:    reqCtx.TaskID,
+		ContextID: reqCtx.ContextID,
+		Artifact: &a2a.Artifact{
+			ID:    r.id,
+			Name:  "report",
+			Parts: a2a.ContentParts{a2a.TextPart{Text: text}},
+		},
+		// The first update must create the artifact, so it never appends.
+		Append:    status == pipeline.StatusReportChunk && r.started,
+		LastChunk: status == pipeline.StatusReportDone,
+	}
+	r.started = true
+	return queue.Write(ctx, ev)
+}
+
 func writeFinal(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue, result *pipeline.Result) error {
 	if result == nil {
 		return fmt.Errorf("writeFinal: result is nil")
 	}
-	dataMsg := a2a.NewMessage(
-		a2a.MessageRoleAgent,
-		a2a.DataPart{Data: map[string]any{
-			"session_id":      result.SessionID,
-			"report_md_key":   result.ReportMDKey,
-			"report_json_key": result.ReportJSONKey,
-		}},
-	)
+	data := map[string]any{
+		"session_id":      result.SessionID,
+		"report_md_key":   result.ReportMDKey,
+		"report_json_key": result.ReportJSONKey,
+		"rounds":          result.Rounds,
+		"language":        result.Language,
+		"token_usage":     usageData(result.Usage),
+		"telemetry": map[string]any{
+			"structure_repairs": result.StructureRepairs,
+		},
+	}
+	if len(result.SkippedStages) > 0 {
+		skipped := make([]any, len(result.SkippedStages))
+		for i, name := range result.SkippedStages {
+			skipped[i] = name
+		}
+		data["skipped_stages"] = skipped
+	}
+	if len(result.Renders) > 0 {
+		renders := make(map[string]any, len(result.Renders))
+		for name, key := range result.Renders {
+			renders[name] = key
+		}
+		data["renders"] = renders
+	}
+	dataMsg := a2a.NewMessage(a2a.MessageRoleAgent, a2a.DataPart{Data: data})
 	return queue.Write(ctx, &a2a.TaskStatusUpdateEvent{
 		TaskID:    reqCtx.TaskID,
 		ContextID: reqCtx.ContextID,
@@ -149,3 +282,35 @@ func writeFinal(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventq
 		Final: true,
 	})
 }
+
+// usageData summarises per-stage token usage for the final DataPart. It only
+// uses the generic map and slice types the task store can copy.
+func usageData(usage []event.TokenUsage) map[string]any {
+	var prompt, completion, total int
+	var cost float64
+	estimated := false
+	stages := make([]any, 0, len(usage))
+	for _, u := range usage {
+		prompt += u.PromptTokens
+		completion += u.CompletionTokens
+		total += u.TotalTokens
+		cost += u.CostUSD
+		estimated = estimated || u.Estimated
+		stages = append(stages, map[string]any{
+			"stage":             u.Stage,
+			"calls":             u.Calls,
+			"prompt_tokens":     u.PromptTokens,
+			"completion_tokens": u.CompletionTokens,
+			"total_tokens":      u.TotalTokens,
+			"cost_usd":          u.CostUSD,
+		})
+	}
+	return map[string]any{
+		"prompt_tokens":     prompt,
+		"completion_tokens": completion,
+		"total_tokens":      total,
+		"cost_usd":          cost,
+		"estimated":         estimated,
+		"by_stage":          stages,
+	}
+}
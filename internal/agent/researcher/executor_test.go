@@ -3,6 +3,7 @@ package researcher_test
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -10,8 +11,10 @@ import (
 	"github.com/a2aproject/a2a-go/a2a"
 	"github.com/a2aproject/a2a-go/a2asrv"
 	"github.com/a2aproject/a2a-go/a2asrv/eventqueue"
+	"github.com/user/research-assistant/internal/agent"
 	"github.com/user/research-assistant/internal/agent/researcher"
 	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/moderation"
 	"github.com/user/research-assistant/internal/pipeline"
 )
 
@@ -40,12 +43,23 @@ type mockPipeline struct {
 	sequence []struct{ status, detail string }
 	result   *pipeline.Result
 	err      error
+	opts     pipeline.Options
+	// started, when set, is closed once the run begins; the run then blocks
+	// until its context is cancelled, as the real pipeline does.
+	started chan struct{}
 }
 
-func (m *mockPipeline) RunWithUpdates(_ context.Context, sessionID, _ string, onUpdate func(string, string)) (*pipeline.Result, error) {
+func (m *mockPipeline) RunWithOptions(ctx context.Context, sessionID, _ string, opts pipeline.Options, onUpdate func(string, string)) (*pipeline.Result, error) {
+	m.opts = opts
 	for _, s := range m.sequence {
 		onUpdate(s.status, s.detail)
 	}
+	if m.started != nil {
+		close(m.started)
+		<-ctx.Done()
+		onUpdate("canceled", "")
+		return nil, ctx.Err()
+	}
 	if m.result != nil {
 		m.result.SessionID = sessionID
 	}
@@ -107,13 +121,14 @@ func statusEvents(events []a2a.Event) []*a2a.TaskStatusUpdateEvent {
 // ---------------------------------------------------------------------------
 
 // TestResearcherExecutor_StreamsWorkingUpdates verifies that intermediate
-// pipeline statuses (searching, structuring, writing_report) produce
+// pipeline statuses (searching, search_cached, structuring, writing_report) produce
 // TaskStateWorking events in the queue, and that no error is returned.
 func TestResearcherExecutor_StreamsWorkingUpdates(t *testing.T) {
 	mock := &mockPipeline{
 		sequence: []struct{ status, detail string }{
 			{"searching", "query1"},
 			{"searching", "query2"},
+			{"search_cached", "query2"},
 			{"searching", "query3"},
 			{"structuring", ""},
 			{"writing_report", ""},
@@ -146,8 +161,8 @@ func TestResearcherExecutor_StreamsWorkingUpdates(t *testing.T) {
 			workingCount++
 		}
 	}
-	if workingCount < 5 { // 3 searching + structuring + writing_report
-		t.Errorf("expected at least 5 TaskStateWorking events, got %d; events: %v", workingCount, statuses)
+	if workingCount < 6 { // 3 searching + search_cached + structuring + writing_report
+		t.Errorf("expected at least 6 TaskStateWorking events, got %d; events: %v", workingCount, statuses)
 	}
 }
 
@@ -246,3 +261,327 @@ func TestResearcherExecutor_FailsOnPipelineError(t *testing.T) {
 		t.Errorf("expected a final TaskStateFailed event; got: %v", statuses)
 	}
 }
+
+// TestResearcherExecutor_PolicyViolationReachesClient verifies that a topic
+// blocked by moderation ends the task with the error code and rephrase
+// suggestion intact once the handler has stored it.
+func TestResearcherExecutor_PolicyViolationReachesClient(t *testing.T) {
+	blocked := moderation.DefaultPolicy().Match("how to make a bomb")
+	mock := &mockPipeline{
+		sequence: []struct{ status, detail string }{{"failed", "Topic validation failed: " + blocked.Reason}},
+		err:      blocked.Err(),
+	}
+	handler := a2asrv.NewHandler(researcher.New(mock, &mockPublisher{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var last a2a.Event
+	for ev, err := range handler.OnSendMessageStream(ctx, &a2a.MessageSendParams{Message: makeReqCtx("how to make a bomb").Message}) {
+		if err != nil {
+			t.Fatalf("stream error: %v", err)
+		}
+		last = ev
+	}
+
+	var status a2a.TaskStatus
+	switch ev := last.(type) {
+	case *a2a.TaskStatusUpdateEvent:
+		status = ev.Status
+	case *a2a.Task:
+		status = ev.Status
+	default:
+		t.Fatalf("unexpected last event %T", last)
+	}
+	if status.State != a2a.TaskStateFailed || status.Message == nil {
+		t.Fatalf("expected a failed status with a message, got %+v", status)
+	}
+	data := agent.ExtractData(status.Message)
+	recovery, _ := data["recovery"].(map[string]any)
+	if data["code"] != "POLICY_VIOLATION" || recovery["type"] != "rephrase" || recovery["suggestion"] == "" {
+		t.Errorf("expected the policy violation with a rephrase suggestion, got %v", data)
+	}
+}
+
+// TestResearcherExecutor_StreamsResearchRounds verifies that each deep
+// research round is surfaced as its own working status in the A2A stream.
+func TestResearcherExecutor_StreamsResearchRounds(t *testing.T) {
+	mock := &mockPipeline{
+		sequence: []struct{ status, detail string }{
+			{"searching", "q1"},
+			{"structuring", ""},
+			{"researching_round", "Round 2: q2"},
+			{"searching", "q2"},
+			{"researching_round", "Round 3: q3"},
+			{"searching", "q3"},
+			{"writing_report", ""},
+			{"complete", "report.md"},
+		},
+		result: &pipeline.Result{ReportMDKey: "report.md", ReportJSONKey: "report.json", Rounds: 3},
+	}
+
+	pub := &mockPublisher{}
+	exec := researcher.New(mock, pub)
+	q := &recordingQueue{}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := exec.Execute(ctx, makeReqCtx("deep topic"), q); err != nil {
+		t.Fatalf("Execute returned unexpected error: %v", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var rounds []string
+	for _, s := range statusEvents(q.events) {
+		if s.Status.State != a2a.TaskStateWorking || s.Status.Message == nil {
+			continue
+		}
+		for _, p := range s.Status.Message.Parts {
+			if tp, ok := p.(a2a.TextPart); ok && strings.HasPrefix(tp.Text, "Deep research") {
+				rounds = append(rounds, tp.Text)
+			}
+		}
+	}
+	if len(rounds) != 2 {
+		t.Errorf("expected 2 deep research round statuses, got %v", rounds)
+	}
+
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	var roundEvents int
+	for _, ev := range pub.events["test-context-id"] {
+		if ev.Type == event.TypeResearchRound {
+			roundEvents++
+		}
+	}
+	if roundEvents != 2 {
+		t.Errorf("expected 2 %s events published, got %d", event.TypeResearchRound, roundEvents)
+	}
+}
+
+// TestResearcherExecutor_StreamsReportArtifact verifies that streamed report
+// text becomes updates of one report artifact: the first chunk creates it,
+// later ones append, and the finished report replaces it as the last chunk.
+// None of it is published to Redis.
+func TestResearcherExecutor_StreamsReportArtifact(t *testing.T) {
+	mock := &mockPipeline{
+		sequence: []struct{ status, detail string }{
+			{"writing_report", ""},
+			{pipeline.StatusReportChunk, "Draft "},
+			{pipeline.StatusReportChunk, "text"},
+			{pipeline.StatusReportRestart, "Polished "},
+			{pipeline.StatusReportChunk, "text"},
+			{pipeline.StatusReportDone, "Polished text [1]"},
+			{"complete", "report.md"},
+		},
+		result: &pipeline.Result{ReportMDKey: "report.md", ReportJSONKey: "report.json"},
+	}
+
+	pub := &mockPublisher{}
+	exec := researcher.New(mock, pub)
+	q := &recordingQueue{}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := exec.Execute(ctx, makeReqCtx("streamed topic"), q); err != nil {
+		t.Fatalf("Execute returned unexpected error: %v", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	type update struct {
+		text         string
+		append, last bool
+	}
+	var updates []update
+	var ids []a2a.ArtifactID
+	for _, e := range q.events {
+		ev, ok := e.(*a2a.TaskArtifactUpdateEvent)
+		if !ok {
+			continue
+		}
+		if ev.TaskID != "test-task-id" || ev.ContextID != "test-context-id" || ev.Artifact.Name != "report" {
+			t.Errorf("unexpected artifact event addressing: %+v", ev)
+		}
+		if !containsID(ids, ev.Artifact.ID) {
+			ids = append(ids, ev.Artifact.ID)
+		}
+		text := ""
+		for _, p := range ev.Artifact.Parts {
+			if tp, ok := p.(a2a.TextPart); ok {
+				text += tp.Text
+			}
+		}
+		updates = append(updates, update{text, ev.Append, ev.LastChunk})
+	}
+
+	want := []update{
+		{"Draft ", false, false},
+		{"text", true, false},
+		{"Polished ", false, false},
+		{"text", true, false},
+		{"Polished text [1]", false, true},
+	}
+	if fmt.Sprint(updates) != fmt.Sprint(want) {
+		t.Errorf("artifact updates = %v, want %v", updates, want)
+	}
+	if len(ids) != 1 {
+		t.Errorf("expected every update to target one artifact, got %v", ids)
+	}
+	if _, ok := q.events[len(q.events)-1].(*a2a.TaskStatusUpdateEvent); !ok {
+		t.Error("expected the completed status to follow the report artifact")
+	}
+
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	for _, ev := range pub.events["test-context-id"] {
+		if strings.Contains(fmt.Sprint(ev.Data), "Polished") {
+			t.Errorf("streamed report text published to Redis: %+v", ev)
+		}
+	}
+}
+
+func containsID(ids []a2a.ArtifactID, id a2a.ArtifactID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// TestResearcherExecutor_PassesOptionsFromDataPart verifies that a research
+// options DataPart is parsed and handed to the pipeline.
+func TestResearcherExecutor_PassesOptionsFromDataPart(t *testing.T) {
+	mock := &mockPipeline{result: &pipeline.Result{ReportMDKey: "report.md"}}
+	exec := researcher.New(mock, &mockPublisher{})
+	reqCtx := makeReqCtx("options topic")
+	reqCtx.Message.Parts = append(reqCtx.Message.Parts, a2a.DataPart{Data: map[string]any{
+		"num_queries":     float64(5),
+		"depth":           float64(2),
+		"language":        "es",
+		"exclude_domains": []any{"Example.com"},
+		"report_format":   "brief",
+	}})
+
+	if err := exec.Execute(context.Background(), reqCtx, &recordingQueue{}); err != nil {
+		t.Fatalf("Execute returned unexpected error: %v", err)
+	}
+
+	got := mock.opts
+	if got.NumQueries != 5 || got.MaxRounds != 2 || got.Language != "es" || got.ReportFormat != "brief" {
+		t.Errorf("options not passed through: %+v", got)
+	}
+	if len(got.ExcludeDomains) != 1 || got.ExcludeDomains[0] != "example.com" {
+		t.Errorf("expected normalized exclude domain, got %v", got.ExcludeDomains)
+	}
+}
+
+// TestResearcherExecutor_RejectsInvalidOptions verifies that options failing
+// schema validation end the task with a QUERY_INVALID error before the
+// pipeline runs.
+func TestResearcherExecutor_RejectsInvalidOptions(t *testing.T) {
+	mock := &mockPipeline{result: &pipeline.Result{}}
+	exec := researcher.New(mock, &mockPublisher{})
+	reqCtx := makeReqCtx("options topic")
+	reqCtx.Message.Parts = append(reqCtx.Message.Parts, a2a.DataPart{Data: map[string]any{
+		"num_queries": float64(50),
+		"colour":      "blue",
+	}})
+	q := &recordingQueue{}
+
+	if err := exec.Execute(context.Background(), reqCtx, q); err != nil {
+		t.Fatalf("Execute returned unexpected error: %v", err)
+	}
+
+	statuses := statusEvents(q.events)
+	if len(statuses) != 1 || statuses[0].Status.State != a2a.TaskStateFailed {
+		t.Fatalf("expected a single failed status, got %v", statuses)
+	}
+	var code any
+	for _, p := range statuses[0].Status.Message.Parts {
+		if dp, ok := p.(a2a.DataPart); ok {
+			code = dp.Data["code"]
+		}
+	}
+	if fmt.Sprint(code) != "QUERY_INVALID" {
+		t.Errorf("expected QUERY_INVALID error code, got %v", code)
+	}
+}
+
+// TestResearcherExecutor_CancelStopsPipeline verifies that Cancel cancels the
+// context of the task's running pipeline, and that the canceled run writes
+// only the canceled event.
+func TestResearcherExecutor_CancelStopsPipeline(t *testing.T) {
+	mock := &mockPipeline{started: make(chan struct{})}
+	exec := researcher.New(mock, nil)
+	q := &recordingQueue{}
+	reqCtx := makeReqCtx("Test Topic")
+
+	done := make(chan error, 1)
+	go func() { done <- exec.Execute(context.Background(), reqCtx, q) }()
+	<-mock.started
+
+	if err := exec.Cancel(context.Background(), reqCtx, q); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Execute returned error after cancel: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("pipeline did not stop after Cancel")
+	}
+
+	events := statusEvents(q.events)
+	if len(events) != 1 || events[0].Status.State != a2a.TaskStateCanceled || !events[0].Final {
+		t.Errorf("expected a single final canceled event, got %+v", events)
+	}
+}
+
+// TestResearcherExecutor_FinalDataIncludesTokenUsage verifies that the final
+// DataPart carries token usage totals, the per-stage breakdown and telemetry.
+func TestResearcherExecutor_FinalDataIncludesTokenUsage(t *testing.T) {
+	mock := &mockPipeline{
+		result: &pipeline.Result{
+			Usage: []event.TokenUsage{
+				{Stage: "queries", Calls: 1, PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110, CostUSD: 0.001},
+				{Stage: "report", Calls: 1, PromptTokens: 400, CompletionTokens: 200, TotalTokens: 600, CostUSD: 0.004},
+			},
+			SkippedStages:    []string{"summary"},
+			StructureRepairs: 1,
+		},
+	}
+	exec := researcher.New(mock, nil)
+	q := &recordingQueue{}
+	if err := exec.Execute(context.Background(), makeReqCtx("test topic"), q); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	statuses := statusEvents(q.events)
+	last := statuses[len(statuses)-1]
+	dp, ok := last.Status.Message.Parts[0].(a2a.DataPart)
+	if !ok {
+		t.Fatalf("expected DataPart in final event, got %v", last.Status.Message.Parts)
+	}
+	usage, ok := dp.Data["token_usage"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected token_usage in final data, got %v", dp.Data)
+	}
+	if usage["total_tokens"] != 710 || usage["prompt_tokens"] != 500 || usage["completion_tokens"] != 210 {
+		t.Errorf("unexpected usage totals: %v", usage)
+	}
+	if stages, _ := usage["by_stage"].([]any); len(stages) != 2 || stages[1].(map[string]any)["stage"] != "report" {
+		t.Errorf("unexpected per-stage usage: %v", usage["by_stage"])
+	}
+	if skipped, _ := dp.Data["skipped_stages"].([]any); len(skipped) != 1 || skipped[0] != "summary" {
+		t.Errorf("expected skipped_stages [summary], got %v", dp.Data["skipped_stages"])
+	}
+	if telemetry, _ := dp.Data["telemetry"].(map[string]any); telemetry["structure_repairs"] != 1 {
+		t.Errorf("expected structure_repairs 1 in telemetry, got %v", dp.Data["telemetry"])
+	}
+}
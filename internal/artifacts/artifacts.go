@@ -1,13 +1,46 @@
 package artifacts
 
 import (
+	"time"
+
 	"github.com/user/research-assistant/internal/event"
 )
 
+// ReportTitle is the Markdown title line the report blob starts with.
+const ReportTitle = "RESEARCH REPORT\n===============\n"
+
+// ReferencesHeading starts the references section appended to the report.
+const ReferencesHeading = "## References"
+
 type Bundle struct {
-	Topic      string                   `json:"topic"`
+	Topic string `json:"topic"`
+	// Language is the ISO 639-1 code of the report and summary.
+	Language   string                   `json:"language,omitempty"`
 	Summary    string                   `json:"summary"`
 	Report     string                   `json:"report"`
 	Sources    []event.SearchSource     `json:"sources"`
 	Structured event.StructuredResearch `json:"structured"`
+	Citations  []Citation               `json:"citations"`
+	// Comparison is the subject × criterion matrix of a comparison run.
+	Comparison *event.ComparisonMatrix `json:"comparison,omitempty"`
+	// RemovedCitations lists citation numbers the report used that matched no
+	// source and were removed from the text.
+	RemovedCitations []int `json:"removed_citations,omitempty"`
+	// PromptVersions records the version of each prompt template used to
+	// produce the report, keyed by template name.
+	PromptVersions map[string]string `json:"prompt_versions,omitempty"`
+	// Renders maps renderer names, e.g. "html", to the blob keys of the
+	// report rendered in that format.
+	Renders map[string]string `json:"renders,omitempty"`
+}
+
+// Citation maps a reference number used in the report to its source.
+type Citation struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Title  string `json:"title,omitempty"`
+	// Credibility is the source's credibility score, from 0 to 1.
+	Credibility float64 `json:"credibility,omitempty"`
+	// Published is the source's publication date, when known.
+	Published time.Time `json:"published,omitzero"`
 }
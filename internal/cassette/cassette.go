@@ -0,0 +1,149 @@
+// Package cassette records the LLM, search and fetch calls of a research
+// session and replays them, so that a session can be re-run offline and
+// deterministically for debugging and regression tests.
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/storage"
+)
+
+// Kinds of LLM call, matching the pipeline's optional client interfaces.
+const (
+	KindText   = "text"
+	KindJSON   = "json"
+	KindStream = "stream"
+)
+
+// Cassette holds the calls made by one research session.
+type Cassette struct {
+	SessionID string `json:"session_id"`
+	Topic     string `json:"topic"`
+	// Options is the request's override of the pipeline defaults.
+	Options pipeline.Options `json:"options"`
+	// Settings is the configuration the session ran with. Cassettes
+	// recorded without it replay with the replaying pipeline's own.
+	Settings   *pipeline.Settings `json:"settings,omitempty"`
+	RecordedAt time.Time          `json:"recorded_at"`
+	LLM        []LLMCall          `json:"llm"`
+	Search     []SearchCall       `json:"search"`
+	Fetch      []FetchCall        `json:"fetch"`
+
+	mu sync.Mutex
+}
+
+// LLMCall is one prompt and the response to it.
+type LLMCall struct {
+	Kind     string `json:"kind"`
+	Prompt   string `json:"prompt"`
+	Response string `json:"response"`
+	// Streamed is set when the response was delivered in chunks.
+	Streamed bool      `json:"streamed,omitempty"`
+	Usage    llm.Usage `json:"usage"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// SearchCall is one search query and its results.
+type SearchCall struct {
+	Query   string                  `json:"query"`
+	Options pipeline.SearchOptions  `json:"options"`
+	Results []pipeline.SearchResult `json:"results"`
+	Error   string                  `json:"error,omitempty"`
+}
+
+// FetchCall is one page download.
+type FetchCall struct {
+	URL       string    `json:"url"`
+	Text      string    `json:"text"`
+	Published time.Time `json:"published,omitzero"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// New returns an empty cassette for a session started with the given topic
+// and request options.
+func New(sessionID, topic string, opts pipeline.Options) *Cassette {
+	return &Cassette{SessionID: sessionID, Topic: topic, Options: opts, RecordedAt: time.Now().UTC()}
+}
+
+// Configure sets p up to replay c: with the recorded settings, when c has
+// them, and with the credibility and freshness clocks stopped at the time of
+// the recording, since source scores and dates are part of recorded prompts.
+func (c *Cassette) Configure(p *pipeline.Pipeline) error {
+	settings := p.Settings(pipeline.Options{})
+	if c.Settings != nil {
+		settings = *c.Settings
+	}
+	recorded := func() time.Time { return c.RecordedAt }
+	settings.Credibility.Now = recorded
+	if err := p.ApplySettings(settings); err != nil {
+		return fmt.Errorf("apply cassette settings: %w", err)
+	}
+	p.SetClock(recorded)
+	return nil
+}
+
+// Load decodes a cassette written by Save.
+func Load(data []byte) (*Cassette, error) {
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("decode cassette: %w", err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to the blob store as JSON and returns its key.
+func (c *Cassette) Save(blobs storage.BlobStorage) (string, error) {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("encode cassette: %w", err)
+	}
+	return blobs.SaveBlob("cassette-"+c.SessionID, data, "json")
+}
+
+func (c *Cassette) addLLM(call LLMCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.LLM = append(c.LLM, call)
+}
+
+func (c *Cassette) addSearch(call SearchCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Search = append(c.Search, call)
+}
+
+func (c *Cassette) addFetch(call FetchCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Fetch = append(c.Fetch, call)
+}
+
+type contextKey struct{}
+
+// WithCassette returns a context under which the recording wrappers record
+// into c.
+func WithCassette(ctx context.Context, c *Cassette) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the cassette recorded into under ctx, if any.
+func FromContext(ctx context.Context) (*Cassette, bool) {
+	c, ok := ctx.Value(contextKey{}).(*Cassette)
+	return c, ok
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
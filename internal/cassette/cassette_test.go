@@ -0,0 +1,276 @@
+package cassette_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/user/research-assistant/internal/cassette"
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/prompts"
+	"github.com/user/research-assistant/internal/storage"
+)
+
+// streamingLLM answers by prompt content and streams the report in two
+// chunks.
+type streamingLLM struct {
+	calls int32
+}
+
+func (m *streamingLLM) GenerateContent(_ context.Context, prompt string) (string, error) {
+	atomic.AddInt32(&m.calls, 1)
+	switch {
+	case strings.Contains(prompt, "specific search queries"):
+		return `["go generics", "go iterators"]`, nil
+	case strings.Contains(prompt, "Convert the search results"):
+		return `{"topic": "Go", "key_findings": [{"finding": "Generics landed in 1.18", "confidence": 0.8, "evidence_urls": ["http://a.com/go generics"]}]}`, nil
+	case strings.Contains(prompt, "fact checker"):
+		return `[{"index": 0, "verdict": "supported", "strength": 0.9, "reason": "r"}]`, nil
+	case strings.Contains(prompt, "executive summary"):
+		return "Summary", nil
+	}
+	return "", errors.New("unexpected prompt")
+}
+
+func (m *streamingLLM) GenerateContentStream(_ context.Context, prompt string, onChunk func(string)) (string, llm.Usage, error) {
+	atomic.AddInt32(&m.calls, 1)
+	onChunk("Go added generics ")
+	onChunk("[1].")
+	return "Go added generics [1].", llm.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, nil
+}
+
+func liveSearch(_ context.Context, query string, _ pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
+	return []pipeline.SearchResult{{Content: "about " + query, URL: "http://a.com/" + query, Title: query, Provider: "mock"}}, nil
+}
+
+func liveFetch(_ context.Context, url string) (pipeline.Page, error) {
+	if strings.HasSuffix(url, "iterators") {
+		return pipeline.Page{}, errors.New("status 404")
+	}
+	return pipeline.Page{Text: "page text of " + url, Published: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)}, nil
+}
+
+type nopDB struct{}
+
+func (nopDB) CreateSession(_, _ string) error                          { return nil }
+func (nopDB) UpdateSessionStatus(_, _, _ string) error                 { return nil }
+func (nopDB) SaveFindings(_ string, _ []event.StructuredFinding) error { return nil }
+func (nopDB) SaveOpenQuestions(_ string, _ []string) error             { return nil }
+func (nopDB) SaveSources(_ string, _ []event.SearchSource) error       { return nil }
+func (nopDB) MarkSessionComplete(_, _, _, _ string) error              { return nil }
+func (nopDB) GetSessionStatus(_ string) (string, string, error)        { return "", "", nil }
+func (nopDB) GetSessionArtifacts(_ string) (string, string, error)     { return "", "", nil }
+func (nopDB) DeleteSession(_ string) error                             { return nil }
+func (nopDB) SaveCheckpoint(_, _ string, _ []byte) error               { return nil }
+func (nopDB) LoadCheckpoint(_ string) (string, []byte, error)          { return "", nil, nil }
+func (nopDB) DeleteCheckpoint(_ string) error                          { return nil }
+func (nopDB) ListUnfinishedSessions() ([]storage.SessionRecord, error) { return nil, nil }
+func (nopDB) SaveTokenUsage(_ string, _ []event.TokenUsage) error      { return nil }
+func (nopDB) SavePromptVersions(_ string, _ map[string]string) error   { return nil }
+func (nopDB) SetSessionLanguage(_, _ string) error                     { return nil }
+func (nopDB) SaveRenders(_ string, _ map[string]string) error          { return nil }
+
+// memBlobs keeps saved blobs by key.
+type memBlobs struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (m *memBlobs) SaveBlob(name string, content []byte, extension string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.blobs == nil {
+		m.blobs = make(map[string][]byte)
+	}
+	key := name + "." + extension
+	m.blobs[key] = content
+	return key, nil
+}
+
+func (m *memBlobs) DeleteBlob(key string) error { return nil }
+
+func (m *memBlobs) get(t *testing.T, key string) []byte {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.blobs[key]
+	if !ok {
+		t.Fatalf("blob %s not saved", key)
+	}
+	return data
+}
+
+func newPipeline(lm pipeline.LLMClient, search pipeline.SearchFunc, fetch pipeline.FetchFunc, blobs *memBlobs) *pipeline.Pipeline {
+	p := pipeline.New(lm, search, nopDB{}, blobs)
+	p.SetFetcher(fetch)
+	return p
+}
+
+// TestRecordAndReplay records a session with a streaming LLM, a failing page
+// download and parallel searches, then replays it offline and expects the
+// same report and updates without any live call.
+func TestRecordAndReplay(t *testing.T) {
+	live := &streamingLLM{}
+	blobs := &memBlobs{}
+	p := newPipeline(cassette.LLM(live), cassette.Search(liveSearch), cassette.Fetch(liveFetch), blobs)
+	recorder := cassette.NewRecorder(p, blobs)
+
+	var recordedChunks []string
+	result, err := recorder.RunWithOptions(context.Background(), "s1", "Go", pipeline.Options{}, func(status, detail string) {
+		if status == pipeline.StatusReportChunk || status == pipeline.StatusReportRestart {
+			recordedChunks = append(recordedChunks, detail)
+		}
+	})
+	if err != nil {
+		t.Fatalf("recorded run: %v", err)
+	}
+	recordedReport := string(blobs.get(t, result.ReportMDKey))
+	if len(recordedChunks) != 2 {
+		t.Errorf("expected the live report to stream in 2 chunks, got %v", recordedChunks)
+	}
+
+	c, err := cassette.Load(blobs.get(t, "cassette-s1.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.SessionID != "s1" || c.Topic != "Go" || len(c.Search) != 2 || len(c.Fetch) != 2 || len(c.LLM) != int(live.calls) {
+		t.Fatalf("unexpected cassette: session %q, topic %q, %d LLM calls (%d live), %d searches, %d fetches",
+			c.SessionID, c.Topic, len(c.LLM), live.calls, len(c.Search), len(c.Fetch))
+	}
+
+	calls := live.calls
+	player := c.Replay()
+	replayBlobs := &memBlobs{}
+	var replayedChunks []string
+	replayed, err := newPipeline(player.LLM(), player.Search(), player.Fetch(), replayBlobs).
+		RunWithOptions(context.Background(), c.SessionID, c.Topic, c.Options, func(status, detail string) {
+			if status == pipeline.StatusReportChunk || status == pipeline.StatusReportRestart {
+				replayedChunks = append(replayedChunks, detail)
+			}
+		})
+	if err != nil {
+		t.Fatalf("replayed run: %v", err)
+	}
+	if live.calls != calls {
+		t.Errorf("expected no live LLM calls during replay, got %d", live.calls-calls)
+	}
+	if got := string(replayBlobs.get(t, replayed.ReportMDKey)); got != recordedReport {
+		t.Errorf("replayed report differs:\n%s\nwant:\n%s", got, recordedReport)
+	}
+	if strings.Join(replayedChunks, "") != strings.Join(recordedChunks, "") {
+		t.Errorf("expected the streamed text to replay, got %v", replayedChunks)
+	}
+}
+
+// TestReplay_RestoresSettings records a session run with non-default options
+// and prompts, and expects a pipeline with the defaults to replay it once the
+// cassette has configured it.
+func TestReplay_RestoresSettings(t *testing.T) {
+	blobs := &memBlobs{}
+	p := newPipeline(cassette.LLM(&streamingLLM{}), cassette.Search(liveSearch), cassette.Fetch(liveFetch), blobs)
+	opts := pipeline.DefaultOptions()
+	opts.MaxSources = 5
+	p.SetOptions(opts)
+	registry, err := prompts.FromTexts(map[string]string{prompts.Summary: "Write an executive summary of:\n{{.Report}}{{.Language}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetPrompts(registry)
+	recorded, err := cassette.NewRecorder(p, blobs).RunWithOptions(context.Background(), "s1", "Go", pipeline.Options{}, nil)
+	if err != nil {
+		t.Fatalf("recorded run: %v", err)
+	}
+
+	c, err := cassette.Load(blobs.get(t, "cassette-s1.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.Settings == nil || c.Settings.Options.MaxSources != 5 || !strings.HasPrefix(c.Settings.Prompts[prompts.Summary], "Write an executive summary") {
+		t.Fatalf("expected the run's settings in the cassette, got %+v", c.Settings)
+	}
+
+	player := c.Replay()
+	replay := newPipeline(player.LLM(), player.Search(), player.Fetch(), &memBlobs{})
+	if err := c.Configure(replay); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	replayed, err := replay.RunWithOptions(context.Background(), c.SessionID, c.Topic, c.Options, nil)
+	if err != nil {
+		t.Fatalf("replayed run: %v", err)
+	}
+	if !slices.Equal(stages(replayed.Usage), stages(recorded.Usage)) {
+		t.Errorf("replay made different LLM calls: %v, want %v", replayed.Usage, recorded.Usage)
+	}
+}
+
+func stages(usage []event.TokenUsage) []string {
+	var out []string
+	for _, u := range usage {
+		out = append(out, fmt.Sprintf("%s×%d", u.Stage, u.Calls))
+	}
+	return out
+}
+
+func TestReplay_MissingCall(t *testing.T) {
+	player := cassette.New("s1", "Go", pipeline.Options{}).Replay()
+
+	if _, err := player.LLM().GenerateContent(context.Background(), "unrecorded"); !errors.Is(err, cassette.ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded from the LLM, got %v", err)
+	}
+	if _, err := player.Search()(context.Background(), "q", pipeline.SearchOptions{}); !errors.Is(err, cassette.ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded from search, got %v", err)
+	}
+	if _, err := player.Fetch()(context.Background(), "http://a.com"); !errors.Is(err, cassette.ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded from fetch, got %v", err)
+	}
+}
+
+func TestReplay_RecordedErrorsAndRepeats(t *testing.T) {
+	c := cassette.New("s1", "Go", pipeline.Options{})
+	ctx := cassette.WithCassette(context.Background(), c)
+	answers := []string{"first", "second"}
+	var n int
+	lm := cassette.LLM(llmFunc(func(prompt string) (string, error) {
+		n++
+		if n > len(answers) {
+			return "", errors.New("overloaded")
+		}
+		return answers[n-1], nil
+	}))
+	for range 3 {
+		_, _ = lm.GenerateContent(ctx, "p")
+	}
+
+	replay := c.Replay().LLM()
+	for i, want := range []string{"first", "second"} {
+		if got, err := replay.GenerateContent(context.Background(), "p"); err != nil || got != want {
+			t.Errorf("call %d: want %q, got %q, %v", i, want, got, err)
+		}
+	}
+	for i := range 2 {
+		if _, err := replay.GenerateContent(context.Background(), "p"); err == nil || err.Error() != "overloaded" {
+			t.Errorf("repeat %d: expected the recorded error, got %v", i, err)
+		}
+	}
+}
+
+func TestLLM_PassesThroughWithoutCassette(t *testing.T) {
+	lm := cassette.LLM(llmFunc(func(string) (string, error) { return "ok", nil }))
+	if got, err := lm.GenerateContent(context.Background(), "p"); err != nil || got != "ok" {
+		t.Errorf("want ok, got %q, %v", got, err)
+	}
+}
+
+type llmFunc func(prompt string) (string, error)
+
+func (f llmFunc) GenerateContent(_ context.Context, prompt string) (string, error) {
+	return f(prompt)
+}
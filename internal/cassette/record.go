@@ -0,0 +1,136 @@
+package cassette
+
+import (
+	"context"
+	"log"
+
+	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/storage"
+)
+
+// recordingLLM records every call made under a context carrying a cassette.
+// It implements all of the pipeline's optional client interfaces and falls
+// back for the ones the wrapped client lacks the way the pipeline itself
+// does, so wrapping a client does not change how it is used.
+type recordingLLM struct {
+	client pipeline.LLMClient
+}
+
+// LLM wraps client so that its calls are recorded into the cassette of the
+// call's context. Calls under contexts without a cassette pass through.
+func LLM(client pipeline.LLMClient) pipeline.LLMClient {
+	return &recordingLLM{client: client}
+}
+
+func (r *recordingLLM) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	text, _, err := r.GenerateContentWithUsage(ctx, prompt)
+	return text, err
+}
+
+func (r *recordingLLM) GenerateContentWithUsage(ctx context.Context, prompt string) (string, llm.Usage, error) {
+	text, usage, err := r.generate(ctx, prompt)
+	record(ctx, LLMCall{Kind: KindText, Prompt: prompt, Response: text, Usage: usage, Error: errorText(err)})
+	return text, usage, err
+}
+
+func (r *recordingLLM) GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, llm.Usage, error) {
+	var text string
+	var usage llm.Usage
+	var err error
+	if c, ok := r.client.(pipeline.JSONLLMClient); ok {
+		text, usage, err = c.GenerateJSON(ctx, prompt, schema)
+	} else {
+		text, usage, err = r.generate(ctx, prompt)
+	}
+	record(ctx, LLMCall{Kind: KindJSON, Prompt: prompt, Response: text, Usage: usage, Error: errorText(err)})
+	return text, usage, err
+}
+
+func (r *recordingLLM) GenerateContentStream(ctx context.Context, prompt string, onChunk func(string)) (string, llm.Usage, error) {
+	var text string
+	var usage llm.Usage
+	var err error
+	streamed := false
+	if c, ok := r.client.(pipeline.StreamingLLMClient); ok {
+		text, usage, err = c.GenerateContentStream(ctx, prompt, func(chunk string) {
+			streamed = true
+			onChunk(chunk)
+		})
+	} else {
+		text, usage, err = r.generate(ctx, prompt)
+	}
+	record(ctx, LLMCall{Kind: KindStream, Prompt: prompt, Response: text, Streamed: streamed, Usage: usage, Error: errorText(err)})
+	return text, usage, err
+}
+
+func (r *recordingLLM) generate(ctx context.Context, prompt string) (string, llm.Usage, error) {
+	if c, ok := r.client.(pipeline.UsageLLMClient); ok {
+		return c.GenerateContentWithUsage(ctx, prompt)
+	}
+	text, err := r.client.GenerateContent(ctx, prompt)
+	return text, llm.Usage{}, err
+}
+
+func record(ctx context.Context, call LLMCall) {
+	if c, ok := FromContext(ctx); ok {
+		c.addLLM(call)
+	}
+}
+
+// Search wraps search so that its calls are recorded into the cassette of
+// the call's context.
+func Search(search pipeline.SearchFunc) pipeline.SearchFunc {
+	return func(ctx context.Context, query string, opts pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
+		results, err := search(ctx, query, opts)
+		if c, ok := FromContext(ctx); ok {
+			c.addSearch(SearchCall{Query: query, Options: opts, Results: results, Error: errorText(err)})
+		}
+		return results, err
+	}
+}
+
+// Fetch wraps fetch so that its calls are recorded into the cassette of the
+// call's context.
+func Fetch(fetch pipeline.FetchFunc) pipeline.FetchFunc {
+	return func(ctx context.Context, url string) (pipeline.Page, error) {
+		page, err := fetch(ctx, url)
+		if c, ok := FromContext(ctx); ok {
+			c.addFetch(FetchCall{URL: url, Text: page.Text, Published: page.Published, Error: errorText(err)})
+		}
+		return page, err
+	}
+}
+
+// SessionRunner runs a research session and reports the settings it runs
+// with. It is implemented by pipeline.Pipeline.
+type SessionRunner interface {
+	RunWithOptions(ctx context.Context, sessionID, topic string, opts pipeline.Options, onUpdate func(status, detail string)) (*pipeline.Result, error)
+	Settings(override pipeline.Options) pipeline.Settings
+}
+
+// Recorder runs sessions under a fresh cassette each and saves the cassette
+// to the blob store when the session ends, whatever its outcome. Only calls
+// made through the LLM, Search and Fetch wrappers are recorded.
+type Recorder struct {
+	runner SessionRunner
+	blobs  storage.BlobStorage
+}
+
+// NewRecorder returns a Recorder running sessions through runner.
+func NewRecorder(runner SessionRunner, blobs storage.BlobStorage) *Recorder {
+	return &Recorder{runner: runner, blobs: blobs}
+}
+
+func (r *Recorder) RunWithOptions(ctx context.Context, sessionID, topic string, opts pipeline.Options, onUpdate func(status, detail string)) (*pipeline.Result, error) {
+	c := New(sessionID, topic, opts)
+	settings := r.runner.Settings(opts)
+	c.Settings = &settings
+	result, err := r.runner.RunWithOptions(WithCassette(ctx, c), sessionID, topic, opts, onUpdate)
+	if key, saveErr := c.Save(r.blobs); saveErr != nil {
+		log.Printf("[CASSETTE] %s failed to save cassette: %v", sessionID, saveErr)
+	} else {
+		log.Printf("[CASSETTE] %s recorded to %s", sessionID, key)
+	}
+	return result, err
+}
@@ -0,0 +1,153 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// ErrNotRecorded is returned by replayed calls the cassette has no entry for.
+var ErrNotRecorded = errors.New("call not recorded in cassette")
+
+// Player answers LLM, search and fetch calls from a cassette instead of the
+// live services. Calls are matched by their request rather than by order, so
+// that stages running calls in parallel replay correctly. A request made more
+// often than it was recorded gets its last recorded response again.
+type Player struct {
+	mu       sync.Mutex
+	llm      map[string][]LLMCall
+	searches map[string][]SearchCall
+	fetches  map[string][]FetchCall
+}
+
+// Replay returns a Player for the calls recorded in c.
+func (c *Cassette) Replay() *Player {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := &Player{
+		llm:      make(map[string][]LLMCall),
+		searches: make(map[string][]SearchCall),
+		fetches:  make(map[string][]FetchCall),
+	}
+	for _, call := range c.LLM {
+		key := llmKey(call.Kind, call.Prompt)
+		p.llm[key] = append(p.llm[key], call)
+	}
+	for _, call := range c.Search {
+		key := pipeline.SearchCacheKey(call.Query, call.Options)
+		p.searches[key] = append(p.searches[key], call)
+	}
+	for _, call := range c.Fetch {
+		p.fetches[call.URL] = append(p.fetches[call.URL], call)
+	}
+	return p
+}
+
+// next pops the first recorded call for key, keeping the last one.
+func next[T any](calls map[string][]T, key string) (T, bool) {
+	queue := calls[key]
+	if len(queue) == 0 {
+		var zero T
+		return zero, false
+	}
+	if len(queue) > 1 {
+		calls[key] = queue[1:]
+	}
+	return queue[0], true
+}
+
+func llmKey(kind, prompt string) string {
+	return kind + "\x00" + prompt
+}
+
+func (p *Player) llmCall(kind, prompt string) (LLMCall, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	call, ok := next(p.llm, llmKey(kind, prompt))
+	if !ok {
+		return LLMCall{}, fmt.Errorf("%w: %s prompt %q", ErrNotRecorded, kind, excerpt(prompt))
+	}
+	if call.Error != "" {
+		return call, errors.New(call.Error)
+	}
+	return call, nil
+}
+
+// LLM returns a client answering from the cassette.
+func (p *Player) LLM() pipeline.LLMClient {
+	return &replayLLM{player: p}
+}
+
+type replayLLM struct {
+	player *Player
+}
+
+func (r *replayLLM) GenerateContent(ctx context.Context, prompt string) (string, error) {
+	text, _, err := r.GenerateContentWithUsage(ctx, prompt)
+	return text, err
+}
+
+func (r *replayLLM) GenerateContentWithUsage(_ context.Context, prompt string) (string, llm.Usage, error) {
+	call, err := r.player.llmCall(KindText, prompt)
+	return call.Response, call.Usage, err
+}
+
+func (r *replayLLM) GenerateJSON(_ context.Context, prompt string, _ map[string]any) (string, llm.Usage, error) {
+	call, err := r.player.llmCall(KindJSON, prompt)
+	return call.Response, call.Usage, err
+}
+
+// GenerateContentStream delivers a response that was streamed when recorded
+// as a single chunk.
+func (r *replayLLM) GenerateContentStream(_ context.Context, prompt string, onChunk func(string)) (string, llm.Usage, error) {
+	call, err := r.player.llmCall(KindStream, prompt)
+	if call.Streamed && call.Response != "" {
+		onChunk(call.Response)
+	}
+	return call.Response, call.Usage, err
+}
+
+// Search returns a search function answering from the cassette.
+func (p *Player) Search() pipeline.SearchFunc {
+	return func(_ context.Context, query string, opts pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		call, ok := next(p.searches, pipeline.SearchCacheKey(query, opts))
+		if !ok {
+			return nil, fmt.Errorf("%w: search %q", ErrNotRecorded, query)
+		}
+		if call.Error != "" {
+			return nil, errors.New(call.Error)
+		}
+		return call.Results, nil
+	}
+}
+
+// Fetch returns a fetch function answering from the cassette.
+func (p *Player) Fetch() pipeline.FetchFunc {
+	return func(_ context.Context, url string) (pipeline.Page, error) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		call, ok := next(p.fetches, url)
+		if !ok {
+			return pipeline.Page{}, fmt.Errorf("%w: fetch %s", ErrNotRecorded, url)
+		}
+		if call.Error != "" {
+			return pipeline.Page{}, errors.New(call.Error)
+		}
+		return pipeline.Page{Text: call.Text, Published: call.Published}, nil
+	}
+}
+
+// excerpt shortens a prompt for error messages.
+func excerpt(prompt string) string {
+	const limit = 80
+	if r := []rune(prompt); len(r) > limit {
+		return string(r[:limit]) + "…"
+	}
+	return prompt
+}
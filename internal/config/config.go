@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -32,6 +34,63 @@ func GetEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// GetEnvInt returns an integer environment variable or defaultValue when it
+// is unset or not a valid integer.
+func GetEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s=%q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// GetEnvFloat returns a float environment variable or defaultValue when it
+// is unset or not a valid number.
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid number for %s=%q, using default %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return f
+}
+
+// GetEnvBool returns a boolean environment variable or defaultValue when it
+// is unset or not a valid boolean.
+func GetEnvBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s=%q, using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+// GetEnvList returns a comma-separated environment variable as a list,
+// dropping blank entries. It returns nil when the variable is unset or empty.
+func GetEnvList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 // GetRequiredEnv returns an environment variable or logs a fatal error if missing.
 func GetRequiredEnv(key string) string {
 	value := os.Getenv(key)
@@ -0,0 +1,18 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/user/research-assistant/internal/config"
+)
+
+func TestGetEnvList(t *testing.T) {
+	t.Setenv("TEST_LIST", " malware, weapons ,,")
+	if got := config.GetEnvList("TEST_LIST"); len(got) != 2 || got[0] != "malware" || got[1] != "weapons" {
+		t.Errorf("GetEnvList = %q", got)
+	}
+	t.Setenv("TEST_LIST", "")
+	if got := config.GetEnvList("TEST_LIST"); got != nil {
+		t.Errorf("expected nil for an empty list, got %q", got)
+	}
+}
@@ -0,0 +1,283 @@
+// Package credibility scores how far a search result can be trusted, from
+// the reputation of its domain, the kind of content it is and its age.
+package credibility
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/research-assistant/internal/config"
+	"github.com/user/research-assistant/internal/event"
+)
+
+// Neutral is the score of a source nothing is known about.
+const Neutral = 0.5
+
+// Score adjustments for the signals a source shows.
+const (
+	trustedBonus      = 0.25
+	lowQualityPenalty = 0.3
+	recentBonus       = 0.1 // published within recentYears
+	datedPenalty      = 0.1 // published more than datedYears ago
+	recentYears       = 2
+	datedYears        = 10
+)
+
+// Content types recognised from a source's URL and title.
+const (
+	TypeResearch      = "research"
+	TypeDocumentation = "documentation"
+	TypeNews          = "news"
+	TypeBlog          = "blog"
+	TypePressRelease  = "press_release"
+	TypeForum         = "forum"
+	TypeSocial        = "social"
+	TypeListicle      = "listicle"
+	TypeOther         = "other"
+)
+
+// typeAdjustments raise or lower the score of each content type.
+var typeAdjustments = map[string]float64{
+	TypeResearch:      0.2,
+	TypeDocumentation: 0.1,
+	TypeNews:          0.05,
+	TypeBlog:          -0.05,
+	TypePressRelease:  -0.1,
+	TypeForum:         -0.15,
+	TypeSocial:        -0.2,
+	TypeListicle:      -0.15,
+}
+
+// DefaultTrusted are the domains DefaultPolicy treats as reputable:
+// government, education and intergovernmental domains, reference works,
+// scientific publishers and wire services.
+var DefaultTrusted = []string{
+	"gov", "edu", "int", "mil", "europa.eu", "gov.uk", "ac.uk",
+	"wikipedia.org", "britannica.com",
+	"nature.com", "science.org", "cell.com", "thelancet.com", "nejm.org", "bmj.com",
+	"springer.com", "sciencedirect.com", "wiley.com", "plos.org", "acm.org", "ieee.org",
+	"arxiv.org", "doi.org", "ncbi.nlm.nih.gov", "oecd.org", "worldbank.org", "imf.org",
+	"reuters.com", "apnews.com",
+}
+
+// DefaultLowQuality are the domains DefaultPolicy treats as unreliable:
+// content farms, question-and-answer sites and image boards.
+var DefaultLowQuality = []string{
+	"pinterest.com", "quora.com", "answers.com", "ehow.com", "wikihow.com",
+	"buzzfeed.com", "scribd.com", "coursehero.com", "studocu.com",
+}
+
+// Policy is the credibility configuration of a pipeline. Domains match
+// themselves and their subdomains; "gov" matches every .gov host.
+type Policy struct {
+	// Allow lists domains whose sources get the full score without further
+	// checks. It takes precedence over the other lists.
+	Allow []string
+	// Trusted lists reputable domains, which raise a source's score.
+	Trusted []string
+	// LowQuality lists unreliable domains, which lower a source's score. It
+	// takes precedence over Trusted.
+	LowQuality []string
+	// Now returns the time recency is measured from. Nil means time.Now.
+	// It is not saved with the policy.
+	Now func() time.Time `json:"-"`
+}
+
+// DefaultPolicy scores sources with the default domain lists.
+func DefaultPolicy() Policy {
+	return Policy{Trusted: DefaultTrusted, LowQuality: DefaultLowQuality}
+}
+
+// PolicyFromEnv returns DefaultPolicy with the domain lists set in
+// CREDIBILITY_ALLOW, CREDIBILITY_TRUSTED and CREDIBILITY_LOW_QUALITY
+// replacing the defaults.
+func PolicyFromEnv() Policy {
+	p := DefaultPolicy()
+	p.Allow = config.GetEnvList("CREDIBILITY_ALLOW")
+	if trusted := config.GetEnvList("CREDIBILITY_TRUSTED"); len(trusted) > 0 {
+		p.Trusted = trusted
+	}
+	if low := config.GetEnvList("CREDIBILITY_LOW_QUALITY"); len(low) > 0 {
+		p.LowQuality = low
+	}
+	return p
+}
+
+// Score rates s between 0 and 1. Sources start at Neutral and are adjusted
+// by their domain's reputation, their content type and, when their
+// publication date is known or can be found in the URL or snippet, their
+// age. Sources on an allowed domain score 1.
+func (p Policy) Score(s event.SearchSource) float64 {
+	host := hostOf(s.URL)
+	if matchesAny(host, p.Allow) {
+		return 1
+	}
+	score := Neutral
+	switch {
+	case matchesAny(host, p.LowQuality):
+		score -= lowQualityPenalty
+	case matchesAny(host, p.Trusted):
+		score += trustedBonus
+	}
+	score += typeAdjustments[ContentType(s.URL, s.Title)]
+	if year, ok := publicationYear(s); ok {
+		switch age := p.now().Year() - year; {
+		case age <= recentYears:
+			score += recentBonus
+		case age > datedYears:
+			score -= datedPenalty
+		}
+	}
+	return math.Round(math.Min(1, math.Max(0, score))*100) / 100
+}
+
+// now returns the current time by the policy's clock.
+func (p Policy) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+var (
+	listiclePattern = regexp.MustCompile(`(?i)\b(top|best|worst)\s+\d+\b|^\d+\s+(best|ways|things|reasons|tips)\b`)
+	pathYearPattern = regexp.MustCompile(`/((?:19|20)\d{2})/(?:0?[1-9]|1[0-2])/`)
+	isoDatePattern  = regexp.MustCompile(`\b((?:19|20)\d{2})-(?:0[1-9]|1[0-2])-(?:0[1-9]|[12]\d|3[01])\b`)
+	textDatePattern = regexp.MustCompile(`\b(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Sept|Oct|Nov|Dec)[a-z]*\.? \d{1,2}, ((?:19|20)\d{2})\b`)
+)
+
+// hostTypes are sites whose content type is known from the host alone,
+// checked in order. A domain must come before any parent domain listed.
+var hostTypes = []struct{ domain, contentType string }{
+	{"pubmed.ncbi.nlm.nih.gov", TypeResearch},
+	{"arxiv.org", TypeResearch},
+	{"doi.org", TypeResearch},
+	{"ssrn.com", TypeResearch},
+	{"researchgate.net", TypeResearch},
+	{"semanticscholar.org", TypeResearch},
+	{"reddit.com", TypeForum},
+	{"stackexchange.com", TypeForum},
+	{"stackoverflow.com", TypeForum},
+	{"quora.com", TypeForum},
+	{"twitter.com", TypeSocial},
+	{"x.com", TypeSocial},
+	{"facebook.com", TypeSocial},
+	{"instagram.com", TypeSocial},
+	{"tiktok.com", TypeSocial},
+	{"youtube.com", TypeSocial},
+	{"medium.com", TypeBlog},
+	{"substack.com", TypeBlog},
+	{"blogspot.com", TypeBlog},
+	{"wordpress.com", TypeBlog},
+	{"prnewswire.com", TypePressRelease},
+	{"businesswire.com", TypePressRelease},
+	{"globenewswire.com", TypePressRelease},
+	{"reuters.com", TypeNews},
+	{"apnews.com", TypeNews},
+	{"bbc.co.uk", TypeNews},
+	{"bbc.com", TypeNews},
+	{"nytimes.com", TypeNews},
+	{"theguardian.com", TypeNews},
+	{"washingtonpost.com", TypeNews},
+}
+
+// pathTypes are URL path fragments that reveal the content type, checked in
+// order.
+var pathTypes = []struct{ fragment, contentType string }{
+	{"/doi/", TypeResearch},
+	{"/abs/", TypeResearch},
+	{"/docs/", TypeDocumentation},
+	{"/documentation/", TypeDocumentation},
+	{"/manual/", TypeDocumentation},
+	{"/reference/", TypeDocumentation},
+	{"/press-release", TypePressRelease},
+	{"/news-release", TypePressRelease},
+	{"/forum", TypeForum},
+	{"/thread", TypeForum},
+	{"/questions/", TypeForum},
+	{"/comments/", TypeForum},
+	{"/blog/", TypeBlog},
+	{"/news/", TypeNews},
+}
+
+// ContentType classifies a source by its URL and title, returning one of
+// the Type constants.
+func ContentType(rawURL, title string) string {
+	if listiclePattern.MatchString(strings.TrimSpace(title)) {
+		return TypeListicle
+	}
+	host := hostOf(rawURL)
+	for _, ht := range hostTypes {
+		if inDomain(host, ht.domain) {
+			return ht.contentType
+		}
+	}
+	switch {
+	case strings.HasPrefix(host, "docs.") || strings.HasPrefix(host, "developer."):
+		return TypeDocumentation
+	case strings.HasPrefix(host, "blog."):
+		return TypeBlog
+	case strings.HasPrefix(host, "forum.") || strings.HasPrefix(host, "community."):
+		return TypeForum
+	}
+	path := "/"
+	if u, err := url.Parse(strings.TrimSpace(rawURL)); err == nil {
+		path = strings.ToLower(u.Path) + "/"
+	}
+	for _, pt := range pathTypes {
+		if strings.Contains(path, pt.fragment) {
+			return pt.contentType
+		}
+	}
+	return TypeOther
+}
+
+// publicationYear returns the year s was published. Without a known
+// publication date it is read from a dated URL path such as /2021/03/, or a
+// date at the start of the snippet as search engines show it.
+func publicationYear(s event.SearchSource) (int, bool) {
+	if !s.Published.IsZero() {
+		return s.Published.Year(), true
+	}
+	if m := pathYearPattern.FindStringSubmatch(s.URL); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		return year, true
+	}
+	head := s.Snippet
+	if len(head) > 40 {
+		head = head[:40]
+	}
+	for _, re := range []*regexp.Regexp{isoDatePattern, textDatePattern} {
+		if m := re.FindStringSubmatch(head); m != nil {
+			year, _ := strconv.Atoi(m[1])
+			return year, true
+		}
+	}
+	return 0, false
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func matchesAny(host string, domains []string) bool {
+	for _, d := range domains {
+		if inDomain(host, d) {
+			return true
+		}
+	}
+	return false
+}
+
+func inDomain(host, domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	return host != "" && domain != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
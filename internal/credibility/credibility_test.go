@@ -0,0 +1,58 @@
+package credibility_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/user/research-assistant/internal/credibility"
+	"github.com/user/research-assistant/internal/event"
+)
+
+func TestPolicy_Score(t *testing.T) {
+	policy := credibility.DefaultPolicy()
+	policy.Allow = []string{"internal.example"}
+	policy.Now = func() time.Time { return time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		name string
+		src  event.SearchSource
+		want float64
+	}{
+		{"unknown", event.SearchSource{URL: "https://example.com/page"}, 0.5},
+		{"trusted tld", event.SearchSource{URL: "https://www.cdc.gov/flu/index.html"}, 0.75},
+		{"trusted research", event.SearchSource{URL: "https://arxiv.org/abs/2401.00001"}, 0.95},
+		{"low quality", event.SearchSource{URL: "https://www.quora.com/What-is-Go"}, 0.05},
+		{"allowed", event.SearchSource{URL: "https://wiki.internal.example/forum/thread/1"}, 1},
+		{"documentation", event.SearchSource{URL: "https://docs.example.com/guide"}, 0.6},
+		{"social", event.SearchSource{URL: "https://x.com/someone/status/1"}, 0.3},
+		{"listicle", event.SearchSource{URL: "https://example.com/a", Title: "Top 10 databases you need"}, 0.35},
+		{"recent blog", event.SearchSource{URL: "https://example.com/blog/2025/04/post"}, 0.55},
+		{"dated news", event.SearchSource{URL: "https://example.com/news/x", Snippet: "Mar 3, 2009 ... The release"}, 0.45},
+		{"iso date", event.SearchSource{URL: "https://example.com/x", Snippet: "2026-01-15 — Released"}, 0.6},
+		{"published", event.SearchSource{URL: "https://example.com/2025/04/x", Published: time.Date(2012, 5, 1, 0, 0, 0, 0, time.UTC)}, 0.4},
+	}
+	for _, c := range cases {
+		if got := policy.Score(c.src); got != c.want {
+			t.Errorf("%s: Score(%s) = %v, want %v", c.name, c.src.URL, got, c.want)
+		}
+	}
+}
+
+func TestContentType(t *testing.T) {
+	cases := map[string]string{
+		"https://doi.org/10.1000/182":                      credibility.TypeResearch,
+		"https://journal.example/doi/10.1/abc":             credibility.TypeResearch,
+		"https://old.reddit.com/r/golang/comments/abc":     credibility.TypeForum,
+		"https://example.com/questions/123/how":            credibility.TypeForum,
+		"https://blog.example.com/hello":                   credibility.TypeBlog,
+		"https://example.com/press-releases/2024-results":  credibility.TypePressRelease,
+		"https://developer.example.com/reference/api":      credibility.TypeDocumentation,
+		"https://www.reuters.com/technology/some-article/": credibility.TypeNews,
+		"https://example.com/about":                        credibility.TypeOther,
+	}
+	for url, want := range cases {
+		if got := credibility.ContentType(url, ""); got != want {
+			t.Errorf("ContentType(%s) = %s, want %s", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// direction tells whether a larger metric value is better (+1) or worse
+// (-1). Metrics without a direction, such as report length, are compared
+// but never count as regressions.
+var direction = map[string]int{
+	"citation_validity":     1,
+	"evidence_coverage":     1,
+	"findings":              1,
+	"hallucinated_url_rate": -1,
+	"json_repair_rate":      -1,
+	"report_words":          0,
+}
+
+// values returns the metrics keyed by their JSON names.
+func (m Metrics) values() map[string]float64 {
+	return map[string]float64{
+		"citation_validity":     m.CitationValidity,
+		"evidence_coverage":     m.EvidenceCoverage,
+		"findings":              float64(m.Findings),
+		"hallucinated_url_rate": m.HallucinatedURLRate,
+		"json_repair_rate":      m.JSONRepairRate,
+		"report_words":          float64(m.ReportWords),
+	}
+}
+
+// Delta is a metric that changed from the baseline.
+type Delta struct {
+	Case       string  `json:"case"`
+	Metric     string  `json:"metric"`
+	Baseline   float64 `json:"baseline"`
+	Current    float64 `json:"current"`
+	Regression bool    `json:"regression"`
+}
+
+// Comparison is the difference between a report and its baseline.
+type Comparison struct {
+	Deltas []Delta `json:"deltas"`
+	// Failed lists cases that completed in the baseline but failed or are
+	// missing now. They count as regressions.
+	Failed      []string `json:"failed,omitempty"`
+	Regressions int      `json:"regressions"`
+}
+
+// LoadReport reads a report written as JSON, such as a saved baseline.
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("decode report: %w", err)
+	}
+	return &r, nil
+}
+
+// Compare lists the metrics of current that differ from baseline, case by
+// case. A metric regresses when it moves in its worse direction by more
+// than tolerance, taken as an absolute difference for rates and relative to
+// the baseline for counts above 1. Cases new since the baseline are skipped.
+func Compare(baseline, current *Report, tolerance float64) Comparison {
+	cmp := Comparison{Deltas: []Delta{}}
+	now := make(map[string]CaseResult)
+	for _, c := range current.Cases {
+		now[c.Name] = c
+	}
+	for _, base := range baseline.Cases {
+		if base.Metrics == nil {
+			continue
+		}
+		cur, ok := now[base.Name]
+		if !ok || cur.Metrics == nil {
+			cmp.Failed = append(cmp.Failed, base.Name)
+			cmp.Regressions++
+			continue
+		}
+		before, after := base.Metrics.values(), cur.Metrics.values()
+		names := make([]string, 0, len(before))
+		for name := range before {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b, a := before[name], after[name]
+			if a == b {
+				continue
+			}
+			d := Delta{Case: base.Name, Metric: name, Baseline: b, Current: a}
+			worse := float64(direction[name]) * (b - a)
+			if worse > tolerance*math.Max(1, math.Abs(b)) {
+				d.Regression = true
+				cmp.Regressions++
+			}
+			cmp.Deltas = append(cmp.Deltas, d)
+		}
+	}
+	return cmp
+}
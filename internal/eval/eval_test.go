@@ -0,0 +1,159 @@
+package eval_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/cassette"
+	"github.com/user/research-assistant/internal/eval"
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/storage"
+)
+
+func TestScore(t *testing.T) {
+	b := artifacts.Bundle{
+		Report: "Go is fast [1]. See https://made-up.example/x and http://a.com/.\n\n" +
+			artifacts.ReferencesHeading + "\n\n1. A — http://a.com",
+		Sources: []event.SearchSource{{URL: "http://a.com"}, {URL: "http://b.com"}},
+		Structured: event.StructuredResearch{KeyFindings: []event.StructuredFinding{
+			{Finding: "F1", EvidenceURLs: []string{"http://a.com"}},
+			{Finding: "F2", EvidenceURLs: []string{"https://www.b.com/"}},
+			{Finding: "F3"},
+			{Finding: "F4"},
+		}},
+		Citations:        []artifacts.Citation{{Number: 1, URL: "http://a.com"}},
+		RemovedCitations: []int{7},
+	}
+	m := eval.Score(&pipeline.Result{Rounds: 2, StructureRepairs: 2}, b)
+
+	want := eval.Metrics{
+		CitationValidity:    0.5,
+		EvidenceCoverage:    0.5,
+		Findings:            4,
+		HallucinatedURLRate: 0.25, // made-up.example among 2 evidence and 2 report URLs
+		JSONRepairRate:      0.5,
+		ReportWords:         8,
+	}
+	if m != want {
+		t.Errorf("Score:\n got %+v\nwant %+v", m, want)
+	}
+}
+
+func TestScore_NothingCited(t *testing.T) {
+	m := eval.Score(nil, artifacts.Bundle{Report: "No sources."})
+	if m.CitationValidity != 0 || m.EvidenceCoverage != 0 || m.HallucinatedURLRate != 0 || m.ReportWords != 2 {
+		t.Errorf("unexpected metrics for an empty run: %+v", m)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	metrics := func(validity float64, findings, words int) *eval.Metrics {
+		return &eval.Metrics{CitationValidity: validity, EvidenceCoverage: 1, Findings: findings, ReportWords: words}
+	}
+	baseline := &eval.Report{Cases: []eval.CaseResult{
+		{Name: "a", Metrics: metrics(1, 10, 500)},
+		{Name: "b", Metrics: metrics(0.9, 4, 300)},
+		{Name: "c", Metrics: metrics(1, 3, 100)},
+		{Name: "broken", Error: "failed"},
+	}}
+	current := &eval.Report{Cases: []eval.CaseResult{
+		// Within tolerance: 10 → 10 findings, validity up, length changed.
+		{Name: "a", Metrics: metrics(1, 10, 900)},
+		// Validity dropped by 0.2 and findings by a quarter.
+		{Name: "b", Metrics: metrics(0.7, 3, 300)},
+		{Name: "c", Error: "overloaded"},
+		{Name: "new", Metrics: metrics(0, 0, 0)},
+	}}
+
+	cmp := eval.Compare(baseline, current, 0.05)
+	if cmp.Regressions != 3 {
+		t.Errorf("expected 3 regressions, got %d: %+v", cmp.Regressions, cmp)
+	}
+	if len(cmp.Failed) != 1 || cmp.Failed[0] != "c" {
+		t.Errorf("expected case c to be reported as failed, got %v", cmp.Failed)
+	}
+	regressed := make(map[string]bool)
+	for _, d := range cmp.Deltas {
+		if d.Regression {
+			regressed[d.Case+"/"+d.Metric] = true
+		}
+	}
+	if len(regressed) != 2 || !regressed["b/citation_validity"] || !regressed["b/findings"] {
+		t.Errorf("unexpected regressions: %v", regressed)
+	}
+	for _, d := range cmp.Deltas {
+		if d.Case == "a" && d.Metric == "report_words" && d.Regression {
+			t.Error("report length must not count as a regression")
+		}
+	}
+}
+
+// TestGoldenSet_MatchesBaseline runs the repository's golden set against the
+// fake providers and expects the saved baseline. Regenerate it with
+// go run ./cmd/eval -update-baseline when a change is intended.
+func TestGoldenSet_MatchesBaseline(t *testing.T) {
+	set, err := eval.LoadGoldenSet("../../eval/golden.json")
+	if err != nil {
+		t.Fatalf("LoadGoldenSet: %v", err)
+	}
+	report, err := eval.Run(context.Background(), set)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, c := range report.Cases {
+		if c.Error != "" {
+			t.Errorf("case %s failed: %s", c.Name, c.Error)
+		}
+	}
+	baseline, err := eval.LoadReport("../../eval/baseline.json")
+	if err != nil {
+		t.Fatalf("LoadReport: %v", err)
+	}
+	if cmp := eval.Compare(baseline, report, 0); len(cmp.Deltas) > 0 || len(cmp.Failed) > 0 {
+		t.Errorf("golden set differs from the baseline: %+v", cmp)
+	}
+}
+
+// TestRun_ReplaysCassette verifies that a case with a cassette replays the
+// recorded session, taking its topic and options from the cassette.
+func TestRun_ReplaysCassette(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.NewSQLiteStore(filepath.Join(dir, "research.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer db.Close()
+	blobs, err := storage.NewDiskBlobStore(dir)
+	if err != nil {
+		t.Fatalf("NewDiskBlobStore: %v", err)
+	}
+	p := pipeline.New(cassette.LLM(eval.FakeLLM{Topic: "Go generics"}), cassette.Search(eval.FakeSearch()), db, blobs)
+	if _, err := cassette.NewRecorder(p, blobs).RunWithOptions(context.Background(), "s1", "Go generics", pipeline.Options{NumQueries: 2}, nil); err != nil {
+		t.Fatalf("recorded run: %v", err)
+	}
+	recorded, _ := filepath.Glob(filepath.Join(dir, "cassette-s1-*.json"))
+	if len(recorded) != 1 {
+		t.Fatalf("expected one cassette, got %v", recorded)
+	}
+	golden := `{"cases": [{"name": "recorded", "cassette": "` + filepath.Base(recorded[0]) + `"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "golden.json"), []byte(golden), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := eval.LoadGoldenSet(filepath.Join(dir, "golden.json"))
+	if err != nil {
+		t.Fatalf("LoadGoldenSet: %v", err)
+	}
+	report, err := eval.Run(context.Background(), set)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got := report.Cases[0]
+	if got.Provider != "cassette" || got.Topic != "Go generics" || got.Error != "" || got.Metrics == nil || got.Metrics.Findings != 3 {
+		t.Errorf("unexpected replayed case: %+v", got)
+	}
+}
@@ -0,0 +1,157 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// maxFakeFindings keeps fake runs on the single-pass report path.
+const maxFakeFindings = 3
+
+var (
+	queryCountPattern = regexp.MustCompile(`generate (\d+) specific search queries`)
+	followUpPattern   = regexp.MustCompile(`Write up to (\d+) new web search queries`)
+	sourceURLPattern  = regexp.MustCompile(`(?m)^- Source: (\S+)`)
+	verifyPattern     = regexp.MustCompile(`(?m)^Finding (\d+):`)
+	reportSrcPattern  = regexp.MustCompile(`(?m)^\[(\d+)\] `)
+	findingPattern    = regexp.MustCompile(`"finding": "((?:[^"\\]|\\.)*)"`)
+	subjectPattern    = regexp.MustCompile(`"([^"]+)"`)
+)
+
+// FakeLLM is a deterministic stand-in for the LLM. It recognises the
+// pipeline's prompts by their wording and answers from what the prompt
+// contains: findings cite the sources listed in the structuring prompt and
+// the report cites the numbered sources. It measures how the pipeline handles
+// well-formed answers, not how good a model's answers are.
+type FakeLLM struct {
+	Topic string
+}
+
+func (f FakeLLM) GenerateContent(_ context.Context, prompt string) (string, error) {
+	switch {
+	case strings.HasPrefix(prompt, "You are a content moderator"):
+		return `{"allowed": true}`, nil
+	case queryCountPattern.MatchString(prompt):
+		n, _ := strconv.Atoi(queryCountPattern.FindStringSubmatch(prompt)[1])
+		return f.queries("", n), nil
+	case followUpPattern.MatchString(prompt):
+		n, _ := strconv.Atoi(followUpPattern.FindStringSubmatch(prompt)[1])
+		return f.queries("follow-up ", n), nil
+	case strings.Contains(prompt, "Convert the search results"):
+		return f.structure(prompt), nil
+	case strings.HasPrefix(prompt, "You are a fact checker"):
+		var verdicts []map[string]any
+		for _, m := range verifyPattern.FindAllStringSubmatch(prompt, -1) {
+			n, _ := strconv.Atoi(m[1])
+			verdicts = append(verdicts, map[string]any{"index": n, "verdict": "supported", "strength": 0.8, "reason": "The evidence states it."})
+		}
+		out, _ := json.Marshal(verdicts)
+		return string(out), nil
+	case strings.Contains(prompt, "Write a comprehensive report"):
+		return f.report(prompt), nil
+	case strings.HasPrefix(prompt, "Create a short executive summary"):
+		return fmt.Sprintf("- Research on %s found consistent evidence.\n- See the report for details.", f.Topic), nil
+	case strings.HasPrefix(prompt, "You are a research analyst. Compare"):
+		return f.compare(prompt), nil
+	}
+	return "", fmt.Errorf("fake LLM: unrecognised prompt %q", firstLine(prompt))
+}
+
+func (f FakeLLM) queries(prefix string, n int) string {
+	aspects := []string{"overview", "evidence", "challenges", "outlook", "history"}
+	var queries []string
+	for i := 0; i < n && i < len(aspects); i++ {
+		queries = append(queries, prefix+f.Topic+" "+aspects[i])
+	}
+	out, _ := json.Marshal(queries)
+	return string(out)
+}
+
+func (f FakeLLM) structure(prompt string) string {
+	type finding struct {
+		Finding      string   `json:"finding"`
+		EvidenceURLs []string `json:"evidence_urls"`
+		Confidence   float64  `json:"confidence"`
+	}
+	var findings []finding
+	for _, m := range sourceURLPattern.FindAllStringSubmatch(prompt, maxFakeFindings) {
+		findings = append(findings, finding{
+			Finding:      fmt.Sprintf("%s is documented by source %d", f.Topic, len(findings)+1),
+			EvidenceURLs: []string{m[1]},
+			Confidence:   0.7,
+		})
+	}
+	out, _ := json.Marshal(map[string]any{
+		"topic":          f.Topic,
+		"key_findings":   findings,
+		"challenges":     []string{"Evidence is limited to a few sources."},
+		"open_questions": []string{"How does " + f.Topic + " develop over time?"},
+	})
+	return string(out)
+}
+
+func (f FakeLLM) report(prompt string) string {
+	sources := len(reportSrcPattern.FindAllString(prompt, -1))
+	var sb strings.Builder
+	sb.WriteString("## Key insights\n\n")
+	for i, m := range findingPattern.FindAllStringSubmatch(prompt, -1) {
+		text, err := strconv.Unquote(`"` + m[1] + `"`)
+		if err != nil {
+			text = m[1]
+		}
+		sb.WriteString("- " + text)
+		if sources > 0 {
+			sb.WriteString(fmt.Sprintf(" [%d]", i%sources+1))
+		}
+		sb.WriteString(".\n")
+	}
+	sb.WriteString("\n## Conclusion\n\nThe sources agree on the main points about " + f.Topic + ".\n")
+	return sb.String()
+}
+
+// compare rates every subject named in the prompt's first line on two
+// criteria, citing the first source.
+func (f FakeLLM) compare(prompt string) string {
+	criteria := []string{"maturity", "performance"}
+	var cells []map[string]any
+	for _, m := range subjectPattern.FindAllStringSubmatch(firstLine(prompt), -1) {
+		for _, c := range criteria {
+			cells = append(cells, map[string]any{"subject": m[1], "criterion": c, "assessment": "Well documented", "sources": []int{1}})
+		}
+	}
+	out, _ := json.Marshal(map[string]any{"criteria": criteria, "cells": cells})
+	return string(out)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// FakeSearch returns a deterministic search function with opts.Num results
+// per query, at most five, on example.org.
+func FakeSearch() pipeline.SearchFunc {
+	return func(_ context.Context, query string, opts pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
+		n := opts.Num
+		if n <= 0 || n > 5 {
+			n = 5
+		}
+		slug := strings.Join(strings.Fields(strings.ToLower(query)), "-")
+		results := make([]pipeline.SearchResult, n)
+		for i := range results {
+			results[i] = pipeline.SearchResult{
+				Content:  fmt.Sprintf("%s: evidence item %d.", query, i+1),
+				URL:      fmt.Sprintf("https://example.org/%s/%d", slug, i+1),
+				Title:    fmt.Sprintf("%s (%d)", query, i+1),
+				Provider: "fake",
+			}
+		}
+		return results, nil
+	}
+}
@@ -0,0 +1,92 @@
+// Package eval scores research runs on report quality, so that prompt and
+// model changes can be compared against a saved baseline.
+package eval
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// Metrics scores one research run.
+type Metrics struct {
+	// CitationValidity is the share of the citation numbers used in the
+	// report that matched a source. A report citing nothing scores 0.
+	CitationValidity float64 `json:"citation_validity"`
+	// EvidenceCoverage is the share of key findings with at least one
+	// evidence URL.
+	EvidenceCoverage float64 `json:"evidence_coverage"`
+	// Findings is the number of key findings.
+	Findings int `json:"findings"`
+	// HallucinatedURLRate is the share of the URLs in the report text and
+	// the findings' evidence that match no source.
+	HallucinatedURLRate float64 `json:"hallucinated_url_rate"`
+	// JSONRepairRate is the share of structuring attempts that produced
+	// invalid output and had to be re-prompted.
+	JSONRepairRate float64 `json:"json_repair_rate"`
+	// ReportWords is the length of the report in words, references excluded.
+	ReportWords int `json:"report_words"`
+}
+
+var reportURLPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+
+// Score computes the metrics of a run from its result and report bundle.
+func Score(result *pipeline.Result, b artifacts.Bundle) Metrics {
+	var m Metrics
+	if cited := len(b.Citations) + len(b.RemovedCitations); cited > 0 {
+		m.CitationValidity = float64(len(b.Citations)) / float64(cited)
+	}
+
+	findings := b.Structured.KeyFindings
+	m.Findings = len(findings)
+	sources := make(map[string]bool)
+	for _, s := range b.Sources {
+		sources[pipeline.CanonicalURL(s.URL)] = true
+	}
+	var urls, unknown, covered int
+	check := func(u string) {
+		urls++
+		if !sources[pipeline.CanonicalURL(strings.TrimRight(u, ".,;:"))] {
+			unknown++
+		}
+	}
+	for _, f := range findings {
+		if len(f.EvidenceURLs) > 0 {
+			covered++
+		}
+		for _, u := range f.EvidenceURLs {
+			check(u)
+		}
+	}
+	if len(findings) > 0 {
+		m.EvidenceCoverage = float64(covered) / float64(len(findings))
+	}
+
+	body := reportBody(b.Report)
+	for _, u := range reportURLPattern.FindAllString(body, -1) {
+		check(u)
+	}
+	if urls > 0 {
+		m.HallucinatedURLRate = float64(unknown) / float64(urls)
+	}
+
+	if result != nil {
+		// Every round structures once; each repair is one more attempt.
+		if attempts := result.Rounds + result.StructureRepairs; attempts > 0 {
+			m.JSONRepairRate = float64(result.StructureRepairs) / float64(attempts)
+		}
+	}
+	m.ReportWords = len(strings.Fields(body))
+	return m
+}
+
+// reportBody returns the report without the references section, whose URLs
+// come from the sources by construction.
+func reportBody(report string) string {
+	if i := strings.LastIndex(report, artifacts.ReferencesHeading); i >= 0 {
+		return report[:i]
+	}
+	return report
+}
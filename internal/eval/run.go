@@ -0,0 +1,228 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/cassette"
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/storage"
+)
+
+// Case is one topic of the golden set.
+type Case struct {
+	Name  string `json:"name"`
+	Topic string `json:"topic,omitempty"`
+	// Options are research options in the form accepted by the agents (see
+	// pipeline.OptionsSchema).
+	Options map[string]any `json:"options,omitempty"`
+	// Cassette is the path of a recorded session, relative to the golden
+	// set file, to replay. Cases without one run against the fake providers.
+	// The cassette supplies the topic and options the case leaves unset.
+	Cassette string `json:"cassette,omitempty"`
+}
+
+// GoldenSet is the list of cases evaluated together.
+type GoldenSet struct {
+	Cases []Case `json:"cases"`
+
+	dir string
+}
+
+// LoadGoldenSet reads a golden set file.
+func LoadGoldenSet(path string) (*GoldenSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set GoldenSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode golden set: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, c := range set.Cases {
+		if c.Name == "" || seen[c.Name] {
+			return nil, fmt.Errorf("golden set: case names must be unique and non-empty, got %q", c.Name)
+		}
+		seen[c.Name] = true
+		if c.Topic == "" && c.Cassette == "" {
+			return nil, fmt.Errorf("golden set: case %q needs a topic or a cassette", c.Name)
+		}
+	}
+	set.dir = filepath.Dir(path)
+	return &set, nil
+}
+
+// CaseResult is the outcome of one case.
+type CaseResult struct {
+	Name     string `json:"name"`
+	Topic    string `json:"topic"`
+	Provider string `json:"provider"` // "fake" or "cassette"
+	// Error is set when the run failed; Metrics are then absent.
+	Error         string   `json:"error,omitempty"`
+	Metrics       *Metrics `json:"metrics,omitempty"`
+	SkippedStages []string `json:"skipped_stages,omitempty"`
+}
+
+// Report is the outcome of a golden set run.
+type Report struct {
+	Cases []CaseResult `json:"cases"`
+	// Mean averages each metric over the cases that completed.
+	Mean map[string]float64 `json:"mean"`
+}
+
+// Run evaluates every case of the set in order. Failed cases are reported,
+// not returned as errors; an error means a case could not be set up.
+func Run(ctx context.Context, set *GoldenSet) (*Report, error) {
+	report := &Report{}
+	for _, c := range set.Cases {
+		res, err := set.run(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("case %q: %w", c.Name, err)
+		}
+		report.Cases = append(report.Cases, res)
+	}
+	report.Mean = mean(report.Cases)
+	return report, nil
+}
+
+func (s *GoldenSet) run(ctx context.Context, c Case) (CaseResult, error) {
+	res := CaseResult{Name: c.Name, Topic: c.Topic, Provider: "fake"}
+	opts, err := pipeline.ParseOptions(c.Options)
+	if err != nil {
+		return res, fmt.Errorf("options: %w", err)
+	}
+
+	var lm pipeline.LLMClient = FakeLLM{Topic: c.Topic}
+	search := FakeSearch()
+	var fetch pipeline.FetchFunc
+	var recorded *cassette.Cassette
+	if c.Cassette != "" {
+		data, err := os.ReadFile(filepath.Join(s.dir, c.Cassette))
+		if err != nil {
+			return res, err
+		}
+		recorded, err = cassette.Load(data)
+		if err != nil {
+			return res, err
+		}
+		if res.Topic == "" {
+			res.Topic = recorded.Topic
+		}
+		if c.Options == nil {
+			opts = recorded.Options
+		}
+		player := recorded.Replay()
+		lm, search = player.LLM(), player.Search()
+		// Sessions recorded without page fetching are replayed without it.
+		if len(recorded.Fetch) > 0 {
+			fetch = player.Fetch()
+		}
+		res.Provider = "cassette"
+	}
+
+	blobs := &memBlobs{}
+	p := pipeline.New(lm, search, discardDB{}, blobs)
+	if fetch != nil {
+		p.SetFetcher(fetch)
+	}
+	// Cassette sessions run with their recorded settings and clock.
+	if recorded != nil {
+		if err := recorded.Configure(p); err != nil {
+			return res, err
+		}
+	}
+	result, err := p.RunWithOptions(ctx, "eval-"+c.Name, res.Topic, opts, nil)
+	if err != nil {
+		log.Printf("[EVAL] %s failed: %v", c.Name, err)
+		res.Error = err.Error()
+		return res, nil
+	}
+	var bundle artifacts.Bundle
+	if err := json.Unmarshal(blobs.get(result.ReportJSONKey), &bundle); err != nil {
+		res.Error = fmt.Sprintf("decode report bundle: %v", err)
+		return res, nil
+	}
+	m := Score(result, bundle)
+	res.Metrics = &m
+	res.SkippedStages = result.SkippedStages
+	return res, nil
+}
+
+func mean(cases []CaseResult) map[string]float64 {
+	out := make(map[string]float64)
+	n := 0
+	for _, c := range cases {
+		if c.Metrics == nil {
+			continue
+		}
+		n++
+		for name, v := range c.Metrics.values() {
+			out[name] += v
+		}
+	}
+	for name := range out {
+		out[name] /= float64(n)
+	}
+	return out
+}
+
+// memBlobs keeps a run's artifacts in memory.
+type memBlobs struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (m *memBlobs) SaveBlob(name string, content []byte, extension string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.blobs == nil {
+		m.blobs = make(map[string][]byte)
+	}
+	key := fmt.Sprintf("%s-%d.%s", strings.ToLower(name), len(m.blobs), extension)
+	m.blobs[key] = content
+	return key, nil
+}
+
+func (m *memBlobs) DeleteBlob(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blobs, key)
+	return nil
+}
+
+func (m *memBlobs) get(key string) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.blobs[key]
+}
+
+// discardDB is a StructuredStorage that keeps nothing; runs are scored from
+// their report bundle.
+type discardDB struct{}
+
+func (discardDB) CreateSession(_, _ string) error                          { return nil }
+func (discardDB) UpdateSessionStatus(_, _, _ string) error                 { return nil }
+func (discardDB) SaveFindings(_ string, _ []event.StructuredFinding) error { return nil }
+func (discardDB) SaveOpenQuestions(_ string, _ []string) error             { return nil }
+func (discardDB) SaveSources(_ string, _ []event.SearchSource) error       { return nil }
+func (discardDB) MarkSessionComplete(_, _, _, _ string) error              { return nil }
+func (discardDB) GetSessionStatus(_ string) (string, string, error)        { return "", "", nil }
+func (discardDB) GetSessionArtifacts(_ string) (string, string, error)     { return "", "", nil }
+func (discardDB) DeleteSession(_ string) error                             { return nil }
+func (discardDB) SaveCheckpoint(_, _ string, _ []byte) error               { return nil }
+func (discardDB) LoadCheckpoint(_ string) (string, []byte, error)          { return "", nil, nil }
+func (discardDB) DeleteCheckpoint(_ string) error                          { return nil }
+func (discardDB) ListUnfinishedSessions() ([]storage.SessionRecord, error) { return nil, nil }
+func (discardDB) SaveTokenUsage(_ string, _ []event.TokenUsage) error      { return nil }
+func (discardDB) SavePromptVersions(_ string, _ map[string]string) error   { return nil }
+func (discardDB) SetSessionLanguage(_, _ string) error                     { return nil }
+func (discardDB) SaveRenders(_ string, _ map[string]string) error          { return nil }
@@ -1,6 +1,9 @@
 package event
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type ResearchEventType string
 
@@ -17,6 +20,8 @@ const (
 
 	TypeSearchRequested     ResearchEventType = "SEARCH_REQUESTED"
 	TypeStructuredDataReady ResearchEventType = "STRUCTURED_DATA_READY"
+	TypeResearchRound       ResearchEventType = "RESEARCH_ROUND"
+	TypeCanceled            ResearchEventType = "CANCELED"
 )
 
 type Event struct {
@@ -31,10 +36,35 @@ type StatusUpdate struct {
 	Message string            `json:"message"`
 }
 
+// TokenUsage is the LLM token consumption of one pipeline stage.
+type TokenUsage struct {
+	Stage            string  `json:"stage"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	// Estimated is set when some calls reported no usage and their tokens
+	// were estimated from the text length.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// SearchSource is a single search hit.
 type SearchSource struct {
-	Query   string
-	URL     string
-	Snippet string
+	Query    string
+	Queries  []string // every query that returned this hit, Query first
+	URL      string
+	Title    string
+	Snippet  string
+	Content  string // readable page text, when the page was fetched
+	Rank     int    // 1-based position in the provider's results
+	Provider string // search backend that returned the hit
+	Cached   bool   // the hit came from the search cache, not a live search
+	// Credibility scores how far the source can be trusted, from 0 to 1.
+	Credibility float64
+	// Published is the page's publication date, or the zero time when
+	// neither the search provider nor the page states one.
+	Published time.Time
 }
 
 type SearchAggregate struct {
@@ -56,6 +86,25 @@ type StructuredFinding struct {
 	Finding      string   `json:"finding"`
 	EvidenceURLs []string `json:"evidence_urls"`
 	Confidence   float64  `json:"confidence"`
+	// Verdict and VerdictReason are set by claim verification.
+	Verdict       string `json:"verdict,omitempty"`
+	VerdictReason string `json:"verdict_reason,omitempty"`
+}
+
+// ComparisonMatrix assesses each subject of a comparative research run
+// against each criterion.
+type ComparisonMatrix struct {
+	Subjects []string         `json:"subjects"`
+	Criteria []string         `json:"criteria"`
+	Cells    []ComparisonCell `json:"cells"`
+}
+
+// ComparisonCell is the assessment of one subject on one criterion.
+type ComparisonCell struct {
+	Subject      string   `json:"subject"`
+	Criterion    string   `json:"criterion"`
+	Assessment   string   `json:"assessment"`
+	EvidenceURLs []string `json:"evidence_urls"`
 }
 
 type StructuredResearch struct {
@@ -0,0 +1,117 @@
+package fetch
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// minLineLen drops short lines (menus, buttons, bylines) from the output.
+const minLineLen = 30
+
+// skipped elements never contain readable article text.
+var skipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Form: true, atom.Button: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+}
+
+// blocks end a line of text.
+var blocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Li: true, atom.Ul: true, atom.Ol: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Table: true, atom.Tr: true, atom.Br: true, atom.Dd: true, atom.Dt: true,
+}
+
+// ExtractText returns the title and main readable text of an HTML document.
+// Text inside <article> or <main> is preferred when present; navigation,
+// scripts and other boilerplate are dropped, as are lines too short to be
+// prose.
+func ExtractText(r io.Reader) (title, text string, err error) {
+	page, err := ExtractPage(r)
+	if err != nil {
+		return "", "", err
+	}
+	return page.Title, page.Text, nil
+}
+
+// ExtractPage is ExtractText that also looks for the document's publication
+// date in its metadata. The returned page has no URL.
+func ExtractPage(r io.Reader) (*Page, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Published: published(doc)}
+	if t := find(doc, atom.Title); t != nil {
+		page.Title = collapse(textOf(t))
+	}
+
+	root := find(doc, atom.Article)
+	if root == nil {
+		root = find(doc, atom.Main)
+	}
+	if root == nil {
+		root = doc
+	}
+
+	var sb strings.Builder
+	render(root, &sb)
+
+	var lines []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		line = collapse(line)
+		if len(line) >= minLineLen {
+			lines = append(lines, line)
+		}
+	}
+	page.Text = strings.Join(lines, "\n")
+	return page, nil
+}
+
+func render(n *html.Node, sb *strings.Builder) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if skipped[n.DataAtom] || n.DataAtom == atom.Head {
+			return
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		render(c, sb)
+	}
+	if n.Type == html.ElementNode && blocks[n.DataAtom] {
+		sb.WriteString("\n")
+	}
+}
+
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := find(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textOf(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	}
+	return sb.String()
+}
+
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
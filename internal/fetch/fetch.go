@@ -0,0 +1,98 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/user/research-assistant/internal/errors"
+)
+
+// Default limits applied when Options leaves them unset.
+const (
+	DefaultTimeout  = 10 * time.Second
+	DefaultMaxBytes = 2 << 20 // 2 MiB
+)
+
+type Options struct {
+	Timeout   time.Duration // whole-request limit, including the body
+	MaxBytes  int64         // bytes read from the body; the rest is ignored
+	UserAgent string
+	Client    *http.Client // optional; a client with Timeout is used when nil
+}
+
+// Page is the readable content of a fetched URL.
+type Page struct {
+	URL   string // final URL after redirects
+	Title string
+	Text  string
+	// Published is the publication date found in the page's metadata, or
+	// the zero time.
+	Published time.Time
+}
+
+// Fetch downloads rawURL and extracts its readable text. Only HTML and plain
+// text responses are accepted; bodies larger than MaxBytes are truncated.
+func Fetch(ctx context.Context, rawURL string, opts Options) (*Page, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: opts.Timeout}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, errors.New(errors.CodeQueryInvalid, "fetch", "Invalid source URL.", err)
+	}
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+	req.Header.Set("Accept", "text/html,text/plain;q=0.9")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.New(errors.CodeProviderUnavailable, "fetch", "Failed to download source.", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(errors.CodeProviderUnavailable, "fetch", fmt.Sprintf("Source returned status %d", resp.StatusCode), nil)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" && mediaType != "text/plain" {
+		return nil, errors.New(errors.CodeQueryInvalid, "fetch", fmt.Sprintf("Unsupported content type %q", mediaType), nil)
+	}
+
+	body := io.LimitReader(resp.Body, opts.MaxBytes)
+	page := &Page{URL: resp.Request.URL.String()}
+	if mediaType == "text/plain" {
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return nil, errors.New(errors.CodeProviderUnavailable, "fetch", "Failed to read source.", err)
+		}
+		page.Text = strings.TrimSpace(string(raw))
+		return page, nil
+	}
+	extracted, err := ExtractPage(body)
+	if err != nil {
+		return nil, errors.New(errors.CodeInternalFailure, "fetch", "Failed to parse source HTML.", err)
+	}
+	extracted.URL = page.URL
+	return extracted, nil
+}
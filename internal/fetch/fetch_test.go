@@ -0,0 +1,139 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const articleHTML = `<!doctype html>
+<html><head><title> Go Concurrency </title><script>var tracking = 1;</script></head>
+<body>
+<nav><a href="/">Home</a> <a href="/blog">Blog posts and other navigation links</a></nav>
+<article>
+  <h1>Go concurrency patterns in practice</h1>
+  <p>Goroutines are cheap, and channels make it easy to <b>coordinate</b> them across a pipeline.</p>
+  <div class="share">Share</div>
+  <p>Context cancellation propagates deadlines through every stage of the work.</p>
+</article>
+<footer>Copyright notice and a long list of legal links for the website</footer>
+</body></html>`
+
+func TestFetch_ExtractsReadableText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(articleHTML))
+	}))
+	defer srv.Close()
+
+	page, err := Fetch(context.Background(), srv.URL, Options{})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if page.Title != "Go Concurrency" {
+		t.Errorf("title = %q", page.Title)
+	}
+	want := "Go concurrency patterns in practice\n" +
+		"Goroutines are cheap, and channels make it easy to coordinate them across a pipeline.\n" +
+		"Context cancellation propagates deadlines through every stage of the work."
+	if page.Text != want {
+		t.Errorf("text =\n%s\nwant\n%s", page.Text, want)
+	}
+}
+
+func TestFetch_TruncatesAtMaxBytes(t *testing.T) {
+	long := "<html><body><p>" + strings.Repeat("word ", 1000) + "</p><p>tail paragraph that must not be read at all</p></body></html>"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(long))
+	}))
+	defer srv.Close()
+
+	page, err := Fetch(context.Background(), srv.URL, Options{MaxBytes: 200})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(page.Text) > 200 || strings.Contains(page.Text, "tail") {
+		t.Errorf("expected body truncated to 200 bytes, got %d chars", len(page.Text))
+	}
+}
+
+func TestFetch_RejectsUnsupportedContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write([]byte("%PDF-1.4"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	if _, err := Fetch(context.Background(), srv.URL+"/pdf", Options{}); err == nil {
+		t.Error("expected error for application/pdf")
+	}
+	if _, err := Fetch(context.Background(), srv.URL+"/missing", Options{}); err == nil {
+		t.Error("expected error for 404")
+	}
+}
+
+func TestFetch_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	if _, err := Fetch(context.Background(), srv.URL, Options{Timeout: 50 * time.Millisecond}); err == nil {
+		t.Error("expected timeout error")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("timeout not enforced, took %v", time.Since(start))
+	}
+}
+
+func TestExtractPage_Published(t *testing.T) {
+	cases := map[string]string{
+		"meta property": `<html><head><meta property="article:published_time" content="2024-03-05T10:00:00+01:00"><meta name="date" content="2020-01-01"></head><body></body></html>`,
+		"json-ld graph": `<html><head><script type="application/ld+json">{"@graph": [{"@type": "WebPage"}, {"@type": "NewsArticle", "datePublished": "2024-03-05"}]}</script></head><body></body></html>`,
+		"time element":  `<html><body><time datetime="2023-01-01">Earlier</time><article><p>Text</p><time datetime="2024-03-05T08:00:00Z">5 March</time></article></body></html>`,
+	}
+	for name, doc := range cases {
+		page, err := ExtractPage(strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("%s: ExtractPage: %v", name, err)
+		}
+		if got := page.Published.Format("2006-01-02"); got != "2024-03-05" {
+			t.Errorf("%s: published = %s, want 2024-03-05", name, got)
+		}
+	}
+
+	page, err := ExtractPage(strings.NewReader(articleHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.Published.IsZero() {
+		t.Errorf("expected no publication date, got %v", page.Published)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for _, s := range []string{"2024-03-05", "2024-03-05T10:00:00Z", "2024/03/05", "20240305", "March 5, 2024", "Tue, 05 Mar 2024 10:00:00 GMT"} {
+		if got, ok := ParseDate(s); !ok || got.Format("2006-01-02") != "2024-03-05" {
+			t.Errorf("ParseDate(%q) = %v, %v", s, got, ok)
+		}
+	}
+	future := time.Now().AddDate(1, 0, 0).Format("2006-01-02")
+	for _, s := range []string{"", "yesterday", "1970-01-01", future} {
+		if _, ok := ParseDate(s); ok {
+			t.Errorf("expected ParseDate(%q) to fail", s)
+		}
+	}
+}
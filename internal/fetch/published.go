@@ -0,0 +1,153 @@
+package fetch
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// publishedKeys are the meta tag names, properties and itemprops that carry a
+// page's publication date, in order of preference.
+var publishedKeys = []string{
+	"article:published_time", "og:article:published_time", "datepublished",
+	"citation_publication_date", "citation_date", "dc.date.issued", "dcterms.issued",
+	"dc.date", "dcterms.date", "dcterms.created", "parsely-pub-date", "sailthru.date",
+	"pubdate", "publishdate", "publish-date", "date",
+}
+
+// dateLayouts are the date formats found in page metadata.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"20060102",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+}
+
+// PublishedFromMeta returns the publication date named in meta, a map of
+// lower-case meta tag names to their content, such as the metatags a search
+// provider reports for a result. It returns the zero time when there is none.
+func PublishedFromMeta(meta map[string]string) time.Time {
+	for _, key := range publishedKeys {
+		if t, ok := ParseDate(meta[key]); ok {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// ParseDate parses a date as pages and search providers write them. Dates
+// before 1990 or in the future are rejected as unlikely publication dates.
+func ParseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if t.Year() < 1990 || t.After(time.Now().Add(24*time.Hour)) {
+			return time.Time{}, false
+		}
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// published finds the publication date of a parsed HTML document in its meta
+// tags, its JSON-LD data or, failing those, the first <time datetime> element
+// of its article.
+func published(doc *html.Node) time.Time {
+	meta := make(map[string]string)
+	var ldJSON []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Meta:
+				key := strings.ToLower(firstAttr(n, "property", "name", "itemprop"))
+				if _, seen := meta[key]; key != "" && !seen {
+					meta[key] = firstAttr(n, "content", "datetime")
+				}
+			case atom.Script:
+				if strings.EqualFold(firstAttr(n, "type"), "application/ld+json") {
+					ldJSON = append(ldJSON, textOf(n))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if t := PublishedFromMeta(meta); !t.IsZero() {
+		return t
+	}
+	for _, data := range ldJSON {
+		if t, ok := ParseDate(ldDatePublished(data)); ok {
+			return t
+		}
+	}
+	root := find(doc, atom.Article)
+	if root == nil {
+		root = doc
+	}
+	if n := find(root, atom.Time); n != nil {
+		if t, ok := ParseDate(firstAttr(n, "datetime")); ok {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// ldDatePublished returns the first datePublished value in a JSON-LD block,
+// which may hold a single object, a list or an @graph of objects.
+func ldDatePublished(data string) string {
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return ""
+	}
+	var search func(v any) string
+	search = func(v any) string {
+		switch v := v.(type) {
+		case map[string]any:
+			if s, ok := v["datePublished"].(string); ok {
+				return s
+			}
+			return search(v["@graph"])
+		case []any:
+			for _, item := range v {
+				if s := search(item); s != "" {
+					return s
+				}
+			}
+		}
+		return ""
+	}
+	return search(v)
+}
+
+func firstAttr(n *html.Node, keys ...string) string {
+	for _, key := range keys {
+		for _, a := range n.Attr {
+			if strings.EqualFold(a.Key, key) && a.Val != "" {
+				return a.Val
+			}
+		}
+	}
+	return ""
+}
@@ -9,9 +9,15 @@ import (
 	"github.com/google/generative-ai-go/genai"
 	apperrors "github.com/user/research-assistant/internal/errors"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
+const (
+	primaryModel  = "gemini-2.5-flash"
+	fallbackModel = "gemini-2.5-flash-lite"
+)
+
 type GeminiClient struct {
 	client *genai.Client
 	model  *genai.GenerativeModel
@@ -23,7 +29,7 @@ func NewGeminiClient(ctx context.Context, apiKey string) (*GeminiClient, error)
 		return nil, fmt.Errorf("failed to create genai client: %w", err)
 	}
 
-	model := client.GenerativeModel("gemini-2.5-flash")
+	model := client.GenerativeModel(primaryModel)
 
 	return &GeminiClient{
 		client: client,
@@ -35,18 +41,120 @@ func (g *GeminiClient) Close() error {
 	return g.client.Close()
 }
 
+// Usage reports the tokens the provider counted for a single generation call.
+type Usage struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
 func (g *GeminiClient) GenerateContent(ctx context.Context, prompt string) (string, error) {
-	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
+	text, _, err := g.GenerateContentWithUsage(ctx, prompt)
+	return text, err
+}
+
+// GenerateContentWithUsage is GenerateContent that also returns the token
+// usage reported in the response metadata. Usage is zero when the call failed
+// before a response arrived.
+func (g *GeminiClient) GenerateContentWithUsage(ctx context.Context, prompt string) (string, Usage, error) {
+	return g.generate(ctx, prompt, nil)
+}
+
+// GenerateJSON asks the model for a JSON response constrained to schema, a
+// JSON schema in the subset accepted by the provider (type, properties,
+// required, items, enum). The response is not validated; callers must still
+// check it.
+func (g *GeminiClient) GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, Usage, error) {
+	responseSchema := toGenaiSchema(schema)
+	return g.generate(ctx, prompt, func(m *genai.GenerativeModel) {
+		m.ResponseMIMEType = "application/json"
+		m.ResponseSchema = responseSchema
+	})
+}
+
+// generate runs prompt on the primary model and falls back to the lighter
+// model on failure. configure, when set, adjusts a per-call copy of the model
+// so that the shared model keeps its default generation config.
+func (g *GeminiClient) generate(ctx context.Context, prompt string, configure func(*genai.GenerativeModel)) (string, Usage, error) {
+	model := g.model
+	if configure != nil {
+		model = g.client.GenerativeModel(primaryModel)
+		configure(model)
+	}
+	modelName := primaryModel
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
 	if err != nil {
-		fmt.Printf("[LLM] Primary model failed, attempting fallback to gemini-2.5-flash-lite: %v\n", err)
-		fallbackModel := g.client.GenerativeModel("gemini-2.5-flash-lite")
-		resp, err = fallbackModel.GenerateContent(ctx, genai.Text(prompt))
+		fmt.Printf("[LLM] Primary model failed, attempting fallback to %s: %v\n", fallbackModel, err)
+		modelName = fallbackModel
+		fallback := g.client.GenerativeModel(fallbackModel)
+		if configure != nil {
+			configure(fallback)
+		}
+		resp, err = fallback.GenerateContent(ctx, genai.Text(prompt))
+		if err != nil {
+			return "", Usage{}, g.wrapError(err)
+		}
+	}
+
+	text, err := responseText(resp)
+	return text, responseUsage(modelName, resp), err
+}
+
+// GenerateContentStream is GenerateContentWithUsage using the provider's
+// streaming API. onChunk is called with each piece of text as it arrives;
+// the returned text is their concatenation. The fallback model is only tried
+// when the primary one fails before producing any text, since the caller has
+// already seen what was streamed.
+func (g *GeminiClient) GenerateContentStream(ctx context.Context, prompt string, onChunk func(string)) (string, Usage, error) {
+	text, usage, streamed, err := g.stream(ctx, g.model, primaryModel, prompt, onChunk)
+	if err != nil && !streamed {
+		fmt.Printf("[LLM] Primary model stream failed, attempting fallback to %s: %v\n", fallbackModel, err)
+		text, usage, _, err = g.stream(ctx, g.client.GenerativeModel(fallbackModel), fallbackModel, prompt, onChunk)
+	}
+	return text, usage, err
+}
+
+// stream runs one streaming call and reports whether any text reached
+// onChunk before it ended.
+func (g *GeminiClient) stream(ctx context.Context, model *genai.GenerativeModel, modelName, prompt string, onChunk func(string)) (string, Usage, bool, error) {
+	iter := model.GenerateContentStream(ctx, genai.Text(prompt))
+	streamed := false
+	for {
+		resp, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
 		if err != nil {
-			return "", g.wrapError(err)
+			return "", responseUsage(modelName, iter.MergedResponse()), streamed, g.wrapError(err)
+		}
+		if chunk := candidateText(resp); chunk != "" {
+			streamed = true
+			onChunk(chunk)
 		}
 	}
+	merged := iter.MergedResponse()
+	text, err := responseText(merged)
+	return text, responseUsage(modelName, merged), streamed, err
+}
+
+func responseUsage(modelName string, resp *genai.GenerateContentResponse) Usage {
+	usage := Usage{Model: modelName}
+	if resp == nil {
+		return usage
+	}
+	if md := resp.UsageMetadata; md != nil {
+		usage.PromptTokens = int(md.PromptTokenCount)
+		usage.CompletionTokens = int(md.CandidatesTokenCount)
+		usage.TotalTokens = int(md.TotalTokenCount)
+	}
+	return usage
+}
 
-	if len(resp.Candidates) == 0 {
+// responseText returns the text of the response's first candidate, or an
+// application error when there is none or it was blocked.
+func responseText(resp *genai.GenerateContentResponse) (string, error) {
+	if resp == nil || len(resp.Candidates) == 0 {
 		return "", apperrors.New(apperrors.CodeInternalFailure, "llm", "No response generated", nil)
 	}
 
@@ -60,14 +168,7 @@ func (g *GeminiClient) GenerateContent(ctx context.Context, prompt string) (stri
 		return "", appErr
 	}
 
-	var result string
-	if candidate.Content != nil {
-		for _, part := range candidate.Content.Parts {
-			if text, ok := part.(genai.Text); ok {
-				result += string(text)
-			}
-		}
-	}
+	result := candidateText(resp)
 
 	if result == "" {
 		return "", apperrors.New(apperrors.CodeInternalFailure, "llm", "Empty response from provider", nil)
@@ -76,6 +177,20 @@ func (g *GeminiClient) GenerateContent(ctx context.Context, prompt string) (stri
 	return result, nil
 }
 
+// candidateText concatenates the text parts of the response's first candidate.
+func candidateText(resp *genai.GenerateContentResponse) string {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			sb.WriteString(string(text))
+		}
+	}
+	return sb.String()
+}
+
 func (g *GeminiClient) wrapError(err error) error {
 	var gErr *googleapi.Error
 	if errors.As(err, &gErr) {
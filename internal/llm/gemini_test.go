@@ -2,10 +2,13 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
+	"github.com/google/generative-ai-go/genai"
 	"github.com/user/research-assistant/internal/config"
+	apperrors "github.com/user/research-assistant/internal/errors"
 )
 
 func TestGeminiClient_GenerateContent_Integration(t *testing.T) {
@@ -57,3 +60,56 @@ func TestGeminiClient_GenerateContent_Integration(t *testing.T) {
 		t.Errorf("Response does not appear to be structured JSON: %s", response)
 	}
 }
+
+func TestGeminiClient_GenerateContentStream_Integration(t *testing.T) {
+	config.LoadEnv()
+	apiKey := config.GetEnv("GEMINI_API_KEY", "")
+
+	if apiKey == "" {
+		t.Skip("Skipping integration test: GEMINI_API_KEY not set")
+	}
+
+	ctx := context.Background()
+	client, err := NewGeminiClient(ctx, apiKey)
+	if err != nil {
+		t.Fatalf("Failed to create Gemini client: %v", err)
+	}
+	defer client.Close()
+
+	var chunks []string
+	text, usage, err := client.GenerateContentStream(ctx, "Write three short paragraphs about the history of the SNES.", func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream content: %v", err)
+	}
+	if len(chunks) == 0 {
+		t.Fatal("no chunks streamed")
+	}
+	if got := strings.Join(chunks, ""); got != text {
+		t.Errorf("chunks do not add up to the returned text:\n%q\n%q", got, text)
+	}
+	if usage.TotalTokens == 0 {
+		t.Error("streamed call reported no usage")
+	}
+}
+
+func TestResponseText(t *testing.T) {
+	text := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content: &genai.Content{Parts: []genai.Part{genai.Text("Hello, "), genai.Text("world")}},
+	}}}
+	if got, err := responseText(text); err != nil || got != "Hello, world" {
+		t.Errorf("responseText = %q, %v; want %q", got, err, "Hello, world")
+	}
+
+	blocked := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonSafety}}}
+	_, err := responseText(blocked)
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) || appErr.Code != apperrors.CodePolicyViolation {
+		t.Errorf("blocked response error = %v, want a policy violation", err)
+	}
+
+	if _, err := responseText(&genai.GenerateContentResponse{}); err == nil {
+		t.Error("responseText accepted a response without candidates")
+	}
+}
@@ -0,0 +1,49 @@
+package llm
+
+import "github.com/google/generative-ai-go/genai"
+
+// toGenaiSchema converts a JSON schema held as nested maps into the provider's
+// response schema. Keywords the provider does not support, such as minimum or
+// pattern, are dropped.
+func toGenaiSchema(schema map[string]any) *genai.Schema {
+	if schema == nil {
+		return nil
+	}
+	out := &genai.Schema{}
+	switch schema["type"] {
+	case "object":
+		out.Type = genai.TypeObject
+	case "array":
+		out.Type = genai.TypeArray
+	case "string":
+		out.Type = genai.TypeString
+	case "integer":
+		out.Type = genai.TypeInteger
+	case "number":
+		out.Type = genai.TypeNumber
+	case "boolean":
+		out.Type = genai.TypeBoolean
+	}
+	if desc, ok := schema["description"].(string); ok {
+		out.Description = desc
+	}
+	if enum, ok := schema["enum"].([]string); ok {
+		out.Format = "enum"
+		out.Enum = enum
+	}
+	if required, ok := schema["required"].([]string); ok {
+		out.Required = required
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		out.Items = toGenaiSchema(items)
+	}
+	if props, ok := schema["properties"].(map[string]any); ok {
+		out.Properties = make(map[string]*genai.Schema, len(props))
+		for name, sub := range props {
+			if subSchema, ok := sub.(map[string]any); ok {
+				out.Properties[name] = toGenaiSchema(subSchema)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,32 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestToGenaiSchema(t *testing.T) {
+	got := toGenaiSchema(map[string]any{
+		"type":     "object",
+		"required": []string{"topic"},
+		"properties": map[string]any{
+			"topic":  map[string]any{"type": "string", "pattern": "^x"},
+			"format": map[string]any{"type": "string", "enum": []string{"a", "b"}},
+			"scores": map[string]any{"type": "array", "items": map[string]any{"type": "number", "minimum": 0}},
+		},
+	})
+
+	if got.Type != genai.TypeObject || len(got.Required) != 1 || got.Required[0] != "topic" {
+		t.Fatalf("unexpected object schema: %+v", got)
+	}
+	if got.Properties["topic"].Type != genai.TypeString {
+		t.Errorf("expected string topic, got %+v", got.Properties["topic"])
+	}
+	if f := got.Properties["format"]; f.Format != "enum" || len(f.Enum) != 2 {
+		t.Errorf("expected enum format, got %+v", f)
+	}
+	if s := got.Properties["scores"]; s.Type != genai.TypeArray || s.Items == nil || s.Items.Type != genai.TypeNumber {
+		t.Errorf("expected array of numbers, got %+v", s)
+	}
+}
@@ -0,0 +1,224 @@
+// Package moderation decides whether a research topic may be researched,
+// before any search or fetch is spent on it.
+package moderation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	apperrors "github.com/user/research-assistant/internal/errors"
+)
+
+// Category is a kind of topic the policy blocks.
+type Category struct {
+	// Name identifies the category in configuration and error telemetry.
+	Name string
+	// Description tells the LLM classifier what the category covers.
+	Description string
+	// Phrases are matched against the topic by the deterministic
+	// classifier. They are lower case and matched on word boundaries.
+	Phrases []string
+	// Suggestion tells the user how to rephrase a blocked topic.
+	Suggestion string
+}
+
+// DefaultCategories are the categories blocked by DefaultPolicy. Their
+// phrases describe requests for operational help, so that topics about the
+// history, regulation or prevention of the same subjects pass.
+var DefaultCategories = []Category{
+	{
+		Name:        "weapons",
+		Description: "instructions for making or using weapons capable of mass harm, such as explosives or chemical, biological or nuclear agents",
+		Phrases: []string{
+			"make a bomb", "build a bomb", "pipe bomb", "make explosives", "build explosives",
+			"make nerve agent", "synthesize nerve agent", "make sarin", "make ricin", "weaponize anthrax",
+		},
+		Suggestion: "Ask about the history, regulation or detection of these weapons rather than how to make them.",
+	},
+	{
+		Name:        "self_harm",
+		Description: "methods or encouragement of suicide or self-harm",
+		Phrases: []string{
+			"how to kill myself", "ways to kill myself", "suicide methods", "painless suicide", "how to self harm",
+		},
+		Suggestion: "If you are struggling, please contact a local crisis line. To research the subject, ask about prevention, support or treatment.",
+	},
+	{
+		Name:        "malware",
+		Description: "writing or deploying malicious software or breaking into systems without authorization",
+		Phrases: []string{
+			"write ransomware", "create ransomware", "write malware", "create malware", "write a keylogger",
+			"build a keylogger", "build a botnet", "hack into", "steal passwords",
+		},
+		Suggestion: "Ask how such attacks work and how to defend against them.",
+	},
+	{
+		Name:        "illegal_drugs",
+		Description: "manufacturing or acquiring illegal drugs",
+		Phrases: []string{
+			"make meth", "cook meth", "synthesize meth", "synthesize methamphetamine", "make fentanyl",
+			"synthesize fentanyl", "buy cocaine", "buy heroin",
+		},
+		Suggestion: "Ask about drug policy, public health or addiction treatment instead.",
+	},
+	{
+		Name:        "sexual_minors",
+		Description: "sexual content involving minors",
+		Phrases:     []string{"child porn", "child pornography", "sexualize minors", "sexual content with minors"},
+		Suggestion:  "Ask about child protection and online safety instead.",
+	},
+	{
+		Name:        "violent_extremism",
+		Description: "planning attacks or recruiting for violent extremist groups",
+		Phrases: []string{
+			"plan a terrorist attack", "carry out a terrorist attack", "join isis", "recruit for jihad",
+			"mass shooting plan",
+		},
+		Suggestion: "Ask about the history of extremist movements or efforts to counter them.",
+	},
+}
+
+// Policy is the moderation configuration of a pipeline.
+type Policy struct {
+	// Categories are the blocked kinds of topic.
+	Categories []Category
+	// Allow lists phrases that let a topic pass without classification.
+	// They take precedence over Deny.
+	Allow []string
+	// Deny lists phrases that block a topic without classification.
+	Deny []string
+	// Classify asks the LLM to classify topics the lists leave undecided.
+	// Without it, or when the LLM fails, the category phrases decide.
+	Classify bool
+}
+
+// DefaultPolicy blocks DefaultCategories using the deterministic classifier.
+func DefaultPolicy() Policy {
+	return Policy{Categories: DefaultCategories}
+}
+
+// Select returns the default categories with the given names, in the order
+// given. Unknown names are an error.
+func Select(names []string) ([]Category, error) {
+	var out []Category
+	for _, name := range names {
+		c, ok := lookup(DefaultCategories, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown moderation category %q", name)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// Decision is the outcome of moderating a topic.
+type Decision struct {
+	Allowed bool
+	// Category is the blocked category, or "deny_list" for topics blocked
+	// by the deny list.
+	Category   string
+	Reason     string
+	Suggestion string
+	// DecidedBy is "allow_list", "deny_list", "classifier" or "phrases".
+	DecidedBy string
+}
+
+// Lists decides topics matched by the allow or deny list. The second result
+// is false when neither list matches.
+func (p Policy) Lists(topic string) (Decision, bool) {
+	text := normalize(topic)
+	for _, phrase := range p.Allow {
+		if contains(text, phrase) {
+			return Decision{Allowed: true, DecidedBy: "allow_list"}, true
+		}
+	}
+	for _, phrase := range p.Deny {
+		if contains(text, phrase) {
+			return Decision{
+				Category:   "deny_list",
+				Reason:     "the topic matches a blocked term",
+				Suggestion: "Rephrase the topic without the blocked term.",
+				DecidedBy:  "deny_list",
+			}, true
+		}
+	}
+	return Decision{}, false
+}
+
+// Match classifies topic by the phrases of the policy's categories.
+func (p Policy) Match(topic string) Decision {
+	text := normalize(topic)
+	for _, c := range p.Categories {
+		for _, phrase := range c.Phrases {
+			if contains(text, phrase) {
+				return p.blocked(c, fmt.Sprintf("the topic asks for %s", c.Description), "", "phrases")
+			}
+		}
+	}
+	return Decision{Allowed: true, DecidedBy: "phrases"}
+}
+
+// Classified turns the LLM classifier's verdict into a decision. A verdict
+// naming a category outside the policy lets the topic pass, since only the
+// configured categories are blocked. An empty suggestion is replaced by the
+// category's.
+func (p Policy) Classified(allowed bool, category, reason, suggestion string) Decision {
+	if allowed {
+		return Decision{Allowed: true, DecidedBy: "classifier"}
+	}
+	c, ok := lookup(p.Categories, category)
+	if !ok {
+		return Decision{Allowed: true, DecidedBy: "classifier"}
+	}
+	if reason = strings.TrimSpace(reason); reason == "" {
+		reason = fmt.Sprintf("the topic asks for %s", c.Description)
+	}
+	return p.blocked(c, reason, strings.TrimSpace(suggestion), "classifier")
+}
+
+func (p Policy) blocked(c Category, reason, suggestion, decidedBy string) Decision {
+	if suggestion == "" {
+		suggestion = c.Suggestion
+	}
+	return Decision{Category: c.Name, Reason: reason, Suggestion: suggestion, DecidedBy: decidedBy}
+}
+
+// Err returns the error reported for a blocked topic: a policy violation the
+// user can recover from by rephrasing.
+func (d Decision) Err() *apperrors.AppError {
+	appErr := apperrors.New(apperrors.CodePolicyViolation, "moderation",
+		"This topic can't be researched: "+d.Reason+".", nil)
+	appErr.Recovery = &apperrors.RecoveryAction{Type: apperrors.RecoveryRephrase, Suggestion: d.Suggestion}
+	appErr.Telemetry = map[string]any{
+		"category":   d.Category,
+		"decided_by": d.DecidedBy,
+	}
+	return appErr
+}
+
+func lookup(categories []Category, name string) (Category, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, c := range categories {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Category{}, false
+}
+
+// normalize lower-cases text and reduces it to words separated by single
+// spaces, padded with a space on each side for word-boundary matching.
+func normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(words, " ") + " "
+}
+
+// contains reports whether the normalized text contains phrase as whole
+// words.
+func contains(text, phrase string) bool {
+	phrase = strings.TrimSpace(normalize(phrase))
+	return phrase != "" && strings.Contains(text, " "+phrase+" ")
+}
@@ -0,0 +1,69 @@
+package moderation_test
+
+import (
+	"testing"
+
+	"github.com/user/research-assistant/internal/moderation"
+)
+
+func TestPolicy_Match(t *testing.T) {
+	p := moderation.DefaultPolicy()
+	cases := map[string]string{
+		"How to make a bomb?":                      "weapons",
+		"Best way to COOK METH at home":            "illegal_drugs",
+		"History of the Manhattan Project":         "",
+		"Why is unsafe Rust disallowed in kernels": "",
+		"Suicide prevention programs in schools":   "",
+		"Bomb disposal robots":                     "",
+		"remake a bombastic film":                  "",
+	}
+	for topic, want := range cases {
+		d := p.Match(topic)
+		if d.Allowed != (want == "") || d.Category != want {
+			t.Errorf("Match(%q) = %+v, want category %q", topic, d, want)
+		}
+		if !d.Allowed && d.Suggestion == "" {
+			t.Errorf("Match(%q) blocked without a suggestion", topic)
+		}
+	}
+}
+
+func TestPolicy_Lists(t *testing.T) {
+	p := moderation.Policy{Allow: []string{"pipe bomb history"}, Deny: []string{"pipe bomb", "doxxing"}}
+
+	if d, ok := p.Lists("Pipe bomb history in the 1970s"); !ok || !d.Allowed {
+		t.Errorf("expected the allow list to take precedence, got %+v, %v", d, ok)
+	}
+	if d, ok := p.Lists("Doxxing a journalist"); !ok || d.Allowed || d.DecidedBy != "deny_list" {
+		t.Errorf("expected the deny list to block, got %+v, %v", d, ok)
+	}
+	if _, ok := p.Lists("Gardening in spring"); ok {
+		t.Error("expected topics matching neither list to be undecided")
+	}
+}
+
+func TestPolicy_Classified(t *testing.T) {
+	p := moderation.Policy{Categories: moderation.DefaultCategories[:1]}
+
+	if d := p.Classified(false, "Weapons", "", ""); d.Allowed || d.Category != "weapons" || d.Reason == "" || d.Suggestion == "" {
+		t.Errorf("expected a blocked decision with defaults filled in, got %+v", d)
+	}
+	if d := p.Classified(false, "malware", "r", "s"); !d.Allowed {
+		t.Errorf("expected categories outside the policy to pass, got %+v", d)
+	}
+
+	err := p.Classified(false, "weapons", "r", "s").Err()
+	if err.Recovery == nil || err.Recovery.Suggestion != "s" || err.Telemetry["decided_by"] != "classifier" {
+		t.Errorf("unexpected error %+v", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	got, err := moderation.Select([]string{"malware", "weapons"})
+	if err != nil || len(got) != 2 || got[0].Name != "malware" || got[1].Name != "weapons" {
+		t.Errorf("Select = %v, %v", got, err)
+	}
+	if _, err := moderation.Select([]string{"gambling"}); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+}
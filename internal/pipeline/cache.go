@@ -0,0 +1,90 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SearchCache stores serialized search results by key. It is implemented by
+// storage.SQLiteStore.
+type SearchCache interface {
+	// GetCachedSearch returns the results stored under key, or false when
+	// there are none younger than maxAge.
+	GetCachedSearch(key string, maxAge time.Duration) ([]byte, bool, error)
+	// PutCachedSearch stores results under key, keeping at most maxEntries
+	// entries when maxEntries is positive.
+	PutCachedSearch(key string, results []byte, maxEntries int) error
+}
+
+// CacheOptions configures CachedSearch.
+type CacheOptions struct {
+	// TTL is how long results are served from the cache.
+	TTL time.Duration
+	// MaxEntries caps the number of cached searches; zero means no cap.
+	MaxEntries int
+}
+
+// CachedSearch wraps search with a cache keyed by the normalized query and
+// the provider options. Results served from the cache are marked Cached.
+// Failed and empty searches are not cached, and cache errors fall back to
+// a live search.
+func CachedSearch(search SearchFunc, cache SearchCache, opts CacheOptions) SearchFunc {
+	return func(ctx context.Context, query string, so SearchOptions) ([]SearchResult, error) {
+		key := SearchCacheKey(query, so)
+		data, ok, err := cache.GetCachedSearch(key, opts.TTL)
+		if err != nil {
+			log.Printf("[PIPELINE] search cache read failed for %q: %v", query, err)
+		}
+		if ok {
+			var results []SearchResult
+			if err := json.Unmarshal(data, &results); err == nil {
+				for i := range results {
+					results[i].Cached = true
+				}
+				return results, nil
+			}
+			log.Printf("[PIPELINE] search cache entry for %q unreadable: %v", query, err)
+		}
+
+		results, err := search(ctx, query, so)
+		if err != nil || len(results) == 0 {
+			return results, err
+		}
+		if data, err := json.Marshal(results); err == nil {
+			if err := cache.PutCachedSearch(key, data, opts.MaxEntries); err != nil {
+				log.Printf("[PIPELINE] search cache write failed for %q: %v", query, err)
+			}
+		}
+		return results, nil
+	}
+}
+
+// SearchCacheKey returns the cache key of a search. Queries that differ only
+// in case or whitespace, and domain lists that differ only in order or case,
+// share a key.
+func SearchCacheKey(query string, opts SearchOptions) string {
+	opts.Language = strings.ToLower(opts.Language)
+	opts.IncludeDomains = normalizedDomains(opts.IncludeDomains)
+	opts.ExcludeDomains = normalizedDomains(opts.ExcludeDomains)
+	encoded, _ := json.Marshal(opts)
+	sum := sha256.Sum256([]byte(normalizeName(query) + "\n" + string(encoded)))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizedDomains(domains []string) []string {
+	if len(domains) == 0 {
+		return nil
+	}
+	out := make([]string, len(domains))
+	for i, d := range domains {
+		out[i] = strings.ToLower(strings.TrimSpace(d))
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,133 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// memCache is an in-memory SearchCache. It records the TTL it is asked for
+// but never expires entries.
+type memCache struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+	ttl     time.Duration
+}
+
+func (c *memCache) GetCachedSearch(key string, maxAge time.Duration) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = maxAge
+	data, ok := c.entries[key]
+	return data, ok, nil
+}
+
+func (c *memCache) PutCachedSearch(key string, results []byte, _ int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string][]byte)
+	}
+	c.entries[key] = results
+	return nil
+}
+
+func TestCachedSearch_ServesRepeatQueries(t *testing.T) {
+	ms := &mockSearcher{
+		results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com", Title: "A", Provider: "mock"}},
+		errIdx:  -1,
+	}
+	cache := &memCache{}
+	search := pipeline.CachedSearch(ms.search, cache, pipeline.CacheOptions{TTL: time.Hour})
+	opts := pipeline.SearchOptions{Num: 5, Language: "en"}
+
+	first, err := search(context.Background(), "Go generics", opts)
+	if err != nil || len(first) != 1 || first[0].Cached {
+		t.Fatalf("expected a live result, got %+v, %v", first, err)
+	}
+	second, err := search(context.Background(), "  go   GENERICS ", opts)
+	if err != nil || len(second) != 1 || !second[0].Cached || second[0].URL != "http://a.com" || second[0].Provider != "mock" {
+		t.Fatalf("expected the cached result, got %+v, %v", second, err)
+	}
+	if ms.calls != 1 {
+		t.Errorf("expected 1 backend call, got %d", ms.calls)
+	}
+	if cache.ttl != time.Hour {
+		t.Errorf("expected the TTL to be passed to the cache, got %v", cache.ttl)
+	}
+
+	if _, err := search(context.Background(), "Go generics", pipeline.SearchOptions{Num: 5, Language: "de"}); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if ms.calls != 2 {
+		t.Errorf("expected different provider options to miss the cache, got %d backend calls", ms.calls)
+	}
+}
+
+func TestCachedSearch_DoesNotCacheFailures(t *testing.T) {
+	ms := &mockSearcher{err: errors.New("quota exceeded"), errIdx: 0}
+	cache := &memCache{}
+	search := pipeline.CachedSearch(ms.search, cache, pipeline.CacheOptions{TTL: time.Hour})
+
+	if _, err := search(context.Background(), "q", pipeline.SearchOptions{}); err == nil {
+		t.Fatal("expected the backend error")
+	}
+	if _, err := search(context.Background(), "q", pipeline.SearchOptions{}); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if ms.calls != 2 {
+		t.Errorf("expected the failure to be retried live, got %d backend calls", ms.calls)
+	}
+}
+
+func TestSearchCacheKey(t *testing.T) {
+	base := pipeline.SearchOptions{Num: 5, IncludeDomains: []string{"go.dev", "golang.org"}}
+	key := pipeline.SearchCacheKey("Go generics", base)
+
+	same := pipeline.SearchOptions{Num: 5, IncludeDomains: []string{"GOLANG.org", "go.dev"}}
+	if got := pipeline.SearchCacheKey("go  generics", same); got != key {
+		t.Error("expected case, spacing and domain order not to change the key")
+	}
+	for name, opts := range map[string]pipeline.SearchOptions{
+		"num":      {Num: 10, IncludeDomains: base.IncludeDomains},
+		"language": {Num: 5, Language: "de", IncludeDomains: base.IncludeDomains},
+		"exclude":  {Num: 5, IncludeDomains: base.IncludeDomains, ExcludeDomains: []string{"go.dev"}},
+	} {
+		if pipeline.SearchCacheKey("Go generics", opts) == key {
+			t.Errorf("%s: expected a different key", name)
+		}
+	}
+}
+
+// TestPipeline_MarksCachedSources verifies that a run answered from the
+// search cache reports it in its status updates and source records.
+func TestPipeline_MarksCachedSources(t *testing.T) {
+	search := pipeline.CachedSearch(perQuerySearch, &memCache{}, pipeline.CacheOptions{TTL: time.Hour})
+	run := func() ([]string, *sourceRecordingDB) {
+		db := &sourceRecordingDB{}
+		p := pipeline.New(&routeLLM{routes: deadlineRoutes}, search, db, &mockBlob{})
+		cb, statuses, _ := collectStatuses(nil)
+		if _, err := p.RunWithOptions(context.Background(), "s1", "Topic", pipeline.Options{MaxRounds: 1}, cb); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return *statuses, db
+	}
+
+	statuses, db := run()
+	if countOf(statuses, "search_cached") != 0 || len(db.sources) == 0 || db.sources[0].Cached {
+		t.Fatalf("expected a live first run, got statuses %v and sources %+v", statuses, db.sources)
+	}
+	statuses, db = run()
+	if countOf(statuses, "search_cached") != 1 {
+		t.Errorf("expected one search_cached update, got %v", statuses)
+	}
+	for _, s := range db.sources {
+		if !s.Cached {
+			t.Errorf("expected source %s to be marked cached", s.URL)
+		}
+	}
+}
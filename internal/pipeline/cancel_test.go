@@ -0,0 +1,85 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// cancelingBlob cancels the run once the report.md blob is written and
+// records deleted keys.
+type cancelingBlob struct {
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	deleted []string
+}
+
+func (b *cancelingBlob) SaveBlob(_ string, _ []byte, ext string) (string, error) {
+	if ext == "md" {
+		b.cancel()
+	}
+	return "partial." + ext, nil
+}
+
+func (b *cancelingBlob) DeleteBlob(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deleted = append(b.deleted, key)
+	return nil
+}
+
+// TestPipeline_CancelStopsRun verifies that cancelling the context stops the
+// run before its next stage and records the session as canceled, not failed.
+func TestPipeline_CancelStopsRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lm := &mockLLM{responses: []string{`["query1"]`}}
+	search := func(sctx context.Context, _ string, _ pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
+		cancel()
+		return nil, sctx.Err()
+	}
+	db := newCheckpointDB()
+	p := pipeline.New(lm, search, db, &mockBlob{})
+
+	onUpdate, statuses, _ := collectStatuses(nil)
+	_, err := p.RunWithUpdates(ctx, "s1", "Test Topic", onUpdate)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if last := (*statuses)[len(*statuses)-1]; last != "canceled" {
+		t.Errorf("expected final status canceled, got %v", *statuses)
+	}
+	if firstIndexOf(*statuses, "failed") >= 0 || firstIndexOf(*statuses, "structuring") >= 0 {
+		t.Errorf("canceled run continued or failed: %v", *statuses)
+	}
+	if len(lm.prompts) != 1 {
+		t.Errorf("expected no LLM calls after cancel, got %d prompts", len(lm.prompts))
+	}
+	if got := db.status["s1"]; got != "canceled" {
+		t.Errorf("expected session status canceled, got %q", got)
+	}
+	if _, ok := db.checkpoints["s1"]; ok {
+		t.Error("expected checkpoint of a canceled session to be dropped")
+	}
+}
+
+// TestPipeline_CancelDeletesPartialBlobs verifies that blobs written by a
+// persist stage interrupted by cancellation are deleted.
+func TestPipeline_CancelDeletesPartialBlobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lm := &mockLLM{responses: []string{`["query1"]`, `{"topic":"T"}`, "Report", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com"}}, errIdx: -1}
+	blobs := &cancelingBlob{cancel: cancel}
+	p := pipeline.New(lm, ms.search, &mockDB{}, blobs)
+
+	if _, err := p.RunWithUpdates(ctx, "s1", "Test Topic", func(string, string) {}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(blobs.deleted) != 2 || blobs.deleted[0] != "partial.md" || blobs.deleted[1] != "partial.json" {
+		t.Errorf("expected partial blobs to be deleted, got %v", blobs.deleted)
+	}
+}
@@ -0,0 +1,169 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/storage"
+)
+
+// ErrNoCheckpoint is returned by Resume for a session that has no checkpoint.
+var ErrNoCheckpoint = errors.New("no checkpoint for session")
+
+// checkpoint is the snapshot of a run's State saved after each stage.
+type checkpoint struct {
+	Topic             string
+	Options           Options
+	PromptVersions    map[string]string
+	TopicLanguage     string
+	Language          string
+	Queries           []string
+	QueryLanguages    map[string]string
+	Sources           []event.SearchSource
+	Structured        event.StructuredResearch
+	Comparison        *event.ComparisonMatrix
+	Rounds            int
+	Report            string
+	Citations         []artifacts.Citation
+	RemovedCitations  []int
+	Summary           string
+	ReportMDKey       string
+	ReportJSONKey     string
+	SkippedStages     []string
+	StructuringFailed bool
+	StructureRepairs  int
+	Usage             []event.TokenUsage
+}
+
+// saveCheckpoint records st as the output of the named stage. A failed save
+// only costs the ability to resume, so it is logged and the run continues.
+func (p *Pipeline) saveCheckpoint(st *State, stage string) {
+	data, err := json.Marshal(checkpoint{
+		Topic:             st.Topic,
+		Options:           st.Options,
+		PromptVersions:    st.PromptVersions,
+		TopicLanguage:     st.TopicLanguage,
+		Language:          st.Language,
+		Queries:           st.Queries,
+		QueryLanguages:    st.QueryLanguages,
+		Sources:           st.Sources,
+		Structured:        st.Structured,
+		Comparison:        st.Comparison,
+		Rounds:            st.Rounds,
+		Report:            st.Report,
+		Citations:         st.Citations,
+		RemovedCitations:  st.RemovedCitations,
+		Summary:           st.Summary,
+		ReportMDKey:       st.ReportMDKey,
+		ReportJSONKey:     st.ReportJSONKey,
+		SkippedStages:     st.SkippedStages,
+		StructuringFailed: st.structuringFailed,
+		StructureRepairs:  st.StructureRepairs,
+		Usage:             st.Usage(),
+	})
+	if err == nil {
+		err = p.db.SaveCheckpoint(st.SessionID, stage, data)
+	}
+	if err != nil {
+		log.Printf("[PIPELINE] %s checkpoint after %s failed: %v", st.SessionID, stage, err)
+	}
+}
+
+// Resume continues sessionID from its latest checkpoint, running the stages
+// registered after the checkpointed one with the options of the original run.
+// It returns ErrNoCheckpoint when the session has no checkpoint.
+func (p *Pipeline) Resume(ctx context.Context, sessionID string, onUpdate func(status, detail string)) (*Result, error) {
+	stage, data, err := p.db.LoadCheckpoint(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if stage == "" {
+		return nil, ErrNoCheckpoint
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("decode checkpoint: %w", err)
+	}
+
+	stages := p.stages.Stages()
+	next := -1
+	for i, s := range stages {
+		if s.Name() == stage {
+			next = i + 1
+			break
+		}
+	}
+	if next < 0 {
+		return nil, fmt.Errorf("checkpoint stage %q is not registered", stage)
+	}
+
+	st := &State{
+		SessionID:         sessionID,
+		Topic:             cp.Topic,
+		Options:           cp.Options,
+		PromptVersions:    cp.PromptVersions,
+		TopicLanguage:     cp.TopicLanguage,
+		Language:          cp.Language,
+		Queries:           cp.Queries,
+		QueryLanguages:    cp.QueryLanguages,
+		Sources:           cp.Sources,
+		Structured:        cp.Structured,
+		Comparison:        cp.Comparison,
+		Rounds:            cp.Rounds,
+		Report:            cp.Report,
+		Citations:         cp.Citations,
+		RemovedCitations:  cp.RemovedCitations,
+		Summary:           cp.Summary,
+		ReportMDKey:       cp.ReportMDKey,
+		ReportJSONKey:     cp.ReportJSONKey,
+		SkippedStages:     cp.SkippedStages,
+		StructureRepairs:  cp.StructureRepairs,
+		structuringFailed: cp.StructuringFailed,
+		onUpdate:          onUpdate,
+	}
+	st.restoreUsage(cp.Usage)
+	log.Printf("[PIPELINE] %s resuming after stage %s", sessionID, stage)
+	return p.run(ctx, st, stages[next:])
+}
+
+// UnfinishedSessions lists the sessions that never reached a terminal status.
+// Taken before the process accepts requests, it is exactly the set a previous
+// process left mid-run.
+func (p *Pipeline) UnfinishedSessions() ([]storage.SessionRecord, error) {
+	return p.db.ListUnfinishedSessions()
+}
+
+// RecoverSessions resumes sessions a previous process left unfinished, as
+// listed by UnfinishedSessions. Sessions without a checkpoint, or that cannot
+// be resumed, are marked failed. It returns the number of sessions resumed to
+// completion.
+func (p *Pipeline) RecoverSessions(ctx context.Context, sessions []storage.SessionRecord) (int, error) {
+	completed := 0
+	for _, s := range sessions {
+		if ctx.Err() != nil {
+			return completed, ctx.Err()
+		}
+		_, err := p.Resume(ctx, s.ID, nil)
+		switch {
+		case err == nil:
+			completed++
+		case ctx.Err() != nil:
+			return completed, ctx.Err()
+		case errors.Is(err, ErrNoCheckpoint):
+			log.Printf("[PIPELINE] %s interrupted in %q before any checkpoint, marking failed", s.ID, s.Status)
+			_ = p.db.UpdateSessionStatus(s.ID, "failed", "interrupted before the first checkpoint")
+		default:
+			log.Printf("[PIPELINE] %s resume failed: %v", s.ID, err)
+			// run marks the session failed itself; this covers errors before it starts.
+			if status, _, _ := p.db.GetSessionStatus(s.ID); status != "failed" {
+				_ = p.db.UpdateSessionStatus(s.ID, "failed", fmt.Sprintf("resume: %v", err))
+			}
+		}
+	}
+	return completed, nil
+}
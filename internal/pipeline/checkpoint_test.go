@@ -0,0 +1,154 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/storage"
+)
+
+// checkpointDB keeps checkpoints and session statuses in memory.
+type checkpointDB struct {
+	mockDB
+	mu          sync.Mutex
+	saved       []string // stage names, in checkpoint order
+	checkpoints map[string]string
+	states      map[string][]byte
+	status      map[string]string
+	unfinished  []storage.SessionRecord
+}
+
+func newCheckpointDB() *checkpointDB {
+	return &checkpointDB{checkpoints: map[string]string{}, states: map[string][]byte{}, status: map[string]string{}}
+}
+
+func (m *checkpointDB) UpdateSessionStatus(id, status, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status[id] = status
+	return nil
+}
+
+func (m *checkpointDB) GetSessionStatus(id string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status[id], "", nil
+}
+
+func (m *checkpointDB) SaveCheckpoint(id, stage string, state []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saved = append(m.saved, stage)
+	m.checkpoints[id] = stage
+	m.states[id] = state
+	return nil
+}
+
+func (m *checkpointDB) LoadCheckpoint(id string) (string, []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.checkpoints[id], m.states[id], nil
+}
+
+func (m *checkpointDB) DeleteCheckpoint(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.checkpoints, id)
+	delete(m.states, id)
+	return nil
+}
+
+func (m *checkpointDB) ListUnfinishedSessions() ([]storage.SessionRecord, error) {
+	return m.unfinished, nil
+}
+
+// TestPipeline_CheckpointsEachStage verifies that a checkpoint is saved after
+// every stage and removed once the run completes.
+func TestPipeline_CheckpointsEachStage(t *testing.T) {
+	lm := &mockLLM{responses: []string{`["query1"]`, `{"topic":"T"}`, "Report", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com"}}, errIdx: -1}
+	db := newCheckpointDB()
+	p := pipeline.New(lm, ms.search, db, &mockBlob{})
+
+	if _, err := p.RunWithUpdates(context.Background(), "s1", "Test Topic", func(string, string) {}); err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+	if got, want := strings.Join(db.saved, ","), strings.Join(p.Stages().Names(), ","); got != want {
+		t.Errorf("checkpoints: want %s, got %s", want, got)
+	}
+	if _, ok := db.checkpoints["s1"]; ok {
+		t.Error("expected checkpoint to be deleted after completion")
+	}
+}
+
+// TestPipeline_ResumeFromCheckpoint verifies that a run interrupted during the
+// report stage resumes there with its earlier outputs, without searching again.
+func TestPipeline_ResumeFromCheckpoint(t *testing.T) {
+	db := newCheckpointDB()
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com", Title: "A"}}, errIdx: -1}
+	first := &mockLLM{responses: []string{`["query1"]`, `{"topic":"T","key_findings":[{"finding":"F","confidence":0.5}]}`}}
+	p := pipeline.New(first, ms.search, db, &mockBlob{})
+	if _, err := p.RunWithOptions(context.Background(), "s1", "Test Topic", pipeline.Options{ReportFormat: pipeline.ReportFormatBrief, IncludeUnverified: true}, func(string, string) {}); err == nil {
+		t.Fatal("expected the first run to fail when the LLM runs out of responses")
+	}
+	if db.checkpoints["s1"] == "" {
+		t.Fatal("expected a checkpoint from the interrupted run")
+	}
+
+	searches := ms.calls
+	second := &mockLLM{responses: []string{"Report", "Summary"}}
+	blobs := &recordingBlob{}
+	resumed := pipeline.New(second, ms.search, db, blobs)
+	res, err := resumed.Resume(context.Background(), "s1", func(string, string) {})
+	if err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if res.SessionID != "s1" {
+		t.Errorf("unexpected session id %q", res.SessionID)
+	}
+	if ms.calls != searches {
+		t.Errorf("resume searched again: %d calls, want %d", ms.calls, searches)
+	}
+	if len(second.prompts) == 0 || !strings.Contains(second.prompts[0], "Write a comprehensive report") || !strings.Contains(second.prompts[0], `"F"`) {
+		t.Errorf("expected resume to start at the report stage, prompts: %v", second.prompts)
+	}
+	if !strings.Contains(second.prompts[0], "Keep it brief") {
+		t.Errorf("resumed run lost the original options:\n%s", second.prompts[0])
+	}
+	if b := blobs.bundle(t); b.Topic != "Test Topic" || len(b.Sources) != 1 {
+		t.Errorf("unexpected bundle after resume: %+v", b)
+	}
+}
+
+// TestPipeline_RecoverSessions verifies that sessions with a checkpoint are
+// resumed and sessions without one are marked failed.
+func TestPipeline_RecoverSessions(t *testing.T) {
+	db := newCheckpointDB()
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com"}}, errIdx: -1}
+	interrupted := pipeline.New(&mockLLM{responses: []string{`["query1"]`}}, ms.search, db, &mockBlob{})
+	_, _ = interrupted.RunWithUpdates(context.Background(), "with-checkpoint", "Topic", func(string, string) {})
+	db.unfinished = []storage.SessionRecord{
+		{ID: "with-checkpoint", Topic: "Topic", Status: "structuring"},
+		{ID: "no-checkpoint", Topic: "Other", Status: "queued"},
+	}
+
+	lm := &mockLLM{responses: []string{`{"topic":"T"}`, "Report", "Summary"}}
+	p := pipeline.New(lm, ms.search, db, &mockBlob{})
+	unfinished, err := p.UnfinishedSessions()
+	if err != nil {
+		t.Fatalf("UnfinishedSessions: %v", err)
+	}
+	n, err := p.RecoverSessions(context.Background(), unfinished)
+	if err != nil {
+		t.Fatalf("RecoverSessions: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 session recovered, got %d", n)
+	}
+	if got := db.status["no-checkpoint"]; got != "failed" {
+		t.Errorf("expected session without checkpoint to be failed, got %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/event"
+)
+
+// citationPattern matches inline citations such as [1], [2, 3] and [4][5]
+// (the latter as two matches).
+var citationPattern = regexp.MustCompile(`\s?\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// citationMargin is how far past the last source a bracketed number is still
+// taken for a citation of a source the LLM invented. Larger numbers, such as
+// the year in "[2024]", are prose and left alone.
+const citationMargin = 10
+
+// citeSources checks the report's citations against the numbered sources and
+// appends the references section. A streamed report is replaced by the final
+// text, since citations were renumbered after it was streamed.
+func (p *Pipeline) citeSources(_ context.Context, st *State) error {
+	report, citations, removed := ResolveCitations(st.Report, st.Sources)
+	if len(removed) > 0 {
+		log.Printf("[PIPELINE] %s removed citations to unknown sources: %v", st.SessionID, removed)
+	}
+	st.Report = report
+	st.Citations = citations
+	st.RemovedCitations = removed
+	if st.reportStreamed {
+		st.Update(StatusReportDone, st.Report)
+	}
+	return nil
+}
+
+// ResolveCitations validates the numbered citations in report against
+// sources, where [n] refers to sources[n-1]. Citations to numbers without a
+// source are removed and returned in removed; bracketed numbers too large to
+// be citations are kept as text. The remaining citations are renumbered in
+// order of first use, and a references section listing them is appended to
+// the report, with the publication date and credibility of each source that
+// has them. citations maps each new number to its source.
+func ResolveCitations(report string, sources []event.SearchSource) (resolved string, citations []artifacts.Citation, removed []int) {
+	renumber := make(map[int]int)
+	seenRemoved := make(map[int]bool)
+
+	body := citationPattern.ReplaceAllStringFunc(report, func(match string) string {
+		lead := ""
+		if strings.HasPrefix(match, " ") || strings.HasPrefix(match, "\t") || strings.HasPrefix(match, "\n") {
+			lead = match[:1]
+		}
+		inner := strings.Trim(strings.TrimSpace(match), "[]")
+		var nums []int
+		for _, part := range strings.Split(inner, ",") {
+			n, _ := strconv.Atoi(strings.TrimSpace(part))
+			nums = append(nums, n)
+		}
+		if !slices.ContainsFunc(nums, func(n int) bool { return n >= 1 && n <= len(sources)+citationMargin }) {
+			return match
+		}
+		var kept []string
+		for _, n := range nums {
+			if n < 1 || n > len(sources) {
+				if !seenRemoved[n] {
+					seenRemoved[n] = true
+					removed = append(removed, n)
+				}
+				continue
+			}
+			num, ok := renumber[n]
+			if !ok {
+				num = len(renumber) + 1
+				renumber[n] = num
+				src := sources[n-1]
+				citations = append(citations, artifacts.Citation{Number: num, URL: src.URL, Title: src.Title, Credibility: src.Credibility, Published: src.Published})
+			}
+			kept = append(kept, strconv.Itoa(num))
+		}
+		if len(kept) == 0 {
+			return ""
+		}
+		return lead + "[" + strings.Join(kept, ", ") + "]"
+	})
+
+	if len(citations) == 0 {
+		return body, nil, removed
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(body, "\n"))
+	sb.WriteString("\n\n" + artifacts.ReferencesHeading + "\n\n")
+	for _, c := range citations {
+		if c.Title != "" {
+			sb.WriteString(fmt.Sprintf("[%d] %s. %s", c.Number, c.Title, c.URL))
+		} else {
+			sb.WriteString(fmt.Sprintf("[%d] %s", c.Number, c.URL))
+		}
+		var notes []string
+		if !c.Published.IsZero() {
+			notes = append(notes, "published "+c.Published.Format(time.DateOnly))
+		}
+		if c.Credibility > 0 {
+			notes = append(notes, fmt.Sprintf("credibility %.2f", c.Credibility))
+		}
+		if len(notes) > 0 {
+			sb.WriteString(" (" + strings.Join(notes, ", ") + ")")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), citations, removed
+}
+
+// numberedSources lists the sources as the report prompt cites them, with
+// their publication dates when known.
+func numberedSources(sources []event.SearchSource) string {
+	var sb strings.Builder
+	for i, s := range sources {
+		title := s.Title
+		if title == "" {
+			title = s.URL
+		}
+		sb.WriteString(fmt.Sprintf("[%d] %s (%s)", i+1, title, s.URL))
+		if date := publishedDate(s); date != "" {
+			sb.WriteString(", published " + date)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// publishedDate formats the publication date of s, or returns "" when it is
+// unknown.
+func publishedDate(s event.SearchSource) string {
+	if s.Published.IsZero() {
+		return ""
+	}
+	return s.Published.Format(time.DateOnly)
+}
+
+// sourceNumbers maps each source URL to its citation number.
+func sourceNumbers(sources []event.SearchSource) map[string]int {
+	nums := make(map[string]int, len(sources))
+	for i, s := range sources {
+		if _, ok := nums[s.URL]; !ok {
+			nums[s.URL] = i + 1
+		}
+	}
+	return nums
+}
@@ -0,0 +1,101 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+func TestResolveCitations(t *testing.T) {
+	sources := []event.SearchSource{
+		{URL: "http://a.com", Title: "A"},
+		{URL: "http://b.com"},
+		{URL: "http://c.com", Title: "C"},
+	}
+	report := "Go is fast [3]. Goroutines are cheap [1, 3]. Unicorns exist [7]. Both [2][9].\n"
+
+	got, citations, removed := pipeline.ResolveCitations(report, sources)
+
+	wantBody := "Go is fast [1]. Goroutines are cheap [2, 1]. Unicorns exist. Both [3]."
+	if !strings.HasPrefix(got, wantBody) {
+		t.Errorf("body = %q, want prefix %q", got, wantBody)
+	}
+	wantRefs := "## References\n\n[1] C. http://c.com\n[2] A. http://a.com\n[3] http://b.com\n"
+	if !strings.HasSuffix(got, wantRefs) {
+		t.Errorf("references section = %q, want suffix %q", got, wantRefs)
+	}
+	if len(citations) != 3 || citations[0].URL != "http://c.com" || citations[0].Number != 1 {
+		t.Errorf("unexpected citation map: %+v", citations)
+	}
+	if len(removed) != 2 || removed[0] != 7 || removed[1] != 9 {
+		t.Errorf("expected citations 7 and 9 removed, got %v", removed)
+	}
+}
+
+func TestResolveCitations_KeepsBracketedNumbers(t *testing.T) {
+	sources := []event.SearchSource{{URL: "http://a.com", Title: "A"}}
+	report := "Sales peaked [2024] and fell [1]. Unknown [4]. See [0].\n"
+
+	got, citations, removed := pipeline.ResolveCitations(report, sources)
+
+	if want := "Sales peaked [2024] and fell [1]. Unknown. See [0]."; !strings.HasPrefix(got, want) {
+		t.Errorf("body = %q, want prefix %q", got, want)
+	}
+	if len(citations) != 1 || len(removed) != 1 || removed[0] != 4 {
+		t.Errorf("expected only citation 4 removed, got %+v %v", citations, removed)
+	}
+}
+
+func TestResolveCitations_NoCitations(t *testing.T) {
+	got, citations, removed := pipeline.ResolveCitations("Plain report.", []event.SearchSource{{URL: "http://a.com"}})
+	if got != "Plain report." || citations != nil || removed != nil {
+		t.Errorf("expected report unchanged without citations, got %q %v %v", got, citations, removed)
+	}
+}
+
+// TestPipeline_ReportCitesNumberedSources verifies that the report prompt
+// numbers the sources, and that the citation map and references section are
+// stored with the report.
+func TestPipeline_ReportCitesNumberedSources(t *testing.T) {
+	lm := &mockLLM{
+		responses: []string{
+			`["query1"]`,
+			`{"topic":"T","key_findings":[{"finding":"F","evidence_urls":["http://b.com"],"confidence":0.7}]}`,
+			`[{"index":0,"verdict":"supported","strength":0.9,"reason":"r"}]`,
+			"Finding F holds [2]. Made up [5].",
+			"Summary",
+		},
+	}
+	ms := &mockSearcher{
+		results: []pipeline.SearchResult{
+			{Content: "snippet a", URL: "http://a.com", Title: "A"},
+			{Content: "snippet b", URL: "http://b.com", Title: "B"},
+		},
+		errIdx: -1,
+	}
+	blobs := &recordingBlob{}
+	p := pipeline.New(lm, ms.search, &mockDB{}, blobs)
+
+	if _, err := p.RunWithUpdates(context.Background(), uuid.New().String(), "Test Topic", func(string, string) {}); err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+
+	prompt := lm.prompts[3]
+	if !strings.Contains(prompt, "[1] A (http://a.com)") || !strings.Contains(prompt, "[2] B (http://b.com)") {
+		t.Errorf("report prompt missing numbered sources:\n%s", prompt)
+	}
+	b := blobs.bundle(t)
+	if len(b.Citations) != 1 || b.Citations[0].Number != 1 || b.Citations[0].URL != "http://b.com" {
+		t.Errorf("unexpected citations in report.json: %+v", b.Citations)
+	}
+	if len(b.RemovedCitations) != 1 || b.RemovedCitations[0] != 5 {
+		t.Errorf("expected citation 5 flagged as removed, got %v", b.RemovedCitations)
+	}
+	if !strings.Contains(b.Report, "Finding F holds [1]. Made up.") || !strings.Contains(b.Report, "[1] B. http://b.com") {
+		t.Errorf("unexpected report:\n%s", b.Report)
+	}
+}
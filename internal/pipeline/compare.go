@@ -0,0 +1,296 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/prompts"
+)
+
+const (
+	// maxSubjects and maxCriteria bound the size of the comparison matrix.
+	maxSubjects = 5
+	maxCriteria = 8
+	// autoCriteria is the number of criteria the LLM picks when none are given.
+	autoCriteria = 5
+	// ComparisonHeading starts the matrix section appended to the report.
+	ComparisonHeading = "## Comparison matrix"
+)
+
+// versusPattern separates the subjects of topics such as "Postgres vs MySQL".
+// Upper-case "VS" is left alone, since it is more often a name, as in
+// "VS Code".
+var versusPattern = regexp.MustCompile(`\s+(?:[Vv]s\.?|[Vv]ersus)\s+`)
+
+// ComparisonSubjects returns the subjects of an "X vs Y" topic, or nil when
+// the topic is not a comparison.
+func ComparisonSubjects(topic string) []string {
+	parts := versusPattern.Split(topic, -1)
+	if len(parts) < 2 {
+		return nil
+	}
+	var subjects []string
+	for _, part := range parts {
+		part = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(part), "?.!"))
+		if part == "" {
+			return nil
+		}
+		subjects = append(subjects, part)
+	}
+	if len(subjects) > maxSubjects {
+		subjects = subjects[:maxSubjects]
+	}
+	return subjects
+}
+
+// comparing reports whether the run compares several subjects.
+func (o Options) comparing() bool {
+	return len(o.Subjects) >= 2
+}
+
+// ComparisonSchema returns the JSON schema of the compare stage's LLM output.
+// Cells cite their evidence by source number.
+func ComparisonSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"criteria": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"cells": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"subject":    map[string]any{"type": "string"},
+						"criterion":  map[string]any{"type": "string"},
+						"assessment": map[string]any{"type": "string"},
+						"sources":    map[string]any{"type": "array", "items": map[string]any{"type": "integer"}},
+					},
+					"required": []string{"subject", "criterion", "assessment"},
+				},
+			},
+		},
+		"required": []string{"cells"},
+	}
+}
+
+// compareSubjects builds the subject × criterion matrix of a comparison run
+// from the findings and sources. Runs that compare nothing skip it, and LLM
+// or parse failures leave the report without a matrix.
+func (p *Pipeline) compareSubjects(ctx context.Context, st *State) error {
+	if !st.Options.comparing() || len(st.Sources) == 0 {
+		return nil
+	}
+	st.Update("comparing", strings.Join(st.Options.Subjects, " vs "))
+
+	structured := st.Structured
+	structured.KeyFindings = reportedFindings(structured.KeyFindings, st.Options.IncludeUnverified)
+	findingsJSON, _ := json.MarshalIndent(reportInput(structured, st.Sources), "", "  ")
+	prompt, err := p.prompts.Render(prompts.Compare, map[string]any{
+		"Subjects":    st.Options.Subjects,
+		"Criteria":    st.Options.Criteria,
+		"NumCriteria": autoCriteria,
+		"Language":    st.Language,
+		"Structured":  string(findingsJSON),
+		"Sources":     comparisonSources(st.Sources),
+	})
+	if err != nil {
+		return err
+	}
+
+	raw, err := p.generateJSON(ctx, st, prompt, ComparisonSchema())
+	if err != nil {
+		log.Printf("[PIPELINE] %s comparison failed: %v", st.SessionID, err)
+		return nil
+	}
+	matrix, err := parseComparison(raw, st.Options.Subjects, st.Options.Criteria, st.Sources)
+	if err != nil {
+		log.Printf("[PIPELINE] %s comparison unusable: %v", st.SessionID, err)
+		return nil
+	}
+	st.Comparison = matrix
+	return nil
+}
+
+// comparisonSources numbers the sources as the report does and adds an
+// excerpt of each, so that cells can cite the evidence they rest on.
+func comparisonSources(sources []event.SearchSource) string {
+	var sb strings.Builder
+	for i, s := range sources {
+		excerpt := s.Content
+		if excerpt == "" {
+			excerpt = s.Snippet
+		}
+		sb.WriteString(fmt.Sprintf("[%d] %s (%s)\n%s\n\n", i+1, titleOrURL(s), s.URL, truncate(excerpt, evidenceChars)))
+	}
+	return sb.String()
+}
+
+func titleOrURL(s event.SearchSource) string {
+	if s.Title != "" {
+		return s.Title
+	}
+	return s.URL
+}
+
+// parseComparison decodes the LLM's comparison and keeps only cells for the
+// requested subjects and criteria, matched case-insensitively. When no
+// criteria were requested, those chosen by the LLM are used. Source numbers
+// are resolved to evidence URLs; unknown numbers are dropped.
+func parseComparison(raw string, subjects, criteria []string, sources []event.SearchSource) (*event.ComparisonMatrix, error) {
+	obj, err := extractJSONObject(raw)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Criteria []string `json:"criteria"`
+		Cells    []struct {
+			Subject    string `json:"subject"`
+			Criterion  string `json:"criterion"`
+			Assessment string `json:"assessment"`
+			Sources    []int  `json:"sources"`
+		} `json:"cells"`
+	}
+	if err := json.Unmarshal([]byte(obj), &out); err != nil {
+		return nil, err
+	}
+
+	if len(criteria) == 0 {
+		criteria = out.Criteria
+		for _, c := range out.Cells {
+			criteria = append(criteria, c.Criterion)
+		}
+	}
+	criterionNames := canonicalNames(criteria, maxCriteria)
+	subjectNames := canonicalNames(subjects, maxSubjects)
+
+	type key struct{ subject, criterion string }
+	cells := make(map[key]event.ComparisonCell)
+	for _, c := range out.Cells {
+		subject, ok := subjectNames[normalizeName(c.Subject)]
+		if !ok {
+			continue
+		}
+		criterion, ok := criterionNames[normalizeName(c.Criterion)]
+		if !ok {
+			continue
+		}
+		k := key{subject, criterion}
+		assessment := strings.TrimSpace(c.Assessment)
+		if _, dup := cells[k]; dup || assessment == "" {
+			continue
+		}
+		cell := event.ComparisonCell{Subject: subject, Criterion: criterion, Assessment: assessment, EvidenceURLs: []string{}}
+		for _, n := range c.Sources {
+			if n >= 1 && n <= len(sources) && !containsString(cell.EvidenceURLs, sources[n-1].URL) {
+				cell.EvidenceURLs = append(cell.EvidenceURLs, sources[n-1].URL)
+			}
+		}
+		cells[k] = cell
+	}
+	if len(cells) == 0 {
+		return nil, fmt.Errorf("no cells for the requested subjects and criteria")
+	}
+
+	matrix := &event.ComparisonMatrix{Subjects: orderedNames(subjects, subjectNames)}
+	for _, c := range orderedNames(criteria, criterionNames) {
+		used := false
+		for _, s := range matrix.Subjects {
+			if cell, ok := cells[key{s, c}]; ok {
+				matrix.Cells = append(matrix.Cells, cell)
+				used = true
+			}
+		}
+		if used {
+			matrix.Criteria = append(matrix.Criteria, c)
+		}
+	}
+	return matrix, nil
+}
+
+func normalizeName(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// canonicalNames maps the normalized form of the first limit distinct names
+// to their spelling in names.
+func canonicalNames(names []string, limit int) map[string]string {
+	out := make(map[string]string)
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		norm := normalizeName(n)
+		if norm == "" {
+			continue
+		}
+		if _, dup := out[norm]; dup {
+			continue
+		}
+		if len(out) == limit {
+			break
+		}
+		out[norm] = n
+	}
+	return out
+}
+
+// orderedNames lists the canonical names in the order they first appear in
+// names.
+func orderedNames(names []string, canonical map[string]string) []string {
+	var out []string
+	for _, n := range names {
+		if c, ok := canonical[normalizeName(n)]; ok && !containsString(out, c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// comparisonTable renders the matrix as a Markdown table with a row per
+// criterion and a column per subject. Evidence is cited by source number so
+// that the cite stage renumbers it along with the report's citations.
+func comparisonTable(m *event.ComparisonMatrix, sources []event.SearchSource) string {
+	nums := sourceNumbers(sources)
+	cell := func(subject, criterion string) string {
+		for _, c := range m.Cells {
+			if c.Subject != subject || c.Criterion != criterion {
+				continue
+			}
+			text := tableText(c.Assessment)
+			var refs []string
+			for _, u := range c.EvidenceURLs {
+				if n, ok := nums[u]; ok {
+					refs = append(refs, fmt.Sprint(n))
+				}
+			}
+			if len(refs) > 0 {
+				text += " [" + strings.Join(refs, ", ") + "]"
+			}
+			return text
+		}
+		return "n/a"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| Criterion |")
+	for _, s := range m.Subjects {
+		sb.WriteString(" " + tableText(s) + " |")
+	}
+	sb.WriteString("\n|---|" + strings.Repeat("---|", len(m.Subjects)) + "\n")
+	for _, c := range m.Criteria {
+		sb.WriteString("| " + tableText(c) + " |")
+		for _, s := range m.Subjects {
+			sb.WriteString(" " + cell(s, c) + " |")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// tableText makes s safe to use inside a Markdown table cell.
+func tableText(s string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", "/")
+}
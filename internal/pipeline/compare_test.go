@@ -0,0 +1,119 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+func TestComparisonSubjects(t *testing.T) {
+	tests := []struct {
+		topic string
+		want  []string
+	}{
+		{"Postgres vs MySQL", []string{"Postgres", "MySQL"}},
+		{"React vs. Vue versus Svelte?", []string{"React", "Vue", "Svelte"}},
+		{"History of the VS Code editor", nil},
+		{"vs MySQL", nil},
+	}
+	for _, tt := range tests {
+		got := pipeline.ComparisonSubjects(tt.topic)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("ComparisonSubjects(%q) = %v, want %v", tt.topic, got, tt.want)
+		}
+	}
+}
+
+// TestPipeline_ComparisonMode verifies that a comparison run generates
+// queries per subject, keeps only matrix cells for the requested subjects
+// and criteria, and adds the matrix to the report and the bundle.
+func TestPipeline_ComparisonMode(t *testing.T) {
+	comparison := `{"cells": [
+		{"subject": "go", "criterion": "speed", "assessment": "Fast | compiled.", "sources": [2]},
+		{"subject": "Rust", "criterion": "Speed", "assessment": "Faster.", "sources": [1, 9]},
+		{"subject": "Java", "criterion": "Speed", "assessment": "Not requested."},
+		{"subject": "Go", "criterion": "Price", "assessment": "Not requested."}
+	]}`
+	lm := &mockLLM{responses: []string{`["go speed"]`, `["rust speed"]`, `{"topic":"T"}`, comparison, "Go and Rust compared.", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{
+		{Content: "snippet a", URL: "http://a.com", Title: "A"},
+		{Content: "snippet b", URL: "http://b.com", Title: "B"},
+	}, errIdx: -1}
+	blobs := &recordingBlob{}
+	p := pipeline.New(lm, ms.search, &mockDB{}, blobs)
+
+	var details []string
+	opts := pipeline.Options{NumQueries: 1, Subjects: []string{"Go", "Rust"}, Criteria: []string{"Speed"}}
+	_, err := p.RunWithOptions(context.Background(), "s1", "Go or Rust for services", opts, func(status, detail string) {
+		if status == "comparing" {
+			details = append(details, detail)
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(lm.prompts[0], `about "Go" alone`) || !strings.Contains(lm.prompts[1], `about "Rust" alone`) {
+		t.Errorf("expected one queries prompt per subject, got %q and %q", lm.prompts[0], lm.prompts[1])
+	}
+	if ms.calls != 2 {
+		t.Errorf("expected 2 searches, got %d", ms.calls)
+	}
+	if len(details) != 1 || details[0] != "Go vs Rust" {
+		t.Errorf("expected one comparing update, got %v", details)
+	}
+	if !strings.Contains(lm.prompts[4], "| Speed | Fast / compiled. [2] | Faster. [1] |") {
+		t.Errorf("expected the report prompt to include the matrix, got:\n%s", lm.prompts[4])
+	}
+
+	b := blobs.bundle(t)
+	m := b.Comparison
+	if m == nil {
+		t.Fatal("expected a comparison matrix in the bundle")
+	}
+	if strings.Join(m.Subjects, ",") != "Go,Rust" || strings.Join(m.Criteria, ",") != "Speed" || len(m.Cells) != 2 {
+		t.Fatalf("unexpected matrix: %+v", m)
+	}
+	if m.Cells[1].Subject != "Rust" || len(m.Cells[1].EvidenceURLs) != 1 || m.Cells[1].EvidenceURLs[0] != "http://a.com" {
+		t.Errorf("unexpected Rust cell: %+v", m.Cells[1])
+	}
+	// The cite stage renumbers the table's citations in order of first use.
+	if !strings.Contains(b.Report, pipeline.ComparisonHeading+"\n\n| Criterion | Go | Rust |\n|---|---|---|\n| Speed | Fast / compiled. [1] | Faster. [2] |") {
+		t.Errorf("expected the matrix table in the report, got:\n%s", b.Report)
+	}
+}
+
+func TestPipeline_ComparisonFromTopic(t *testing.T) {
+	comparison := `{"criteria": ["Cost"], "cells": [{"subject": "Postgres", "criterion": "Cost", "assessment": "Free."}]}`
+	lm := &mockLLM{responses: []string{`["q1"]`, `["q2"]`, `{"topic":"T"}`, comparison, "Report", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com"}}, errIdx: -1}
+	blobs := &recordingBlob{}
+	p := pipeline.New(lm, ms.search, &mockDB{}, blobs)
+
+	opts := pipeline.Options{NumQueries: 1}
+	if _, err := p.RunWithOptions(context.Background(), "s1", "Postgres vs MySQL", opts, func(string, string) {}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := blobs.bundle(t).Comparison
+	if m == nil || strings.Join(m.Subjects, ",") != "Postgres,MySQL" || strings.Join(m.Criteria, ",") != "Cost" {
+		t.Fatalf("expected subjects from the topic and criteria chosen by the LLM, got %+v", m)
+	}
+	if !strings.Contains(blobs.bundle(t).Report, "| Cost | Free. | n/a |") {
+		t.Errorf("expected a placeholder for the missing cell, got:\n%s", blobs.bundle(t).Report)
+	}
+}
+
+func TestParseOptions_Comparison(t *testing.T) {
+	opts, err := pipeline.ParseOptions(map[string]any{"subjects": []any{"PostgreSQL", "MySQL"}, "criteria": []any{"Licensing"}})
+	if err != nil {
+		t.Fatalf("ParseOptions: %v", err)
+	}
+	if strings.Join(opts.Subjects, ",") != "PostgreSQL,MySQL" || strings.Join(opts.Criteria, ",") != "Licensing" {
+		t.Errorf("expected subjects and criteria to keep their case, got %v and %v", opts.Subjects, opts.Criteria)
+	}
+	if _, err := pipeline.ParseOptions(map[string]any{"subjects": []any{"PostgreSQL"}}); err == nil {
+		t.Error("expected a single subject to be rejected")
+	}
+}
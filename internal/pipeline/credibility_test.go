@@ -0,0 +1,61 @@
+package pipeline_test
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// TestPipeline_ScoresSourceCredibility verifies that sources are scored,
+// that the scores reach the structuring prompt and the references, and that
+// finding confidence follows the credibility of the evidence.
+func TestPipeline_ScoresSourceCredibility(t *testing.T) {
+	lm := &mockLLM{
+		responses: []string{
+			`["query1"]`,
+			`{"topic":"T","key_findings":[` +
+				`{"finding":"Trusted","evidence_urls":["https://www.nih.gov/a"],"confidence":0.6},` +
+				`{"finding":"Pinned","evidence_urls":["https://www.pinterest.com/pin/1"],"confidence":0.6}]}`,
+			"Trusted holds [1]. Pinned too [2].",
+			"Summary",
+		},
+	}
+	ms := &mockSearcher{
+		results: []pipeline.SearchResult{
+			{Content: "snippet a", URL: "https://www.nih.gov/a", Title: "NIH"},
+			{Content: "snippet b", URL: "https://www.pinterest.com/pin/1", Title: "Pin"},
+		},
+		errIdx: -1,
+	}
+	db := &sourceRecordingDB{}
+	blobs := &recordingBlob{}
+	p := pipeline.New(lm, ms.search, db, blobs)
+	if err := p.Stages().Remove(pipeline.StageVerify); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.RunWithUpdates(context.Background(), "s1", "Test Topic", nil); err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+
+	if len(db.sources) != 2 || db.sources[0].Credibility != 0.75 || db.sources[1].Credibility != 0.2 {
+		t.Fatalf("unexpected source scores: %+v", db.sources)
+	}
+	if prompt := lm.prompts[1]; !strings.Contains(prompt, "Credibility: 0.75") || !strings.Contains(prompt, "Credibility: 0.20") {
+		t.Errorf("structuring prompt missing credibility:\n%s", prompt)
+	}
+	b := blobs.bundle(t)
+	findings := b.Structured.KeyFindings
+	if len(findings) != 2 || math.Abs(findings[0].Confidence-0.75) > 1e-9 || math.Abs(findings[1].Confidence-0.42) > 1e-9 {
+		t.Errorf("expected confidence weighed by credibility, got %+v", findings)
+	}
+	if !strings.Contains(b.Report, "[1] NIH. https://www.nih.gov/a (credibility 0.75)") {
+		t.Errorf("references missing credibility:\n%s", b.Report)
+	}
+	if len(b.Citations) != 2 || b.Citations[1].Credibility != 0.2 {
+		t.Errorf("unexpected citations: %+v", b.Citations)
+	}
+}
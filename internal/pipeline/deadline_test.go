@@ -0,0 +1,95 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+func deadlineRoutes(prompt string) string {
+	switch {
+	case strings.Contains(prompt, "specific search queries"):
+		return `["q"]`
+	case strings.Contains(prompt, "follow-up research round"):
+		return `["follow-up"]`
+	case strings.Contains(prompt, "Convert the search results"):
+		return `{"topic": "T", "key_findings": [{"finding": "F", "confidence": 0.5, "evidence_urls": ["http://a.com/q"]}], "open_questions": ["Q?"]}`
+	case strings.Contains(prompt, "fact checker"):
+		return `[{"index": 0, "verdict": "supported", "strength": 0.9, "reason": "r"}]`
+	case strings.Contains(prompt, "Write a comprehensive report"):
+		return "Report [1]."
+	case strings.Contains(prompt, "executive summary"):
+		return "Summary"
+	}
+	return "unexpected"
+}
+
+// runWithDeadline runs a three-round research under deadline, with searches
+// that take searchTime, and returns the result and the number of fetches.
+func runWithDeadline(t *testing.T, deadline, searchTime time.Duration) (*pipeline.Result, *routeLLM, int32) {
+	t.Helper()
+	lm := &routeLLM{routes: deadlineRoutes}
+	search := func(ctx context.Context, query string, _ pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
+		time.Sleep(searchTime)
+		return []pipeline.SearchResult{{Content: "snippet about " + query, URL: "http://a.com/" + query, Title: query}}, nil
+	}
+	p := pipeline.New(lm, search, &mockDB{}, &mockBlob{})
+	var fetches int32
+	p.SetFetcher(func(context.Context, string) (pipeline.Page, error) {
+		atomic.AddInt32(&fetches, 1)
+		return pipeline.Page{Text: "page text"}, nil
+	})
+
+	res, err := p.RunWithOptions(context.Background(), "s1", "Topic", pipeline.Options{Deadline: deadline, MaxRounds: 3}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return res, lm, atomic.LoadInt32(&fetches)
+}
+
+// TestPipeline_DeadlineSkipsOptionalWork verifies that a run whose search
+// used up the time before the report reserve skips fetching, further rounds,
+// verification and the summary, yet still delivers the report.
+func TestPipeline_DeadlineSkipsOptionalWork(t *testing.T) {
+	res, lm, fetches := runWithDeadline(t, 400*time.Millisecond, 350*time.Millisecond)
+
+	if got := strings.Join(res.SkippedStages, ","); got != "fetch,deepen,verify,summary" {
+		t.Errorf("skipped stages = %q", got)
+	}
+	if fetches != 0 || res.Rounds != 1 {
+		t.Errorf("expected no fetches and one round, got %d fetches and %d rounds", fetches, res.Rounds)
+	}
+	if len(lm.promptsWith("Write a comprehensive report")) != 1 || res.ReportMDKey == "" {
+		t.Error("expected the report to be written and saved")
+	}
+}
+
+// TestPipeline_DeadlineAllowsOptionalWork verifies that a run well within
+// its deadline skips nothing.
+func TestPipeline_DeadlineAllowsOptionalWork(t *testing.T) {
+	res, lm, fetches := runWithDeadline(t, time.Minute, 0)
+
+	if len(res.SkippedStages) != 0 {
+		t.Errorf("expected no skipped stages, got %v", res.SkippedStages)
+	}
+	if fetches == 0 || res.Rounds < 2 {
+		t.Errorf("expected fetching and deep research, got %d fetches and %d rounds", fetches, res.Rounds)
+	}
+	if len(lm.promptsWith("executive summary")) != 1 {
+		t.Error("expected a summary")
+	}
+}
+
+func TestParseOptions_Deadline(t *testing.T) {
+	opts, err := pipeline.ParseOptions(map[string]any{"deadline_seconds": float64(90)})
+	if err != nil {
+		t.Fatalf("ParseOptions: %v", err)
+	}
+	if opts.Deadline != 90*time.Second {
+		t.Errorf("Deadline = %v, want 90s", opts.Deadline)
+	}
+}
@@ -0,0 +1,179 @@
+package pipeline
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/user/research-assistant/internal/event"
+)
+
+const (
+	// shingleSize is the number of words per snippet shingle.
+	shingleSize = 3
+	// minShingles is the number of shingles a snippet needs before it is
+	// compared; shorter snippets are too generic to call duplicates.
+	minShingles = 4
+	// nearDuplicateSimilarity is the Jaccard similarity of two snippets' shingle
+	// sets above which their sources are merged.
+	nearDuplicateSimilarity = 0.8
+)
+
+// trackingParams are query parameters that identify a click, not a document.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"igshid": true, "mc_cid": true, "mc_eid": true, "_ga": true, "_gl": true,
+	"ref": true, "ref_src": true, "spm": true,
+}
+
+// dedupSources merges duplicate first-round sources.
+func (p *Pipeline) dedupSources(_ context.Context, st *State) error {
+	before := len(st.Sources)
+	st.Sources = DedupSources(st.Sources)
+	if removed := before - len(st.Sources); removed > 0 {
+		log.Printf("[PIPELINE] %s merged %d duplicate source(s), %d remain", st.SessionID, removed, len(st.Sources))
+	}
+	return nil
+}
+
+// DedupSources merges sources that point at the same document: URLs that are
+// equal after CanonicalURL, or snippets that are near-duplicates by shingle
+// similarity. The first occurrence is kept, in order, with the queries of
+// every duplicate added to its Queries, and missing title, snippet or content
+// filled in from the duplicates.
+func DedupSources(sources []event.SearchSource) []event.SearchSource {
+	out := make([]event.SearchSource, 0, len(sources))
+	byURL := make(map[string]int, len(sources))
+	var shingles []map[uint64]struct{}
+
+	for _, s := range sources {
+		s.URL = StripTracking(s.URL)
+		if len(s.Queries) == 0 && s.Query != "" {
+			s.Queries = []string{s.Query}
+		}
+		sh := snippetShingles(s.Snippet)
+
+		dup := -1
+		key := CanonicalURL(s.URL)
+		if i, ok := byURL[key]; ok {
+			dup = i
+		} else if len(sh) >= minShingles {
+			for i, other := range shingles {
+				if len(other) >= minShingles && jaccard(sh, other) >= nearDuplicateSimilarity {
+					dup = i
+					break
+				}
+			}
+		}
+
+		if dup < 0 {
+			byURL[key] = len(out)
+			out = append(out, s)
+			shingles = append(shingles, sh)
+			continue
+		}
+		byURL[key] = dup
+		mergeSource(&out[dup], s)
+	}
+	return out
+}
+
+// mergeSource folds a duplicate into the kept source.
+func mergeSource(kept *event.SearchSource, dup event.SearchSource) {
+	kept.Queries = appendUnique(append([]string(nil), kept.Queries...), dup.Queries...)
+	if kept.Title == "" {
+		kept.Title = dup.Title
+	}
+	if kept.Snippet == "" {
+		kept.Snippet = dup.Snippet
+	}
+	if kept.Content == "" {
+		kept.Content = dup.Content
+	}
+	if kept.Rank == 0 || (dup.Rank > 0 && dup.Rank < kept.Rank) {
+		kept.Rank = dup.Rank
+	}
+}
+
+// CanonicalURL returns a comparison key for rawURL: scheme and "www." are
+// dropped, the host is lower-cased, default ports, fragments, tracking
+// parameters and trailing slashes are removed, and the remaining query
+// parameters are sorted. Unparseable URLs are returned trimmed.
+func CanonicalURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(rawURL)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	path := strings.TrimRight(u.EscapedPath(), "/")
+	query := cleanQuery(u.Query()).Encode() // Encode sorts by key
+	key := host + path
+	if query != "" {
+		key += "?" + query
+	}
+	return key
+}
+
+// StripTracking removes the fragment and tracking parameters from rawURL,
+// leaving it otherwise unchanged.
+func StripTracking(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(rawURL)
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.RawQuery != "" {
+		u.RawQuery = cleanQuery(u.Query()).Encode()
+	}
+	return u.String()
+}
+
+func cleanQuery(q url.Values) url.Values {
+	for k := range q {
+		lk := strings.ToLower(k)
+		if trackingParams[lk] || strings.HasPrefix(lk, "utm_") {
+			q.Del(k)
+		}
+	}
+	return q
+}
+
+// snippetShingles hashes the overlapping word n-grams of a snippet.
+func snippetShingles(snippet string) map[uint64]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(snippet), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+	if len(words) < shingleSize {
+		return nil
+	}
+	set := make(map[uint64]struct{}, len(words)-shingleSize+1)
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+shingleSize], " ")))
+		set[h.Sum64()] = struct{}{}
+	}
+	return set
+}
+
+func jaccard(a, b map[uint64]struct{}) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	inter := 0
+	for k := range a {
+		if _, ok := b[k]; ok {
+			inter++
+		}
+	}
+	union := len(a) + len(b) - inter
+	if union == 0 {
+		return 0
+	}
+	return float64(inter) / float64(union)
+}
@@ -0,0 +1,69 @@
+package pipeline_test
+
+import (
+	"testing"
+
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	same := []string{
+		"https://www.example.com/post/",
+		"http://example.com/post",
+		"https://EXAMPLE.com:443/post?utm_source=x&utm_medium=y#comments",
+		"https://example.com/post?fbclid=abc",
+	}
+	want := pipeline.CanonicalURL(same[0])
+	for _, u := range same[1:] {
+		if got := pipeline.CanonicalURL(u); got != want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", u, got, want)
+		}
+	}
+	if pipeline.CanonicalURL("https://example.com/post?id=1&b=2") != pipeline.CanonicalURL("https://example.com/post?b=2&id=1") {
+		t.Error("query parameter order must not matter")
+	}
+	if pipeline.CanonicalURL("https://example.com/post?id=1") == pipeline.CanonicalURL("https://example.com/post?id=2") {
+		t.Error("meaningful query parameters must be kept")
+	}
+}
+
+func TestStripTracking(t *testing.T) {
+	got := pipeline.StripTracking("http://www.example.com/a?utm_campaign=z&page=2#top")
+	if want := "http://www.example.com/a?page=2"; got != want {
+		t.Errorf("StripTracking = %q, want %q", got, want)
+	}
+}
+
+func TestDedupSources(t *testing.T) {
+	snippet := "The Go scheduler multiplexes goroutines onto operating system threads using work stealing"
+	sources := []event.SearchSource{
+		{Query: "q1", URL: "https://go.dev/blog/sched?utm_source=news", Snippet: snippet, Rank: 3},
+		{Query: "q2", URL: "http://www.go.dev/blog/sched/", Title: "Scheduler", Rank: 1},
+		{Query: "q2", URL: "https://mirror.example.org/sched", Snippet: snippet + " efficiently."},
+		{Query: "q3", URL: "https://other.com/a", Snippet: "Go"},
+		{Query: "q3", URL: "https://other.com/b", Snippet: "Go"},
+	}
+
+	got := pipeline.DedupSources(sources)
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 sources after dedup, got %d: %+v", len(got), got)
+	}
+	first := got[0]
+	if first.URL != "https://go.dev/blog/sched" {
+		t.Errorf("expected tracking parameters stripped from kept URL, got %q", first.URL)
+	}
+	if len(first.Queries) != 2 || first.Queries[0] != "q1" || first.Queries[1] != "q2" {
+		t.Errorf("expected queries of all duplicates, got %v", first.Queries)
+	}
+	if first.Title != "Scheduler" || first.Rank != 1 {
+		t.Errorf("expected missing title and best rank taken from duplicates, got %+v", first)
+	}
+	if got[1].URL != "https://other.com/a" || got[2].URL != "https://other.com/b" {
+		t.Errorf("short identical snippets must not be merged: %+v", got[1:])
+	}
+	if len(sources[0].Queries) != 0 {
+		t.Error("DedupSources must not modify its input")
+	}
+}
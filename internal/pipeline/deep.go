@@ -0,0 +1,213 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/prompts"
+)
+
+// followUpQueriesPerRound caps the number of follow-up queries in one round.
+const followUpQueriesPerRound = 3
+
+// deepen runs follow-up research rounds driven by the open questions and
+// low-confidence findings of the research so far. It stops when the round
+// limit, the confidence target or the token budget is reached, when a round
+// produces nothing new, or when another round, estimated to take as long as
+// the slowest so far, would run into the time reserved for the report. Each
+// round emits a "researching_round" update.
+func (p *Pipeline) deepen(ctx context.Context, st *State) error {
+	if st.structuringFailed || st.Rounds == 0 {
+		return nil
+	}
+	// The first round is timed from the start of the run.
+	roundTime := time.Since(st.started)
+	for st.Rounds < st.Options.MaxRounds {
+		if confidenceReached(st.Structured, st.Options.ConfidenceTarget) {
+			log.Printf("[PIPELINE] %s confidence target reached after %d round(s)", st.SessionID, st.Rounds)
+			return nil
+		}
+		if st.overBudget() {
+			log.Printf("[PIPELINE] %s token budget reached after %d round(s)", st.SessionID, st.Rounds)
+			return nil
+		}
+		if spare, ok := st.spareTime(); ok && spare < roundTime {
+			log.Printf("[PIPELINE] %s deadline reached after %d round(s)", st.SessionID, st.Rounds)
+			st.skip(StageDeepen)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		started := time.Now()
+
+		gaps := researchGaps(st.Structured, st.Options.ConfidenceTarget)
+		queries, err := p.followUpQueries(ctx, st, gaps)
+		if err != nil {
+			log.Printf("[PIPELINE] %s follow-up query generation failed: %v", st.SessionID, err)
+			return nil
+		}
+		if len(queries) == 0 {
+			return nil
+		}
+
+		round := st.Rounds + 1
+		st.Update("researching_round", fmt.Sprintf("Round %d: %s", round, strings.Join(queries, "; ")))
+		st.Queries = append(st.Queries, queries...)
+		for _, q := range queries {
+			st.tagQuery(q, st.primarySearchLanguage())
+		}
+
+		// Hits already known from earlier rounds only gain the new query; the
+		// round itself works on the sources it discovered.
+		all := DedupSources(st.Sources)
+		known := len(all)
+		all = DedupSources(append(all, p.searchQueries(ctx, st, queries)...))
+		sources := all[known:]
+		if len(sources) == 0 {
+			st.Sources = all
+			return nil
+		}
+		sources = p.fetchSources(ctx, st, sources)
+		all = all[:known+len(sources)]
+		if len(sources) == 0 {
+			st.Sources = all
+			return nil
+		}
+		next, err := p.structure(ctx, st, sources)
+		if err != nil {
+			log.Printf("[PIPELINE] %s round %d structuring failed: %v", st.SessionID, round, err)
+			return nil
+		}
+		if strings.TrimSpace(next.Error) != "" {
+			log.Printf("[PIPELINE] %s round %d blocked: %s", st.SessionID, round, next.Error)
+			return nil
+		}
+
+		st.Sources = all
+		st.Structured = MergeStructuredResearch(st.Structured, next)
+		st.Rounds = round
+		roundTime = max(roundTime, time.Since(started))
+	}
+	return nil
+}
+
+// followUpQueries asks the LLM for queries that would close the given gaps,
+// dropping any query that was already searched.
+func (p *Pipeline) followUpQueries(ctx context.Context, st *State, gaps []string) ([]string, error) {
+	if len(gaps) == 0 {
+		return nil, nil
+	}
+	prompt, err := p.prompts.Render(prompts.FollowUp, map[string]any{"Gaps": gaps, "MaxQueries": followUpQueriesPerRound, "Language": st.primarySearchLanguage(), "Topic": st.Topic})
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := p.generate(ctx, st, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(st.Queries))
+	for _, q := range st.Queries {
+		seen[normalizeText(q)] = struct{}{}
+	}
+	var queries []string
+	for _, q := range extractJSONStringArray(raw) {
+		key := normalizeText(q)
+		if key == "" {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		queries = append(queries, strings.TrimSpace(q))
+		if len(queries) == followUpQueriesPerRound {
+			break
+		}
+	}
+	return queries, nil
+}
+
+// confidenceReached reports whether every finding meets target and no open
+// questions remain.
+func confidenceReached(sr event.StructuredResearch, target float64) bool {
+	if len(sr.KeyFindings) == 0 || len(sr.OpenQuestions) > 0 {
+		return false
+	}
+	for _, f := range sr.KeyFindings {
+		if f.Confidence < target {
+			return false
+		}
+	}
+	return true
+}
+
+// researchGaps lists the open questions and the findings whose confidence is
+// below target, phrased for a follow-up query prompt.
+func researchGaps(sr event.StructuredResearch, target float64) []string {
+	gaps := append([]string(nil), sr.OpenQuestions...)
+	for _, f := range sr.KeyFindings {
+		if f.Confidence < target {
+			gaps = append(gaps, fmt.Sprintf("Low-confidence finding (%.2f): %s", f.Confidence, f.Finding))
+		}
+	}
+	return gaps
+}
+
+// MergeStructuredResearch folds the results of a follow-up round into the
+// research so far. Findings with the same text keep the highest confidence
+// and the union of their evidence URLs. Open questions are replaced by those
+// of the newer round, since the round was aimed at answering the old ones.
+func MergeStructuredResearch(prev, next event.StructuredResearch) event.StructuredResearch {
+	merged := prev
+	merged.KeyFindings = append([]event.StructuredFinding(nil), prev.KeyFindings...)
+
+	index := make(map[string]int, len(merged.KeyFindings))
+	for i, f := range merged.KeyFindings {
+		index[normalizeText(f.Finding)] = i
+	}
+	for _, f := range next.KeyFindings {
+		key := normalizeText(f.Finding)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged.KeyFindings)
+			merged.KeyFindings = append(merged.KeyFindings, f)
+			continue
+		}
+		existing := &merged.KeyFindings[i]
+		if f.Confidence > existing.Confidence {
+			existing.Confidence = f.Confidence
+		}
+		existing.EvidenceURLs = appendUnique(append([]string(nil), existing.EvidenceURLs...), f.EvidenceURLs...)
+	}
+
+	merged.Challenges = appendUnique(append([]string(nil), prev.Challenges...), next.Challenges...)
+	merged.OpenQuestions = append([]string(nil), next.OpenQuestions...)
+	merged.Sources = append(append([]event.SearchSource(nil), prev.Sources...), next.Sources...)
+	return merged
+}
+
+func appendUnique(dst []string, values ...string) []string {
+	seen := make(map[string]struct{}, len(dst))
+	for _, v := range dst {
+		seen[v] = struct{}{}
+	}
+	for _, v := range values {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
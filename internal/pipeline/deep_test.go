@@ -0,0 +1,184 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// TestPipeline_DeepResearchRunsFollowUpRounds verifies that open questions
+// drive a follow-up round, that the round is reported as its own status, and
+// that deep research stops once the confidence target is met.
+func TestPipeline_DeepResearchRunsFollowUpRounds(t *testing.T) {
+	lm := &mockLLM{
+		responses: []string{
+			`["query1"]`,
+			`{"topic":"T","key_findings":[{"finding":"F1","evidence_urls":["http://a.com/query1"],"confidence":0.5}],"open_questions":["Why?"],"sources":[{"url":"http://a.com/query1","query":"query1","snippet":"s"}]}`,
+			// follow-up queries for round 2
+			`["query1","follow-up"]`,
+			`{"topic":"T","key_findings":[{"finding":"f1","evidence_urls":["http://a.com/follow-up"],"confidence":0.9},{"finding":"F2","evidence_urls":["http://a.com/follow-up"],"confidence":0.85}],"open_questions":[],"sources":[{"url":"http://a.com/follow-up","query":"follow-up","snippet":"s"}]}`,
+			`[{"index":0,"verdict":"supported","strength":0.9,"reason":"r"},{"index":1,"verdict":"supported","strength":0.9,"reason":"r"}]`,
+			"Report",
+			"Summary",
+		},
+	}
+	p := pipeline.New(lm, perQuerySearch, &mockDB{}, &mockBlob{})
+	opts := pipeline.DefaultOptions()
+	opts.MaxRounds = 3
+	p.SetOptions(opts)
+
+	var roundDetails []string
+	cb, statuses, mu := collectStatuses(func(status, detail string) {
+		if status == "researching_round" {
+			roundDetails = append(roundDetails, detail)
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := p.RunWithUpdates(ctx, uuid.New().String(), "Test Topic", cb)
+	if err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+	if result.Rounds != 2 {
+		t.Errorf("expected 2 rounds (confidence target met after round 2), got %d", result.Rounds)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(roundDetails) != 1 || roundDetails[0] != "Round 2: follow-up" {
+		t.Errorf("expected one round update for the new query only, got %v", roundDetails)
+	}
+	if countOf(*statuses, "searching") != 2 {
+		t.Errorf("expected 2 'searching' updates across both rounds, got %v", *statuses)
+	}
+	if firstIndexOf(*statuses, "researching_round") >= firstIndexOf(*statuses, "writing_report") {
+		t.Errorf("round updates must precede writing_report; statuses: %v", *statuses)
+	}
+}
+
+// TestPipeline_DeepResearchRespectsTokenBudget verifies that an exhausted
+// token budget prevents follow-up rounds and skips the optional verify and
+// summary stages.
+func TestPipeline_DeepResearchRespectsTokenBudget(t *testing.T) {
+	lm := &mockLLM{
+		responses: []string{
+			`["query1"]`,
+			`{"topic":"T","key_findings":[{"finding":"F1","evidence_urls":["http://a.com"],"confidence":0.2}],"open_questions":["Why?"],"sources":[{"url":"http://a.com","query":"query1","snippet":"s"}]}`,
+			"Report",
+		},
+	}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "content", URL: "http://a.com"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+	opts := pipeline.DefaultOptions()
+	opts.MaxRounds = 5
+	opts.TokenBudget = 10
+	p.SetOptions(opts)
+
+	cb, statuses, mu := collectStatuses(nil)
+	result, err := p.RunWithUpdates(context.Background(), uuid.New().String(), "Test Topic", cb)
+	if err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+	if result.Rounds != 1 {
+		t.Errorf("expected budget to stop after 1 round, got %d", result.Rounds)
+	}
+	if got := strings.Join(result.SkippedStages, ","); got != "verify,summary" {
+		t.Errorf("expected verify and summary to be skipped, got %q", got)
+	}
+	if len(lm.prompts) != 3 {
+		t.Errorf("expected only queries, structuring and report LLM calls, got %d", len(lm.prompts))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if countOf(*statuses, "researching_round") != 0 {
+		t.Errorf("expected no follow-up rounds; statuses: %v", *statuses)
+	}
+}
+
+// TestPipeline_DeepResearchStopsWhenRoundFindsNothingNew verifies that a
+// follow-up round whose hits were all seen before ends deep research without
+// another structuring call, and that the known source records the new query.
+func TestPipeline_DeepResearchStopsWhenRoundFindsNothingNew(t *testing.T) {
+	lm := &mockLLM{
+		responses: []string{
+			`["query1"]`,
+			`{"topic":"T","key_findings":[{"finding":"F1","evidence_urls":["http://a.com"],"confidence":0.5}],"open_questions":["Why?"]}`,
+			`["follow-up"]`,
+			`[{"index":0,"verdict":"supported","strength":0.9,"reason":"r"}]`,
+			"Report",
+			"Summary",
+		},
+	}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "content", URL: "http://a.com"}}, errIdx: -1}
+	db := &sourceRecordingDB{}
+	p := pipeline.New(lm, ms.search, db, &mockBlob{})
+	opts := pipeline.DefaultOptions()
+	opts.MaxRounds = 3
+	p.SetOptions(opts)
+
+	result, err := p.RunWithUpdates(context.Background(), uuid.New().String(), "Test Topic", func(string, string) {})
+	if err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+	if result.Rounds != 1 {
+		t.Errorf("expected 1 completed round, got %d", result.Rounds)
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.sources) != 1 || len(db.sources[0].Queries) != 2 {
+		t.Errorf("expected one source found by both queries, got %+v", db.sources)
+	}
+}
+
+// perQuerySearch returns a distinct hit for every query.
+func perQuerySearch(_ context.Context, query string, _ pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
+	return []pipeline.SearchResult{{Content: "content for " + query, URL: "http://a.com/" + query}}, nil
+}
+
+func TestMergeStructuredResearch(t *testing.T) {
+	prev := event.StructuredResearch{
+		KeyFindings: []event.StructuredFinding{
+			{Finding: "Go is fast", EvidenceURLs: []string{"http://a.com"}, Confidence: 0.4},
+		},
+		Challenges:    []string{"C1"},
+		OpenQuestions: []string{"Old?"},
+		Sources:       []event.SearchSource{{URL: "http://a.com"}},
+	}
+	next := event.StructuredResearch{
+		KeyFindings: []event.StructuredFinding{
+			{Finding: "go is  FAST", EvidenceURLs: []string{"http://b.com"}, Confidence: 0.8},
+			{Finding: "New finding", Confidence: 0.6},
+		},
+		Challenges:    []string{"C1", "C2"},
+		OpenQuestions: []string{"New?"},
+		Sources:       []event.SearchSource{{URL: "http://b.com"}},
+	}
+
+	merged := pipeline.MergeStructuredResearch(prev, next)
+
+	if len(merged.KeyFindings) != 2 {
+		t.Fatalf("expected 2 findings after merge, got %d", len(merged.KeyFindings))
+	}
+	f := merged.KeyFindings[0]
+	if f.Confidence != 0.8 || len(f.EvidenceURLs) != 2 {
+		t.Errorf("expected duplicate finding merged with max confidence and both URLs, got %+v", f)
+	}
+	if len(prev.KeyFindings[0].EvidenceURLs) != 1 {
+		t.Errorf("merge must not modify the previous round's findings")
+	}
+	if len(merged.Challenges) != 2 {
+		t.Errorf("expected challenges deduplicated to 2, got %v", merged.Challenges)
+	}
+	if len(merged.OpenQuestions) != 1 || merged.OpenQuestions[0] != "New?" {
+		t.Errorf("expected open questions from the newer round, got %v", merged.OpenQuestions)
+	}
+	if len(merged.Sources) != 2 {
+		t.Errorf("expected sources from both rounds, got %d", len(merged.Sources))
+	}
+}
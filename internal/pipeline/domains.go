@@ -0,0 +1,39 @@
+package pipeline
+
+import (
+	"net/url"
+	"strings"
+)
+
+// domainAllowed reports whether rawURL passes the include and exclude domain
+// filters. A domain matches itself and any of its subdomains. Providers that
+// ignore domain restrictions are filtered here as well.
+func domainAllowed(rawURL string, include, exclude []string) bool {
+	if len(include) == 0 && len(exclude) == 0 {
+		return true
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Hostname() == "" {
+		return len(include) == 0
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range exclude {
+		if hostInDomain(host, d) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, d := range include {
+		if hostInDomain(host, d) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostInDomain(host, domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	return domain != "" && (host == domain || strings.HasSuffix(host, "."+domain))
+}
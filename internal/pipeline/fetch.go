@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/user/research-assistant/internal/event"
+)
+
+// Page is the content of a downloaded source.
+type Page struct {
+	Text string
+	// Published is the publication date stated by the page, or the zero
+	// time.
+	Published time.Time
+}
+
+// FetchFunc downloads a source URL and returns its readable text.
+type FetchFunc func(ctx context.Context, url string) (Page, error)
+
+const (
+	// fetchConcurrency caps the number of pages downloaded at once.
+	fetchConcurrency = 4
+	// maxContentChars caps the page text kept per source.
+	maxContentChars = 20000
+	// promptContentChars caps the page text per source in the structuring prompt.
+	promptContentChars = 3000
+)
+
+// SetFetcher enables full-page fetching. Without a fetcher the fetch stage
+// is a no-op and structuring works from search snippets alone.
+func (p *Pipeline) SetFetcher(fetch FetchFunc) {
+	p.fetch = fetch
+}
+
+// fetchPages downloads the pages behind the first-round sources. It is
+// skipped when the run is already close to its deadline.
+func (p *Pipeline) fetchPages(ctx context.Context, st *State) error {
+	if p.fetch != nil && st.nearDeadline() {
+		log.Printf("[PIPELINE] %s deadline reached, skipping page fetching", st.SessionID)
+		st.skip(StageFetch)
+		return nil
+	}
+	st.Sources = p.fetchSources(ctx, st, st.Sources)
+	return nil
+}
+
+// fetchSources stores the readable text of each source URL in Content, and
+// the page's publication date in Published when the search provider gave
+// none, rescoring the source's credibility by it. Failed downloads are logged
+// and leave Content empty; the snippet still applies. Downloads still running
+// when the time reserved for the report begins are abandoned. It returns
+// sources, compacted in place, without the pages whose publication date falls
+// outside the freshness window.
+func (p *Pipeline) fetchSources(ctx context.Context, st *State, sources []event.SearchSource) []event.SearchSource {
+	if p.fetch == nil || len(sources) == 0 {
+		return sources
+	}
+	if spare, ok := st.spareTime(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spare)
+		defer cancel()
+	}
+	st.Update("fetching", fmt.Sprintf("%d sources", len(sources)))
+
+	sem := make(chan struct{}, fetchConcurrency)
+	var wg sync.WaitGroup
+	for i := range sources {
+		wg.Add(1)
+		go func(s *event.SearchSource) {
+			defer wg.Done()
+			sem <- struct{}{}
+			page, err := p.fetch(ctx, s.URL)
+			<-sem
+			if err != nil {
+				log.Printf("[PIPELINE] fetch failed for %s: %v", s.URL, err)
+				return
+			}
+			s.Content = truncate(strings.TrimSpace(page.Text), maxContentChars)
+			if s.Published.IsZero() && !page.Published.IsZero() {
+				s.Published = page.Published
+				s.Credibility = p.credibility.Score(*s)
+			}
+		}(&sources[i])
+	}
+	wg.Wait()
+
+	cutoff := p.freshnessCutoff(st)
+	if cutoff.IsZero() {
+		return sources
+	}
+	fresh := slices.DeleteFunc(sources, func(s event.SearchSource) bool { return stale(s.Published, cutoff) })
+	if dropped := len(sources) - len(fresh); dropped > 0 {
+		log.Printf("[PIPELINE] %s dropped %d fetched pages published before %s", st.SessionID, dropped, cutoff.Format(time.DateOnly))
+	}
+	return fresh
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,90 @@
+package pipeline_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/user/research-assistant/internal/fetch"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// TestPipeline_FetchStageAddsPageContent verifies that fetched page text is
+// stored on the source and reaches the structuring prompt next to the snippet.
+func TestPipeline_FetchStageAddsPageContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.Error(w, "gone", http.StatusGone)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><nav>Menu</nav><main><p>The full article explains how the scheduler preempts goroutines.</p></main></body></html>`))
+	}))
+	defer srv.Close()
+
+	lm := &mockLLM{
+		responses: []string{
+			`["query1"]`,
+			`{"topic":"T","key_findings":[],"open_questions":[],"sources":[]}`,
+			"Report",
+			"Summary",
+		},
+	}
+	ms := &mockSearcher{
+		results: []pipeline.SearchResult{
+			{Content: "short snippet", URL: srv.URL + "/article"},
+			{Content: "other snippet", URL: srv.URL + "/broken"},
+		},
+		errIdx: -1,
+	}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+	p.SetFetcher(func(ctx context.Context, url string) (pipeline.Page, error) {
+		page, err := fetch.Fetch(ctx, url, fetch.Options{})
+		if err != nil {
+			return pipeline.Page{}, err
+		}
+		return pipeline.Page{Text: page.Text, Published: page.Published}, nil
+	})
+
+	cb, statuses, mu := collectStatuses(nil)
+	if _, err := p.RunWithUpdates(context.Background(), uuid.New().String(), "Test Topic", cb); err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+
+	prompt := lm.prompts[1]
+	if !strings.Contains(prompt, "Snippet: short snippet") || !strings.Contains(prompt, "Content: The full article explains how the scheduler preempts goroutines.") {
+		t.Errorf("structuring prompt missing snippet or page content:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "Menu") {
+		t.Errorf("boilerplate leaked into page content:\n%s", prompt)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if countOf(*statuses, "fetching") != 1 {
+		t.Errorf("expected one fetching update, got %v", *statuses)
+	}
+}
+
+// TestPipeline_FetchStageIsNoOpWithoutFetcher verifies that pipelines
+// without a fetcher skip fetching entirely.
+func TestPipeline_FetchStageIsNoOpWithoutFetcher(t *testing.T) {
+	lm := &mockLLM{responses: []string{`["q"]`, `{"topic":"T","key_findings":[],"open_questions":[],"sources":[]}`, "Report", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "c", URL: "http://a.com"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+
+	cb, statuses, mu := collectStatuses(nil)
+	if _, err := p.RunWithUpdates(context.Background(), uuid.New().String(), "Test Topic", cb); err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if countOf(*statuses, "fetching") != 0 {
+		t.Errorf("expected no fetching updates, got %v", *statuses)
+	}
+	if strings.Contains(lm.prompts[1], "Content:") {
+		t.Errorf("unexpected page content in prompt:\n%s", lm.prompts[1])
+	}
+}
@@ -0,0 +1,81 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/user/research-assistant/internal/credibility"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// TestPipeline_FreshnessAndPublishDates verifies that the freshness window
+// reaches the search provider and drops stale results, whether the search
+// provider or the fetched page dates them, that publish dates come from the
+// search results or the fetched pages, and that they are shown to the LLM and
+// in the references.
+func TestPipeline_FreshnessAndPublishDates(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	lm := &mockLLM{
+		responses: []string{
+			`["query1"]`,
+			`{"topic":"T","key_findings":[{"finding":"F","evidence_urls":["https://a.example/new","https://c.example/undated"],"confidence":0.7}]}`,
+			"F holds [1]. So says [2].",
+			"Summary",
+		},
+	}
+	ms := &mockSearcher{
+		results: []pipeline.SearchResult{
+			{Content: "fresh", URL: "https://a.example/new", Title: "A", Published: time.Date(2026, 5, 20, 0, 0, 0, 0, time.UTC)},
+			{Content: "stale", URL: "https://b.example/old", Title: "B", Published: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{Content: "undated", URL: "https://c.example/undated", Title: "C"},
+			{Content: "stale page", URL: "https://d.example/undated", Title: "D"},
+		},
+		errIdx: -1,
+	}
+	db := &sourceRecordingDB{}
+	blobs := &recordingBlob{}
+	p := pipeline.New(lm, ms.search, db, blobs)
+	if err := p.Stages().Remove(pipeline.StageVerify); err != nil {
+		t.Fatal(err)
+	}
+	policy := credibility.DefaultPolicy()
+	policy.Now = func() time.Time { return now }
+	p.SetCredibility(policy)
+	p.SetClock(func() time.Time { return now })
+	p.SetFetcher(func(_ context.Context, url string) (pipeline.Page, error) {
+		if url == "https://d.example/undated" {
+			return pipeline.Page{Text: "old page", Published: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, nil
+		}
+		return pipeline.Page{Text: "page text", Published: time.Date(2026, 5, 25, 0, 0, 0, 0, time.UTC)}, nil
+	})
+
+	opts := pipeline.Options{NumQueries: 1, Freshness: 30 * 24 * time.Hour}
+	if _, err := p.RunWithOptions(context.Background(), "s1", "Topic", opts, nil); err != nil {
+		t.Fatalf("RunWithOptions: %v", err)
+	}
+
+	if len(ms.opts) != 1 || ms.opts[0].MaxAge != opts.Freshness {
+		t.Errorf("expected the freshness window passed to the provider, got %+v", ms.opts)
+	}
+	if len(db.sources) != 2 || db.sources[0].URL != "https://a.example/new" || db.sources[1].URL != "https://c.example/undated" {
+		t.Fatalf("expected the stale results dropped, got %+v", db.sources)
+	}
+	if got := db.sources[1]; got.Published.Format(time.DateOnly) != "2026-05-25" || got.Credibility != 0.6 {
+		t.Errorf("expected the page's publish date and a rescored credibility, got %+v", got)
+	}
+	if prompt := lm.prompts[1]; strings.Contains(prompt, "d.example") {
+		t.Errorf("structuring prompt includes the stale page:\n%s", prompt)
+	}
+	if prompt := lm.prompts[1]; !strings.Contains(prompt, "Published: 2026-05-20") || !strings.Contains(prompt, "Published: 2026-05-25") {
+		t.Errorf("structuring prompt missing publish dates:\n%s", prompt)
+	}
+	if prompt := lm.prompts[2]; !strings.Contains(prompt, "[1] A (https://a.example/new), published 2026-05-20") {
+		t.Errorf("report prompt missing publish dates:\n%s", prompt)
+	}
+	b := blobs.bundle(t)
+	if !strings.Contains(b.Report, "[1] A. https://a.example/new (published 2026-05-20, credibility 0.60)") {
+		t.Errorf("references missing publish dates:\n%s", b.Report)
+	}
+}
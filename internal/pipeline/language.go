@@ -0,0 +1,139 @@
+package pipeline
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultLanguage is the report language when neither the options nor the
+// topic determine one.
+const DefaultLanguage = "en"
+
+// stopwords holds short, frequent function words of the Latin-script
+// languages DetectLanguage recognises.
+var stopwords = map[string][]string{
+	"en": {"the", "of", "and", "is", "are", "what", "how", "why", "for", "with", "in", "on", "to", "does", "which"},
+	"es": {"el", "la", "los", "las", "de", "del", "y", "en", "que", "por", "para", "con", "es", "cómo", "qué", "una", "un"},
+	"fr": {"le", "la", "les", "des", "du", "de", "et", "en", "est", "pour", "avec", "comment", "quel", "quelle", "une", "un", "sur", "l", "d"},
+	"de": {"der", "die", "das", "und", "ist", "für", "mit", "von", "wie", "was", "ein", "eine", "im", "auf", "zu"},
+	"it": {"il", "lo", "gli", "di", "del", "della", "e", "è", "per", "con", "come", "che", "un", "una", "nel"},
+	"pt": {"o", "os", "as", "de", "do", "da", "e", "é", "para", "com", "como", "que", "um", "uma", "em", "no", "na"},
+	"nl": {"de", "het", "een", "en", "van", "is", "voor", "met", "hoe", "wat", "op", "bij"},
+}
+
+// letterHints are letters specific enough to one language to count as
+// additional evidence for it.
+var letterHints = map[rune]string{
+	'ñ': "es", '¿': "es", '¡': "es",
+	'ß': "de", 'ä': "de", 'ö': "de", 'ü': "de",
+	'ç': "fr", 'œ': "fr", 'è': "fr", 'ê': "fr",
+	'ã': "pt", 'õ': "pt",
+	'ì': "it", 'ò': "it",
+}
+
+// DetectLanguage guesses the ISO 639-1 language of a short text such as a
+// research topic. Non-Latin scripts are recognised by their characters and
+// Latin-script languages by their function words. It returns "" when the
+// text gives no clear signal, which is common for short keyword topics.
+func DetectLanguage(text string) string {
+	scripts := map[string]int{}
+	latin := 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			scripts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["zh"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	// Japanese mixes kana with Han characters; any kana decides it.
+	if scripts["ja"] > 0 {
+		return "ja"
+	}
+	best, bestCount := "", 0
+	for lang, n := range scripts {
+		if n > bestCount {
+			best, bestCount = lang, n
+		}
+	}
+	if bestCount > latin {
+		return best
+	}
+
+	lower := strings.ToLower(text)
+	scores := map[string]int{}
+	words := strings.FieldsFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	for lang, list := range stopwords {
+		for _, w := range words {
+			for _, sw := range list {
+				if w == sw {
+					scores[lang]++
+				}
+			}
+		}
+	}
+	for _, r := range lower {
+		if lang, ok := letterHints[r]; ok {
+			scores[lang] += 2
+		}
+	}
+	best, bestScore, tie := "", 0, false
+	for lang, n := range scores {
+		switch {
+		case n > bestScore:
+			best, bestScore, tie = lang, n, false
+		case n == bestScore:
+			tie = true
+		}
+	}
+	if bestScore == 0 || tie {
+		return ""
+	}
+	return best
+}
+
+// searchLanguages returns the languages to search in: the requested search
+// languages, else the requested language, else the detected topic language.
+// An empty result searches without a language restriction.
+func (o Options) searchLanguages(detected string) []string {
+	switch {
+	case len(o.SearchLanguages) > 0:
+		return o.SearchLanguages
+	case o.Language != "":
+		return []string{o.Language}
+	case detected != "":
+		return []string{detected}
+	}
+	return nil
+}
+
+// outputLanguage returns the language of the report and summary: the
+// requested output language, else the requested language, else the detected
+// topic language, else DefaultLanguage.
+func (o Options) outputLanguage(detected string) string {
+	switch {
+	case o.OutputLanguage != "":
+		return o.OutputLanguage
+	case o.Language != "":
+		return o.Language
+	case detected != "":
+		return detected
+	}
+	return DefaultLanguage
+}
@@ -0,0 +1,103 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	cases := map[string]string{
+		"What is the future of Go concurrency":     "en",
+		"El futuro de la concurrencia en Go":       "es",
+		"L'avenir de la programmation concurrente": "fr",
+		"Die Zukunft der Nebenläufigkeit in Go":    "de",
+		"O futuro da programação concorrente":      "pt",
+		"Будущее конкурентности в Go":              "ru",
+		"Go言語の並行処理の未来":                             "ja",
+		"Go 语言并发的未来":                               "zh",
+		"Go concurrency":                           "",
+	}
+	for text, want := range cases {
+		if got := pipeline.DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+// languageDB records the language stored on each session.
+type languageDB struct {
+	mockDB
+	mu        sync.Mutex
+	languages map[string]string
+}
+
+func (m *languageDB) SetSessionLanguage(id, language string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.languages == nil {
+		m.languages = map[string]string{}
+	}
+	m.languages[id] = language
+	return nil
+}
+
+// TestPipeline_MultilingualSearch verifies that queries are generated and
+// searched once per search language and that the report and summary are
+// written in the output language, which is stored on the session.
+func TestPipeline_MultilingualSearch(t *testing.T) {
+	lm := &mockLLM{responses: []string{`["go concurrency"]`, `["go nebenläufigkeit"]`, `{"topic":"T"}`, "Rapport", "Résumé"}}
+	var mu sync.Mutex
+	searched := map[string]string{}
+	search := func(_ context.Context, q string, opts pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		searched[q] = opts.Language
+		return []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com/" + opts.Language}}, nil
+	}
+	db := &languageDB{}
+	p := pipeline.New(lm, search, db, &mockBlob{})
+
+	opts := pipeline.Options{SearchLanguages: []string{"en", "de"}, OutputLanguage: "fr"}
+	res, err := p.RunWithOptions(context.Background(), "s1", "Go concurrency", opts, func(string, string) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(lm.prompts[0], `code "en"`) || !strings.Contains(lm.prompts[1], `code "de"`) {
+		t.Errorf("expected one query prompt per search language, got:\n%s\n---\n%s", lm.prompts[0], lm.prompts[1])
+	}
+	if searched["go concurrency"] != "en" || searched["go nebenläufigkeit"] != "de" {
+		t.Errorf("expected each query searched in its language, got %v", searched)
+	}
+	for _, i := range []int{3, 4} {
+		if !strings.Contains(lm.prompts[i], `code "fr"`) {
+			t.Errorf("expected prompt %d to request French output:\n%s", i, lm.prompts[i])
+		}
+	}
+	if res.Language != "fr" || db.languages["s1"] != "fr" {
+		t.Errorf("expected session language fr, got result %q, stored %q", res.Language, db.languages["s1"])
+	}
+}
+
+// TestPipeline_DetectedLanguage verifies that without language options the
+// topic's detected language is used for searching and writing.
+func TestPipeline_DetectedLanguage(t *testing.T) {
+	lm := &mockLLM{responses: []string{`["concurrencia go"]`, `{"topic":"T"}`, "Informe", "Resumen"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+
+	res, err := p.RunWithUpdates(context.Background(), "s1", "El futuro de la concurrencia en Go", func(string, string) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ms.opts) != 1 || ms.opts[0].Language != "es" {
+		t.Errorf("expected search in es, got %+v", ms.opts)
+	}
+	if res.Language != "es" {
+		t.Errorf("expected report language es, got %q", res.Language)
+	}
+}
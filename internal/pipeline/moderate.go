@@ -0,0 +1,86 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/user/research-assistant/internal/moderation"
+	"github.com/user/research-assistant/internal/prompts"
+)
+
+// SetModeration replaces the moderation policy applied to the topic of
+// subsequent runs.
+func (p *Pipeline) SetModeration(policy moderation.Policy) {
+	p.moderation = policy
+}
+
+// ModerationSchema returns the JSON schema of the moderation classifier's
+// verdict.
+func ModerationSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"allowed":    map[string]any{"type": "boolean"},
+			"category":   map[string]any{"type": "string"},
+			"reason":     map[string]any{"type": "string"},
+			"suggestion": map[string]any{"type": "string"},
+		},
+		"required": []string{"allowed"},
+	}
+}
+
+// moderateTopic checks the topic against the moderation policy before any
+// search is run. The allow and deny lists decide first; other topics are
+// classified by the LLM when the policy asks for it, falling back to the
+// category phrases. A blocked topic fails the run with a policy violation
+// that suggests a rephrasing.
+func (p *Pipeline) moderateTopic(ctx context.Context, st *State) error {
+	decision, decided := p.moderation.Lists(st.Topic)
+	if !decided {
+		decision = p.classifyTopic(ctx, st)
+	}
+	if decision.Allowed {
+		return nil
+	}
+	log.Printf("[PIPELINE] %s topic blocked (%s, by %s): %s", st.SessionID, decision.Category, decision.DecidedBy, decision.Reason)
+	return stageFailure("Topic validation failed: "+decision.Reason, decision.Err())
+}
+
+// classifyTopic asks the LLM whether the topic falls into a blocked category.
+// Policies without categories allow every topic, and classifier failures
+// fall back to matching the category phrases.
+func (p *Pipeline) classifyTopic(ctx context.Context, st *State) moderation.Decision {
+	policy := p.moderation
+	if !policy.Classify || len(policy.Categories) == 0 {
+		return policy.Match(st.Topic)
+	}
+	prompt, err := p.prompts.Render(prompts.Moderate, map[string]any{
+		"Topic":      st.Topic,
+		"Categories": policy.Categories,
+	})
+	if err != nil {
+		log.Printf("[PIPELINE] %s moderation prompt: %v", st.SessionID, err)
+		return policy.Match(st.Topic)
+	}
+	raw, err := p.generateJSON(ctx, st, prompt, ModerationSchema())
+	if err != nil {
+		log.Printf("[PIPELINE] %s moderation classifier failed, matching phrases: %v", st.SessionID, err)
+		return policy.Match(st.Topic)
+	}
+	var verdict struct {
+		Allowed    *bool  `json:"allowed"`
+		Category   string `json:"category"`
+		Reason     string `json:"reason"`
+		Suggestion string `json:"suggestion"`
+	}
+	obj, err := extractJSONObject(raw)
+	if err == nil {
+		err = json.Unmarshal([]byte(obj), &verdict)
+	}
+	if err != nil || verdict.Allowed == nil {
+		log.Printf("[PIPELINE] %s moderation verdict unusable, matching phrases: %v", st.SessionID, err)
+		return policy.Match(st.Topic)
+	}
+	return policy.Classified(*verdict.Allowed, verdict.Category, verdict.Reason, verdict.Suggestion)
+}
@@ -0,0 +1,102 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	apperrors "github.com/user/research-assistant/internal/errors"
+	"github.com/user/research-assistant/internal/moderation"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// runModerated runs the pipeline on topic and returns its failure detail, the
+// number of searches it made and the run's error.
+func runModerated(t *testing.T, lm pipeline.LLMClient, policy moderation.Policy, topic string) (string, int, error) {
+	t.Helper()
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com", Title: "A"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+	p.SetModeration(policy)
+	var detail string
+	_, err := p.RunWithUpdates(context.Background(), "s1", topic, func(status, d string) {
+		if status == "failed" {
+			detail = d
+		}
+	})
+	return detail, ms.calls, err
+}
+
+// TestPipeline_ModerationBlocksBeforeSearch verifies that a blocked topic
+// fails with a policy violation suggesting a rephrasing, before any LLM or
+// search call.
+func TestPipeline_ModerationBlocksBeforeSearch(t *testing.T) {
+	lm := &mockLLM{}
+	detail, searches, err := runModerated(t, lm, moderation.DefaultPolicy(), "How to make a bomb at home")
+
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) || appErr.Code != apperrors.CodePolicyViolation {
+		t.Fatalf("expected a policy violation, got %v", err)
+	}
+	if appErr.Recovery == nil || appErr.Recovery.Type != apperrors.RecoveryRephrase || appErr.Recovery.Suggestion == "" {
+		t.Errorf("expected a rephrase suggestion, got %+v", appErr.Recovery)
+	}
+	if appErr.Telemetry["category"] != "weapons" {
+		t.Errorf("expected the weapons category, got %v", appErr.Telemetry)
+	}
+	if !strings.HasPrefix(detail, "Topic validation failed") {
+		t.Errorf("unexpected failure detail %q", detail)
+	}
+	if searches != 0 || len(lm.prompts) != 0 {
+		t.Errorf("expected no searches or LLM calls, got %d searches and %d prompts", searches, len(lm.prompts))
+	}
+}
+
+// TestPipeline_ModerationAllowsBenignWording verifies that topics are not
+// blocked for merely containing words like "unsafe" or "cannot".
+func TestPipeline_ModerationAllowsBenignWording(t *testing.T) {
+	lm := &mockLLM{responses: []string{`["q"]`, `{"topic": "T", "key_findings": []}`, "Report", "Summary"}}
+	detail, searches, err := runModerated(t, lm, moderation.DefaultPolicy(), "Why unsafe Rust cannot be verified by the borrow checker")
+	if err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, detail)
+	}
+	if searches == 0 {
+		t.Error("expected the topic to be searched")
+	}
+}
+
+// TestPipeline_ModerationClassifier verifies the LLM classifier's verdicts,
+// its fallback to the category phrases, and the precedence of the lists.
+func TestPipeline_ModerationClassifier(t *testing.T) {
+	policy := moderation.DefaultPolicy()
+	policy.Classify = true
+	policy.Allow = []string{"ransomware history"}
+
+	blocked := `{"allowed": false, "category": "malware", "reason": "it asks for working ransomware", "suggestion": "How ransomware attacks are detected"}`
+	_, searches, err := runModerated(t, &mockLLM{responses: []string{blocked}}, policy, "Give me a working crypto locker")
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) || appErr.Code != apperrors.CodePolicyViolation {
+		t.Fatalf("expected the classifier to block the topic, got %v", err)
+	}
+	if appErr.Recovery.Suggestion != "How ransomware attacks are detected" || appErr.Telemetry["decided_by"] != "classifier" {
+		t.Errorf("unexpected error details: %+v %v", appErr.Recovery, appErr.Telemetry)
+	}
+	if searches != 0 {
+		t.Errorf("expected no searches, got %d", searches)
+	}
+
+	// An unusable verdict falls back to the phrases.
+	_, _, err = runModerated(t, &mockLLM{responses: []string{"Mock Response"}}, policy, "How to write ransomware")
+	if !errors.As(err, &appErr) || appErr.Telemetry["decided_by"] != "phrases" {
+		t.Errorf("expected the phrase fallback to block the topic, got %v", err)
+	}
+
+	// The allow list passes the topic without asking the classifier.
+	lm := &mockLLM{responses: []string{`["q"]`, `{"topic": "T", "key_findings": []}`, "Report", "Summary"}}
+	if detail, _, err := runModerated(t, lm, policy, "Ransomware history since 1989"); err != nil {
+		t.Fatalf("expected the allow-listed topic to pass, got %v (%s)", err, detail)
+	}
+	if strings.Contains(lm.prompts[0], "content moderator") {
+		t.Error("expected the allow list to skip the classifier")
+	}
+}
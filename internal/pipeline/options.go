@@ -0,0 +1,276 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/user/research-assistant/internal/render"
+)
+
+// Report formats accepted by Options.ReportFormat.
+const (
+	ReportFormatDetailed = "detailed"
+	ReportFormatBrief    = "brief"
+	ReportFormatBullets  = "bullets"
+)
+
+// OptionsExtensionURI identifies the research options DataPart in agent cards.
+const OptionsExtensionURI = "https://github.com/user/research-assistant/extensions/research-options/v1"
+
+// Options tunes a single pipeline run.
+type Options struct {
+	// NumQueries is the number of search queries generated for the topic.
+	NumQueries int
+	// MaxSources is the number of search results requested per query.
+	MaxSources int
+	// MaxRounds caps the number of research rounds. Values <= 1 run the
+	// classic single round; higher values enable deep research, where open
+	// questions and low-confidence findings drive follow-up query rounds.
+	MaxRounds int
+	// ConfidenceTarget ends deep research early once every key finding has at
+	// least this confidence and no open questions remain.
+	ConfidenceTarget float64
+	// TokenBudget stops starting new rounds once the tokens spent on the
+	// session reach it. Zero means unlimited.
+	TokenBudget int
+	// Deadline bounds the wall-clock time of the run. Optional work is cut
+	// as it approaches so that the report is still delivered; the report
+	// itself is never cut. Zero means no deadline.
+	Deadline time.Duration
+	// Language is an ISO 639-1 code used for searching and for the report.
+	// Empty means the language detected from the topic.
+	Language string
+	// SearchLanguages runs every query once per listed ISO 639-1 code,
+	// overriding Language for searching.
+	SearchLanguages []string
+	// OutputLanguage is the ISO 639-1 code of the report and summary,
+	// overriding Language for writing.
+	OutputLanguage string
+	// IncludeDomains restricts sources to these domains (and subdomains).
+	IncludeDomains []string
+	// ExcludeDomains drops sources from these domains (and subdomains).
+	ExcludeDomains []string
+	// Freshness restricts sources to pages published within this window.
+	// Search providers are asked to restrict their results, and results
+	// with a known publication date outside the window are dropped. Zero
+	// means no restriction.
+	Freshness time.Duration
+	// ReportFormat selects the report style; see the ReportFormat constants.
+	ReportFormat string
+	// IncludeUnverified lets the report use findings that verification found
+	// unsupported or contradicted.
+	IncludeUnverified bool
+	// Renderers names the formats the report is rendered in besides
+	// Markdown, e.g. "html" or "epub"; see the render package.
+	Renderers []string
+	// Subjects switches the run to comparison mode when it lists at least
+	// two subjects, e.g. competing frameworks or vendors. Each subject gets
+	// its own queries and the report includes a subject × criterion matrix.
+	Subjects []string
+	// Criteria are the aspects the subjects are compared on. Empty lets the
+	// LLM choose them.
+	Criteria []string
+}
+
+// DefaultOptions returns the options used when a run does not override them.
+func DefaultOptions() Options {
+	return Options{
+		NumQueries:       3,
+		MaxSources:       3,
+		MaxRounds:        1,
+		ConfidenceTarget: 0.8,
+		ReportFormat:     ReportFormatDetailed,
+	}
+}
+
+// Merge returns o with every field that is set in override replacing its
+// counterpart. Zero values in override leave o unchanged.
+func (o Options) Merge(override Options) Options {
+	if override.NumQueries > 0 {
+		o.NumQueries = override.NumQueries
+	}
+	if override.MaxSources > 0 {
+		o.MaxSources = override.MaxSources
+	}
+	if override.MaxRounds > 0 {
+		o.MaxRounds = override.MaxRounds
+	}
+	if override.ConfidenceTarget > 0 {
+		o.ConfidenceTarget = override.ConfidenceTarget
+	}
+	if override.TokenBudget > 0 {
+		o.TokenBudget = override.TokenBudget
+	}
+	if override.Deadline > 0 {
+		o.Deadline = override.Deadline
+	}
+	if override.Language != "" {
+		o.Language = override.Language
+	}
+	if len(override.SearchLanguages) > 0 {
+		o.SearchLanguages = override.SearchLanguages
+	}
+	if override.OutputLanguage != "" {
+		o.OutputLanguage = override.OutputLanguage
+	}
+	if len(override.IncludeDomains) > 0 {
+		o.IncludeDomains = override.IncludeDomains
+	}
+	if len(override.ExcludeDomains) > 0 {
+		o.ExcludeDomains = override.ExcludeDomains
+	}
+	if override.Freshness > 0 {
+		o.Freshness = override.Freshness
+	}
+	if override.ReportFormat != "" {
+		o.ReportFormat = override.ReportFormat
+	}
+	if override.IncludeUnverified {
+		o.IncludeUnverified = true
+	}
+	if len(override.Renderers) > 0 {
+		o.Renderers = override.Renderers
+	}
+	if len(override.Subjects) > 0 {
+		o.Subjects = override.Subjects
+	}
+	if len(override.Criteria) > 0 {
+		o.Criteria = override.Criteria
+	}
+	return o
+}
+
+// searchOptions derives the provider options for a search call in lang.
+func (o Options) searchOptions(lang string) SearchOptions {
+	return SearchOptions{
+		Num:            o.MaxSources,
+		Language:       lang,
+		IncludeDomains: o.IncludeDomains,
+		ExcludeDomains: o.ExcludeDomains,
+		MaxAge:         o.Freshness,
+	}
+}
+
+// OptionsSchema returns the JSON schema of the research options DataPart
+// accepted by the Researcher and Concierge agents.
+func OptionsSchema() map[string]any {
+	domains := map[string]any{
+		"type":     "array",
+		"maxItems": 10,
+		"items":    map[string]any{"type": "string", "pattern": `^[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`},
+	}
+	language := `^[a-z]{2}$`
+	phrase := map[string]any{"type": "string", "pattern": `^\S.{0,79}$`}
+	return map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"num_queries":        map[string]any{"type": "integer", "minimum": 1, "maximum": 10, "description": "Search queries generated per round (default 3)."},
+			"max_sources":        map[string]any{"type": "integer", "minimum": 1, "maximum": 10, "description": "Search results requested per query (default 3)."},
+			"depth":              map[string]any{"type": "integer", "minimum": 1, "maximum": 5, "description": "Maximum research rounds; values above 1 enable deep research."},
+			"deadline_seconds":   map[string]any{"type": "integer", "minimum": 10, "maximum": 3600, "description": "Time limit for the session; optional work is skipped to deliver the report within it."},
+			"language":           map[string]any{"type": "string", "pattern": language, "description": "ISO 639-1 language for searching and writing the report; detected from the topic when unset."},
+			"search_languages":   map[string]any{"type": "array", "maxItems": 5, "items": map[string]any{"type": "string", "pattern": language}, "description": "ISO 639-1 languages to run every search query in."},
+			"output_language":    map[string]any{"type": "string", "pattern": language, "description": "ISO 639-1 language of the report and summary."},
+			"include_domains":    domains,
+			"exclude_domains":    domains,
+			"freshness_days":     map[string]any{"type": "integer", "minimum": 1, "maximum": 3650, "description": "Only use pages published within this many days."},
+			"report_format":      map[string]any{"type": "string", "enum": []string{ReportFormatDetailed, ReportFormatBrief, ReportFormatBullets}},
+			"include_unverified": map[string]any{"type": "boolean", "description": "Let the report use findings that verification did not support."},
+			"subjects":           map[string]any{"type": "array", "minItems": 2, "maxItems": maxSubjects, "items": phrase, "description": "Subjects to compare; enables comparison mode."},
+			"criteria":           map[string]any{"type": "array", "maxItems": maxCriteria, "items": phrase, "description": "Criteria to compare the subjects on; chosen automatically when unset."},
+			"renderers":          map[string]any{"type": "array", "maxItems": 5, "items": map[string]any{"type": "string", "enum": render.Names()}, "description": "Formats to render the report in besides Markdown."},
+		},
+	}
+}
+
+// ValidateOptions checks a research options DataPart against OptionsSchema.
+func ValidateOptions(data map[string]any) error {
+	if problems := ValidateSchema(OptionsSchema(), data); len(problems) > 0 {
+		return fmt.Errorf("invalid research options: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ParseOptions validates a research options DataPart and converts it into an
+// Options override suitable for Options.Merge. A nil or empty map yields the
+// zero Options.
+func ParseOptions(data map[string]any) (Options, error) {
+	var opts Options
+	if len(data) == 0 {
+		return opts, nil
+	}
+	if err := ValidateOptions(data); err != nil {
+		return opts, err
+	}
+	if n, ok := schemaNumber(data["num_queries"]); ok {
+		opts.NumQueries = int(n)
+	}
+	if n, ok := schemaNumber(data["max_sources"]); ok {
+		opts.MaxSources = int(n)
+	}
+	if n, ok := schemaNumber(data["depth"]); ok {
+		opts.MaxRounds = int(n)
+	}
+	if n, ok := schemaNumber(data["deadline_seconds"]); ok {
+		opts.Deadline = time.Duration(n) * time.Second
+	}
+	if s, ok := data["language"].(string); ok {
+		opts.Language = s
+	}
+	opts.SearchLanguages = stringList(data["search_languages"])
+	if s, ok := data["output_language"].(string); ok {
+		opts.OutputLanguage = s
+	}
+	opts.IncludeDomains = stringList(data["include_domains"])
+	opts.ExcludeDomains = stringList(data["exclude_domains"])
+	if n, ok := schemaNumber(data["freshness_days"]); ok {
+		opts.Freshness = time.Duration(n) * 24 * time.Hour
+	}
+	if s, ok := data["report_format"].(string); ok {
+		opts.ReportFormat = s
+	}
+	if b, ok := data["include_unverified"].(bool); ok {
+		opts.IncludeUnverified = b
+	}
+	opts.Renderers = stringList(data["renderers"])
+	opts.Subjects = textList(data["subjects"])
+	opts.Criteria = textList(data["criteria"])
+	return opts, nil
+}
+
+func stringList(v any) []string {
+	var out []string
+	switch list := v.(type) {
+	case []string:
+		for _, s := range list {
+			out = append(out, strings.ToLower(s))
+		}
+	case []any:
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, strings.ToLower(s))
+			}
+		}
+	}
+	return out
+}
+
+// textList is stringList for free text, which keeps its case.
+func textList(v any) []string {
+	var out []string
+	switch list := v.(type) {
+	case []string:
+		for _, s := range list {
+			out = append(out, strings.TrimSpace(s))
+		}
+	case []any:
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,111 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+func TestParseOptions_Valid(t *testing.T) {
+	opts, err := pipeline.ParseOptions(map[string]any{
+		"num_queries":     float64(4),
+		"max_sources":     float64(8),
+		"depth":           float64(3),
+		"language":        "fr",
+		"include_domains": []any{"Go.dev", "golang.org"},
+		"report_format":   "bullets",
+		"freshness_days":  float64(30),
+	})
+	if err != nil {
+		t.Fatalf("ParseOptions: %v", err)
+	}
+	if opts.NumQueries != 4 || opts.MaxSources != 8 || opts.MaxRounds != 3 || opts.Language != "fr" || opts.ReportFormat != "bullets" {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	if opts.Freshness != 30*24*time.Hour {
+		t.Errorf("expected a 30 day freshness window, got %v", opts.Freshness)
+	}
+	if len(opts.IncludeDomains) != 2 || opts.IncludeDomains[0] != "go.dev" {
+		t.Errorf("expected lower-cased include domains, got %v", opts.IncludeDomains)
+	}
+
+	merged := pipeline.DefaultOptions().Merge(opts)
+	if merged.ConfidenceTarget != pipeline.DefaultOptions().ConfidenceTarget || merged.NumQueries != 4 {
+		t.Errorf("merge must keep defaults for unset fields: %+v", merged)
+	}
+}
+
+func TestParseOptions_Invalid(t *testing.T) {
+	cases := map[string]map[string]any{
+		"unknown field":  {"colour": "blue"},
+		"out of range":   {"num_queries": float64(11)},
+		"fractional":     {"depth": 1.5},
+		"wrong type":     {"max_sources": "many"},
+		"bad language":   {"language": "english"},
+		"bad domain":     {"exclude_domains": []any{"not a domain"}},
+		"too many items": {"include_domains": []any{"a.io", "b.io", "c.io", "d.io", "e.io", "f.io", "g.io", "h.io", "i.io", "j.io", "k.io"}},
+		"bad format":     {"report_format": "poem"},
+		"short deadline": {"deadline_seconds": float64(5)},
+		"no freshness":   {"freshness_days": float64(0)},
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := pipeline.ParseOptions(data); err == nil {
+				t.Errorf("expected %v to be rejected", data)
+			}
+		})
+	}
+}
+
+// TestPipeline_RunWithOptions verifies that per-request options reach the
+// query prompt, the search provider, the domain filter and the report prompt.
+func TestPipeline_RunWithOptions(t *testing.T) {
+	lm := &mockLLM{
+		responses: []string{
+			`["q1","q2","q3"]`,
+			`{"topic":"T","key_findings":[],"open_questions":[],"sources":[]}`,
+			"Report",
+			"Summary",
+		},
+	}
+	ms := &mockSearcher{
+		results: []pipeline.SearchResult{
+			{Content: "kept", URL: "https://blog.go.dev/post"},
+			{Content: "dropped", URL: "https://example.com/post"},
+		},
+		errIdx: -1,
+	}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+
+	override := pipeline.Options{
+		NumQueries:     2,
+		MaxSources:     7,
+		Language:       "de",
+		IncludeDomains: []string{"go.dev"},
+		ReportFormat:   pipeline.ReportFormatBullets,
+	}
+	if _, err := p.RunWithOptions(context.Background(), uuid.New().String(), "Test Topic", override, func(string, string) {}); err != nil {
+		t.Fatalf("RunWithOptions: %v", err)
+	}
+
+	if !strings.Contains(lm.prompts[0], "generate 2 specific search queries") {
+		t.Errorf("query prompt does not use num_queries: %q", lm.prompts[0])
+	}
+	if len(ms.opts) != 2 {
+		t.Fatalf("expected queries capped at 2, got %d searches", len(ms.opts))
+	}
+	if ms.opts[0].Num != 7 || ms.opts[0].Language != "de" {
+		t.Errorf("search options not passed through: %+v", ms.opts[0])
+	}
+	if structuring := lm.prompts[1]; !strings.Contains(structuring, "blog.go.dev") || strings.Contains(structuring, "example.com") {
+		t.Errorf("domain filter not applied to search results: %q", structuring)
+	}
+	report := lm.prompts[2]
+	if !strings.Contains(report, "bullet") || !strings.Contains(report, `"de"`) {
+		t.Errorf("report prompt missing format or language: %q", report)
+	}
+}
@@ -3,13 +3,17 @@ package pipeline
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/user/research-assistant/internal/credibility"
 	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/moderation"
+	"github.com/user/research-assistant/internal/prompts"
 	"github.com/user/research-assistant/internal/storage"
 )
 
@@ -18,248 +22,322 @@ type LLMClient interface {
 	GenerateContent(ctx context.Context, prompt string) (string, error)
 }
 
+// UsageLLMClient is implemented by LLM clients that report the token usage of
+// each call. Calls through other clients are charged an estimate.
+type UsageLLMClient interface {
+	GenerateContentWithUsage(ctx context.Context, prompt string) (string, llm.Usage, error)
+}
+
+// JSONLLMClient is implemented by LLM clients that can constrain a response to
+// a JSON schema. Structured calls through other clients use a plain prompt.
+type JSONLLMClient interface {
+	GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, llm.Usage, error)
+}
+
+// StreamingLLMClient is implemented by LLM clients that can stream a response
+// as it is generated. The report is streamed to the caller through such
+// clients; others deliver it when it is complete.
+type StreamingLLMClient interface {
+	GenerateContentStream(ctx context.Context, prompt string, onChunk func(string)) (string, llm.Usage, error)
+}
+
+// Pricing converts token counts into an approximate cost.
+type Pricing struct {
+	// InputPerMTok and OutputPerMTok are USD per million prompt and
+	// completion tokens.
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// Cost returns the USD cost of the given token counts.
+func (p Pricing) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.InputPerMTok + float64(completionTokens)*p.OutputPerMTok) / 1e6
+}
+
 // SearchResult holds the output of a single web search.
 type SearchResult struct {
-	Content string
-	URL     string
+	Content  string
+	URL      string
+	Title    string
+	Provider string // name of the search backend, e.g. "google_cse"
+	Cached   bool   // served from the search cache rather than the backend
+	// Published is the publication date the backend reported for the
+	// page, or the zero time.
+	Published time.Time
+}
+
+// SearchOptions carries per-request provider settings for a search call.
+type SearchOptions struct {
+	// Num is the maximum number of results to return.
+	Num int
+	// Language is an ISO 639-1 code restricting and localizing results.
+	Language string
+	// IncludeDomains restricts results to these domains.
+	IncludeDomains []string
+	// ExcludeDomains removes results from these domains.
+	ExcludeDomains []string
+	// MaxAge restricts results to pages published this recently. Zero
+	// means no restriction.
+	MaxAge time.Duration
 }
 
 // SearchFunc performs a web search for the given query and returns results.
-type SearchFunc func(ctx context.Context, query string) ([]SearchResult, error)
+type SearchFunc func(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error)
 
 // Result holds the persistence keys produced by a completed pipeline run.
 type Result struct {
 	SessionID     string
 	ReportMDKey   string
 	ReportJSONKey string
+	// Rounds is the number of research rounds the run completed.
+	Rounds int
+	// Usage is the LLM token usage of the run per stage.
+	Usage []event.TokenUsage
+	// SkippedStages lists optional stages skipped, or cut short, to stay
+	// within the token budget or deadline.
+	SkippedStages []string
+	// StructureRepairs counts the re-prompts needed to get valid structured output.
+	StructureRepairs int
+	// Language is the ISO 639-1 code the report and summary were written in.
+	Language string
+	// Renders maps renderer names to the blob keys of the rendered reports.
+	Renders map[string]string
 }
 
 // Pipeline orchestrates the full research pipeline for a single topic.
 type Pipeline struct {
-	llm    LLMClient
-	search SearchFunc
-	db     storage.StructuredStorage
-	blobs  storage.BlobStorage
+	llm     LLMClient
+	search  SearchFunc
+	fetch   FetchFunc
+	db      storage.StructuredStorage
+	blobs   storage.BlobStorage
+	stages  *Registry
+	opts    Options
+	pricing Pricing
+	prompts *prompts.Registry
+	// moderation decides whether a topic may be researched.
+	moderation moderation.Policy
+	// credibility scores every search hit.
+	credibility credibility.Policy
+	// now is the clock freshness windows are measured from.
+	now func() time.Time
 }
 
-// New creates a Pipeline with the given dependencies.
+// pipelinePrompts are the templates rendered by the built-in stages. Their
+// versions are stored with every session.
+var pipelinePrompts = []string{prompts.Moderate, prompts.Queries, prompts.CompareQueries, prompts.FollowUp, prompts.Structure, prompts.Repair, prompts.Verify, prompts.Compare, prompts.Outline, prompts.Section, prompts.Polish, prompts.Report, prompts.Summary}
+
+// New creates a Pipeline with the given dependencies and the default stages.
 func New(llm LLMClient, search SearchFunc, db storage.StructuredStorage, blobs storage.BlobStorage) *Pipeline {
-	return &Pipeline{llm: llm, search: search, db: db, blobs: blobs}
+	p := &Pipeline{llm: llm, search: search, db: db, blobs: blobs, opts: DefaultOptions(), prompts: prompts.Default(), moderation: moderation.DefaultPolicy(), credibility: credibility.DefaultPolicy(), now: time.Now}
+	p.stages = NewRegistry(p.defaultStages()...)
+	return p
 }
 
-// RunWithUpdates runs the research pipeline for the given sessionID and topic.
-// onUpdate is called at each stage transition with a status string and optional detail.
-// It blocks until the pipeline completes or ctx is cancelled.
-// Returns persistence keys on success, nil on failure.
-func (p *Pipeline) RunWithUpdates(ctx context.Context, sessionID, topic string, onUpdate func(status, detail string)) (*Result, error) {
-	fail := func(detail string, err error) (*Result, error) {
-		onUpdate("failed", detail)
-		_ = p.db.UpdateSessionStatus(sessionID, "failed", detail)
-		return nil, err
-	}
+// Stages returns the registry of stages executed by RunWithUpdates. Callers
+// may insert, replace or remove stages before starting a run.
+func (p *Pipeline) Stages() *Registry {
+	return p.stages
+}
 
-	// 1. Persist session record.
-	if err := p.db.CreateSession(sessionID, topic); err != nil {
-		return fail(fmt.Sprintf("create session: %v", err), err)
-	}
+// SetOptions replaces the default options used by subsequent runs.
+func (p *Pipeline) SetOptions(opts Options) {
+	p.opts = opts
+}
 
-	// 2. Generate search queries via LLM.
-	queryPrompt := fmt.Sprintf(
-		"Given the following research topic, generate 3 specific search queries to gather comprehensive information. Return ONLY a JSON array of strings.\nTopic: %s\nReturn ONLY the JSON.", topic)
-	rawQueries, err := p.llm.GenerateContent(ctx, queryPrompt)
-	if err != nil {
-		return fail(fmt.Sprintf("An error occurred: %v", err), err)
-	}
-	queries := extractJSONStringArray(rawQueries)
-	if len(queries) == 0 {
-		// If LLM didn't return JSON, it might be an error message or refusal.
-		// We should check if it looks like a refusal or just use the topic.
-		if strings.Contains(strings.ToLower(rawQueries), "cannot") ||
-			strings.Contains(strings.ToLower(rawQueries), "disallowed") ||
-			strings.Contains(strings.ToLower(rawQueries), "unsafe") {
-			return fail(fmt.Sprintf("Topic validation failed: %s", rawQueries), fmt.Errorf("disallowed topic"))
-		}
-		queries = []string{topic}
-	}
+// SetPricing sets the prices used to report the cost of each run's LLM usage.
+func (p *Pipeline) SetPricing(pricing Pricing) {
+	p.pricing = pricing
+}
 
-	// 3. Run searches in parallel, emitting a "searching" update per query.
-	type rawResult struct {
-		query   string
-		content string
-		url     string
-	}
-	ch := make(chan rawResult, len(queries))
-	for _, q := range queries {
-		q := q
-		go func() {
-			onUpdate("searching", q)
-			searchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-			defer cancel()
-			items, err := p.search(searchCtx, q)
-			if err != nil {
-				log.Printf("[PIPELINE] search failed for %q: %v", q, err)
-				ch <- rawResult{query: q}
-				return
-			}
-			var sb strings.Builder
-			var links []string
-			for _, r := range items {
-				if r.Content != "" {
-					sb.WriteString(r.Content + "\n")
-				}
-				if r.URL != "" {
-					links = append(links, r.URL)
-				}
-			}
-			ch <- rawResult{query: q, content: sb.String(), url: strings.Join(links, " | ")}
-		}()
-	}
+// SetCredibility replaces the policy that scores the sources of subsequent
+// runs.
+func (p *Pipeline) SetCredibility(policy credibility.Policy) {
+	p.credibility = policy
+}
 
-	var sources []event.SearchSource
-	for range queries {
-		r := <-ch
-		if r.content != "" {
-			sources = append(sources, event.SearchSource{Query: r.query, URL: r.url, Snippet: r.content})
-		}
-	}
+// SetClock replaces the clock that freshness windows are measured from, so
+// that replays filter search results as the recorded run did.
+func (p *Pipeline) SetClock(now func() time.Time) {
+	p.now = now
+}
 
-	// 4. Structure findings via LLM.
-	onUpdate("structuring", "")
-	_ = p.db.UpdateSessionStatus(sessionID, "structuring", "")
+// Settings is the configuration a run is made with besides its topic and
+// its clients: the options after merging the request's override into the
+// pipeline defaults, the moderation and credibility policies, and the text
+// of the prompt templates the stages render.
+type Settings struct {
+	Options     Options            `json:"options"`
+	Moderation  moderation.Policy  `json:"moderation"`
+	Credibility credibility.Policy `json:"credibility"`
+	Prompts     map[string]string  `json:"prompts"`
+}
 
-	var sourceBuilder strings.Builder
-	for _, s := range sources {
-		sourceBuilder.WriteString(fmt.Sprintf("- Source: %s\n  Query: %s\n  Snippet: %s\n\n", s.URL, s.Query, s.Snippet))
+// Settings returns the settings a run with the given override would use.
+func (p *Pipeline) Settings(override Options) Settings {
+	return Settings{
+		Options:     p.opts.Merge(override),
+		Moderation:  p.moderation,
+		Credibility: p.credibility,
+		Prompts:     p.prompts.Texts(pipelinePrompts...),
 	}
-	structPrompt := fmt.Sprintf(`You are a research assistant. Convert the search results into the following JSON schema.
-Return ONLY valid JSON. No commentary. No markdown.
-
-Schema:
-{
-  "topic": "string",
-  "key_findings": [{"finding": "string","evidence_urls": ["string"],"confidence": 0.0}],
-  "challenges": ["string"],
-  "open_questions": ["string"],
-  "sources": [{"url": "string","query": "string","snippet": "string"}],
-  "error": "string"
-}
-
-Rules:
-- Use only the provided sources. evidence_urls must be URLs from sources. confidence ranges 0.0–1.0.
-- If the topic is gibberish, unsafe, or disallowed, set "error" to a short explanation and return empty arrays.
-
-Topic: %s
-
-Sources:
-%s`, topic, sourceBuilder.String())
-
-	rawStructured, err := p.llm.GenerateContent(ctx, structPrompt)
-	structured := event.StructuredResearch{
-		SessionID:     sessionID,
-		Topic:         topic,
-		Sources:       sources,
-		OpenQuestions: []string{"Structured extraction failed; using raw sources."},
-	}
-	if err != nil {
-		log.Printf("[PIPELINE] structuring failed: %v", err)
-	} else if parsed, parseErr := ParseStructuredResearch(rawStructured); parseErr == nil {
-		structured = parsed
-	} else {
-		log.Printf("[PIPELINE] JSON parse failed: %v", parseErr)
-	}
-	structured.SessionID = sessionID
+}
 
-	if strings.TrimSpace(structured.Error) != "" {
-		return fail(fmt.Sprintf("Research blocked: %s", structured.Error), fmt.Errorf("structured error: %s", structured.Error))
+// ApplySettings configures subsequent runs as s describes: s.Options become
+// the defaults, the policies are replaced and the prompt templates are
+// rebuilt from s.Prompts.
+func (p *Pipeline) ApplySettings(s Settings) error {
+	registry, err := prompts.FromTexts(s.Prompts)
+	if err != nil {
+		return err
 	}
+	p.opts = s.Options
+	p.moderation = s.Moderation
+	p.credibility = s.Credibility
+	p.prompts = registry
+	return nil
+}
 
-	// 5. Generate report and summary.
-	onUpdate("writing_report", "")
-	_ = p.db.UpdateSessionStatus(sessionID, "writing_report", "")
+// SetPrompts replaces the prompt templates used by subsequent runs.
+func (p *Pipeline) SetPrompts(r *prompts.Registry) {
+	p.prompts = r
+}
 
-	var report, summary string
-	structuredJSON, _ := json.MarshalIndent(structured, "", "  ")
-	reportPrompt := fmt.Sprintf(`You are a research assistant. Write a comprehensive report based only on the structured data below.
-Include key insights, challenges, and a conclusion.
-Structured Data:
-%s`, string(structuredJSON))
+// RunWithUpdates runs the research pipeline for the given sessionID and topic.
+// onUpdate is called at each stage transition with a status string and optional detail.
+// It blocks until the pipeline completes or ctx is cancelled; a cancelled run
+// reports "canceled", is stored with that status and returns ctx.Err().
+// Returns persistence keys on success, nil on failure.
+func (p *Pipeline) RunWithUpdates(ctx context.Context, sessionID, topic string, onUpdate func(status, detail string)) (*Result, error) {
+	return p.RunWithOptions(ctx, sessionID, topic, Options{}, onUpdate)
+}
 
-	report, err = p.llm.GenerateContent(ctx, reportPrompt)
-	if err != nil {
-		return fail(fmt.Sprintf("generate report: %v", err), err)
+// RunWithOptions is RunWithUpdates with per-request options. Fields set in
+// override replace the pipeline defaults for this run only.
+func (p *Pipeline) RunWithOptions(ctx context.Context, sessionID, topic string, override Options, onUpdate func(status, detail string)) (*Result, error) {
+	st := &State{
+		SessionID:      sessionID,
+		Topic:          topic,
+		Options:        p.opts.Merge(override),
+		PromptVersions: p.prompts.Versions(pipelinePrompts...),
+		TopicLanguage:  DetectLanguage(topic),
+		onUpdate:       onUpdate,
 	}
-
-	summaryPrompt := fmt.Sprintf(`Create a short executive summary (3-5 bullet points) for the following report. Return plain text bullets.
-Report:
-%s`, report)
-	summary, err = p.llm.GenerateContent(ctx, summaryPrompt)
-	if err != nil {
-		summary = "Executive summary unavailable due to generation error."
+	st.Language = st.Options.outputLanguage(st.TopicLanguage)
+	if len(st.Options.Subjects) == 0 {
+		st.Options.Subjects = ComparisonSubjects(topic)
 	}
-
-	fullReport := fmt.Sprintf("RESEARCH REPORT\n===============\n%s", report)
-
-	// 6. Persist blobs.
-	reportMDKey, err := p.blobs.SaveBlob("report", []byte(fullReport), "md")
-	if err != nil {
-		log.Printf("[PIPELINE] save report.md failed: %v", err)
+	if err := p.db.CreateSession(sessionID, topic); err != nil {
+		return p.fail(st, fmt.Sprintf("create session: %v", err), err)
 	}
-
-	type bundleJSON struct {
-		Topic      string                   `json:"topic"`
-		Summary    string                   `json:"summary"`
-		Report     string                   `json:"report"`
-		Sources    []event.SearchSource     `json:"sources"`
-		Structured event.StructuredResearch `json:"structured"`
+	if err := p.db.SetSessionLanguage(sessionID, st.Language); err != nil {
+		log.Printf("[PIPELINE] %s save session language: %v", sessionID, err)
 	}
-	bundleBytes, _ := json.MarshalIndent(bundleJSON{
-		Topic:      topic,
-		Summary:    summary,
-		Report:     fullReport,
-		Sources:    sources,
-		Structured: structured,
-	}, "", "  ")
-	reportJSONKey, err := p.blobs.SaveBlob("report", bundleBytes, "json")
-	if err != nil {
-		log.Printf("[PIPELINE] save report.json failed: %v", err)
+	if err := p.db.SavePromptVersions(sessionID, st.PromptVersions); err != nil {
+		log.Printf("[PIPELINE] %s save prompt versions: %v", sessionID, err)
 	}
+	return p.run(ctx, st, p.stages.Stages())
+}
 
-	// 7. Persist structured data to DB.
-	var wg sync.WaitGroup
-	var dbErrors []string
-	var dbMu sync.Mutex
-	addDbErr := func(op string, err error) {
-		if err != nil {
-			dbMu.Lock()
-			dbErrors = append(dbErrors, fmt.Sprintf("%s: %v", op, err))
-			dbMu.Unlock()
+// run executes stages in order against st, checkpointing after each one so
+// that an interrupted run can be resumed. The deadline starts when run is
+// called, so a resumed run gets the full deadline again.
+func (p *Pipeline) run(ctx context.Context, st *State, stages []Stage) (*Result, error) {
+	st.started = time.Now()
+	if st.Options.Deadline > 0 {
+		st.Deadline = st.started.Add(st.Options.Deadline)
+	}
+	for _, stage := range stages {
+		if ctx.Err() != nil {
+			return p.canceled(st, ctx.Err())
 		}
+		if isOptional(stage) && (st.overBudget() || st.nearDeadline()) {
+			reason := "token budget"
+			if !st.overBudget() {
+				reason = "deadline"
+			}
+			log.Printf("[PIPELINE] %s %s reached, skipping stage %s", st.SessionID, reason, stage.Name())
+			st.skip(stage.Name())
+			p.saveCheckpoint(st, stage.Name())
+			continue
+		}
+		st.setStage(stage.Name())
+		if status := stage.Status(); status != "" {
+			st.Update(status, "")
+			_ = p.db.UpdateSessionStatus(st.SessionID, status, "")
+		}
+		if err := stage.Run(ctx, st); err != nil {
+			if ctx.Err() != nil {
+				return p.canceled(st, ctx.Err())
+			}
+			detail := fmt.Sprintf("%s: %v", stage.Name(), err)
+			var stageErr *StageError
+			if errors.As(err, &stageErr) {
+				detail = stageErr.Detail
+				err = stageErr.Err
+			}
+			return p.fail(st, detail, err)
+		}
+		p.saveCheckpoint(st, stage.Name())
 	}
 
-	wg.Add(3)
-	go func() {
-		defer wg.Done()
-		addDbErr("SaveFindings", p.db.SaveFindings(sessionID, structured.KeyFindings))
-	}()
-	go func() {
-		defer wg.Done()
-		addDbErr("SaveOpenQuestions", p.db.SaveOpenQuestions(sessionID, structured.OpenQuestions))
-	}()
-	go func() { defer wg.Done(); addDbErr("SaveSources", p.db.SaveSources(sessionID, structured.Sources)) }()
-	wg.Wait()
-
-	if err := p.db.MarkSessionComplete(sessionID, reportMDKey, reportJSONKey, summary); err != nil {
-		addDbErr("MarkSessionComplete", err)
-	}
+	_ = p.db.DeleteCheckpoint(st.SessionID)
+	p.saveUsage(st)
+	st.Update("complete", st.ReportMDKey)
+	return &Result{
+		SessionID:        st.SessionID,
+		ReportMDKey:      st.ReportMDKey,
+		ReportJSONKey:    st.ReportJSONKey,
+		Rounds:           st.Rounds,
+		Usage:            st.Usage(),
+		SkippedStages:    st.SkippedStages,
+		StructureRepairs: st.StructureRepairs,
+		Language:         st.Language,
+		Renders:          st.Renders,
+	}, nil
+}
 
-	if len(dbErrors) > 0 {
-		log.Printf("[PIPELINE] %s DB persistence errors: %s", sessionID, strings.Join(dbErrors, "; "))
-		// We still consider it a "complete" status for the user if artifacts are safe,
-		// but the Q&A might be degraded.
+// saveUsage stores the run's token usage. Failed and canceled runs are saved
+// too, since their tokens were spent all the same.
+func (p *Pipeline) saveUsage(st *State) {
+	usage := st.Usage()
+	if len(usage) == 0 {
+		return
 	}
+	if err := p.db.SaveTokenUsage(st.SessionID, usage); err != nil {
+		log.Printf("[PIPELINE] %s save token usage: %v", st.SessionID, err)
+	}
+}
 
-	onUpdate("complete", reportMDKey)
-	return &Result{SessionID: sessionID, ReportMDKey: reportMDKey, ReportJSONKey: reportJSONKey}, nil
+func (p *Pipeline) fail(st *State, detail string, err error) (*Result, error) {
+	p.saveUsage(st)
+	st.Update("failed", detail)
+	_ = p.db.UpdateSessionStatus(st.SessionID, "failed", detail)
+	return nil, err
+}
+
+// canceled records a run stopped by its context. Blobs already written by the
+// persist stage are deleted and the checkpoint is dropped, since a canceled
+// session is not resumed.
+func (p *Pipeline) canceled(st *State, err error) (*Result, error) {
+	keys := []string{st.ReportMDKey, st.ReportJSONKey}
+	for _, key := range st.Renders {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if delErr := p.blobs.DeleteBlob(key); delErr != nil {
+			log.Printf("[PIPELINE] %s delete partial blob %s: %v", st.SessionID, key, delErr)
+		}
+	}
+	_ = p.db.DeleteCheckpoint(st.SessionID)
+	p.saveUsage(st)
+	st.Update("canceled", "")
+	_ = p.db.UpdateSessionStatus(st.SessionID, "canceled", "")
+	log.Printf("[PIPELINE] %s canceled: %v", st.SessionID, err)
+	return nil, err
 }
 
 // extractJSONStringArray extracts a JSON string array from raw LLM output,
@@ -284,14 +362,31 @@ func extractJSONStringArray(raw string) []string {
 // URLs are validated against the sources list, and an empty topic is replaced
 // with "Unknown Topic".
 func ParseStructuredResearch(raw string) (event.StructuredResearch, error) {
+	sr, err := decodeStructuredResearch(raw)
+	if err != nil {
+		return sr, err
+	}
+	ValidateStructuredResearch(&sr)
+	return sr, nil
+}
+
+// extractJSONObject returns the outermost JSON object in raw LLM output,
+// tolerating commentary and markdown code-block wrappers around it.
+func extractJSONObject(raw string) (string, error) {
 	cleaned := strings.TrimSpace(raw)
 	start := strings.Index(cleaned, "{")
 	end := strings.LastIndex(cleaned, "}")
 	if start == -1 || end == -1 || end <= start {
-		return event.StructuredResearch{}, fmt.Errorf("no JSON object found in LLM output")
+		return "", fmt.Errorf("no JSON object found in LLM output")
 	}
+	return cleaned[start : end+1], nil
+}
 
-	cleaned = cleaned[start : end+1]
+func decodeStructuredResearch(raw string) (event.StructuredResearch, error) {
+	cleaned, err := extractJSONObject(raw)
+	if err != nil {
+		return event.StructuredResearch{}, err
+	}
 	var sr event.StructuredResearch
 	if err := json.Unmarshal([]byte(cleaned), &sr); err != nil {
 		return event.StructuredResearch{}, err
@@ -305,6 +400,21 @@ func ParseStructuredResearch(raw string) (event.StructuredResearch, error) {
 		sr.OpenQuestions = nil
 		sr.Sources = nil
 	}
+	return sr, nil
+}
+
+// ParseStructuredResearchWithSources is ParseStructuredResearch for output
+// whose sources are already known, such as the structure stage's, which does
+// not ask the LLM to list them: sources are attached, replacing any the output
+// lists, and evidence URLs are checked against them.
+func ParseStructuredResearchWithSources(raw string, sources []event.SearchSource) (event.StructuredResearch, error) {
+	sr, err := decodeStructuredResearch(raw)
+	if err != nil {
+		return sr, err
+	}
+	if sr.Error == "" {
+		sr.Sources = append([]event.SearchSource(nil), sources...)
+	}
 	ValidateStructuredResearch(&sr)
 	return sr, nil
 }
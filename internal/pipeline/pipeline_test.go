@@ -2,14 +2,17 @@ package pipeline_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/user/research-assistant/internal/artifacts"
 	"github.com/user/research-assistant/internal/event"
 	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/storage"
 )
 
 // ---------------------------------------------------------------------------
@@ -21,11 +24,13 @@ type mockLLM struct {
 	responses []string
 	idx       int
 	err       error
+	prompts   []string
 }
 
-func (m *mockLLM) GenerateContent(_ context.Context, _ string) (string, error) {
+func (m *mockLLM) GenerateContent(_ context.Context, prompt string) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.prompts = append(m.prompts, prompt)
 	if m.err != nil {
 		return "", m.err
 	}
@@ -43,11 +48,13 @@ type mockSearcher struct {
 	err     error
 	errIdx  int // -1 = never fail; >=0 = fail on that call index
 	calls   int
+	opts    []pipeline.SearchOptions
 }
 
-func (m *mockSearcher) search(_ context.Context, _ string) ([]pipeline.SearchResult, error) {
+func (m *mockSearcher) search(_ context.Context, _ string, opts pipeline.SearchOptions) ([]pipeline.SearchResult, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.opts = append(m.opts, opts)
 	idx := m.calls
 	m.calls++
 	if m.err != nil && (m.errIdx < 0 || idx == m.errIdx) {
@@ -67,6 +74,14 @@ func (m *mockDB) MarkSessionComplete(_, _, _, _ string) error              { ret
 func (m *mockDB) GetSessionStatus(_ string) (string, string, error)        { return "", "", nil }
 func (m *mockDB) GetSessionArtifacts(_ string) (string, string, error)     { return "", "", nil }
 func (m *mockDB) DeleteSession(_ string) error                             { return nil }
+func (m *mockDB) SaveCheckpoint(_, _ string, _ []byte) error               { return nil }
+func (m *mockDB) LoadCheckpoint(_ string) (string, []byte, error)          { return "", nil, nil }
+func (m *mockDB) DeleteCheckpoint(_ string) error                          { return nil }
+func (m *mockDB) ListUnfinishedSessions() ([]storage.SessionRecord, error) { return nil, nil }
+func (m *mockDB) SaveTokenUsage(_ string, _ []event.TokenUsage) error      { return nil }
+func (m *mockDB) SavePromptVersions(_ string, _ map[string]string) error   { return nil }
+func (m *mockDB) SetSessionLanguage(_, _ string) error                     { return nil }
+func (m *mockDB) SaveRenders(_ string, _ map[string]string) error          { return nil }
 
 type mockBlob struct{}
 
@@ -75,6 +90,35 @@ func (m *mockBlob) SaveBlob(_ string, _ []byte, _ string) (string, error) {
 }
 func (m *mockBlob) DeleteBlob(_ string) error { return nil }
 
+// recordingBlob keeps the last blob saved per extension.
+type recordingBlob struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (m *recordingBlob) SaveBlob(_ string, data []byte, ext string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.blobs == nil {
+		m.blobs = make(map[string][]byte)
+	}
+	m.blobs[ext] = data
+	return "mock-key." + ext, nil
+}
+func (m *recordingBlob) DeleteBlob(_ string) error { return nil }
+
+// bundle decodes the saved report.json.
+func (m *recordingBlob) bundle(t *testing.T) artifacts.Bundle {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b artifacts.Bundle
+	if err := json.Unmarshal(m.blobs["json"], &b); err != nil {
+		t.Fatalf("decode report.json: %v", err)
+	}
+	return b
+}
+
 // ---------------------------------------------------------------------------
 // Helpers
 // ---------------------------------------------------------------------------
@@ -135,9 +179,11 @@ func TestPipeline_EmitsExpectedStatusSequence(t *testing.T) {
 			`["query1","query2","query3"]`,
 			// 2. structuring — valid JSON
 			`{"topic":"T","key_findings":[{"finding":"F","evidence_urls":["http://a.com"],"confidence":0.9}],"challenges":[],"open_questions":[],"sources":[{"url":"http://a.com","query":"query1","snippet":"s"}],"error":""}`,
-			// 3. report generation
+			// 3. claim verification
+			`[{"index":0,"verdict":"supported","strength":0.9,"reason":"r"}]`,
+			// 4. report generation
 			"Full report content",
-			// 4. executive summary
+			// 5. executive summary
 			"• Bullet 1\n• Bullet 2",
 		},
 	}
@@ -260,6 +306,62 @@ func TestPipeline_SearchPartialFailure(t *testing.T) {
 	}
 }
 
+// sourceRecordingDB records the sources passed to SaveSources.
+type sourceRecordingDB struct {
+	mockDB
+	mu      sync.Mutex
+	sources []event.SearchSource
+}
+
+func (m *sourceRecordingDB) SaveSources(_ string, sources []event.SearchSource) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sources = append(m.sources, sources...)
+	return nil
+}
+
+// TestPipeline_KeepsOneSourcePerHit verifies that every search hit becomes its
+// own source record with title, rank and provider, and that evidence URLs
+// pointing at any hit survive validation.
+func TestPipeline_KeepsOneSourcePerHit(t *testing.T) {
+	lm := &mockLLM{
+		responses: []string{
+			`["query1"]`,
+			`{"topic":"T","key_findings":[{"finding":"F","evidence_urls":["http://b.com","http://made-up.com"],"confidence":0.7}],"sources":[]}`,
+			`[{"index":0,"verdict":"supported","strength":0.9,"reason":"r"}]`,
+			"Report",
+			"Summary",
+		},
+	}
+	ms := &mockSearcher{
+		results: []pipeline.SearchResult{
+			{Content: "snippet a", URL: "http://a.com", Title: "A", Provider: "mock"},
+			{Content: "snippet b", URL: "http://b.com", Title: "B", Provider: "mock"},
+		},
+		errIdx: -1,
+	}
+	db := &sourceRecordingDB{}
+	blobs := &recordingBlob{}
+	p := pipeline.New(lm, ms.search, db, blobs)
+
+	if _, err := p.RunWithUpdates(context.Background(), uuid.New().String(), "Test Topic", func(string, string) {}); err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.sources) != 2 {
+		t.Fatalf("expected 2 source records, got %d: %+v", len(db.sources), db.sources)
+	}
+	b := db.sources[1]
+	if b.URL != "http://b.com" || b.Title != "B" || b.Rank != 2 || b.Provider != "mock" || b.Query != "query1" || b.Snippet != "snippet b" {
+		t.Errorf("unexpected source record: %+v", b)
+	}
+	if evidence := blobs.bundle(t).Structured.KeyFindings[0].EvidenceURLs; len(evidence) != 1 || evidence[0] != "http://b.com" {
+		t.Errorf("expected only the real evidence URL to survive, got %v", evidence)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Parser / validator unit tests (functions promoted from cmd/assistant)
 // ---------------------------------------------------------------------------
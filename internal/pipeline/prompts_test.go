@@ -0,0 +1,94 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/pipeline"
+	"github.com/user/research-assistant/internal/prompts"
+)
+
+// promptVersionsDB records the prompt versions saved for each session.
+type promptVersionsDB struct {
+	mockDB
+	mu       sync.Mutex
+	versions map[string]map[string]string
+}
+
+func (m *promptVersionsDB) SavePromptVersions(id string, versions map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.versions == nil {
+		m.versions = map[string]map[string]string{}
+	}
+	m.versions[id] = versions
+	return nil
+}
+
+// bundleBlob keeps the report.json bundle written by the persist stage.
+type bundleBlob struct {
+	mockBlob
+	bundle []byte
+}
+
+func (b *bundleBlob) SaveBlob(name string, data []byte, ext string) (string, error) {
+	if ext == "json" {
+		b.bundle = data
+	}
+	return b.mockBlob.SaveBlob(name, data, ext)
+}
+
+// TestPipeline_PromptOverrideAndVersions verifies that an overridden template
+// is rendered and that the version of every pipeline prompt is stored with
+// the session and in report.json.
+func TestPipeline_PromptOverrideAndVersions(t *testing.T) {
+	dir := t.TempDir()
+	override := "{{- /* version: v2 */ -}}\nList {{.NumQueries}} queries for {{.Topic}} as a JSON array."
+	if err := os.WriteFile(filepath.Join(dir, "queries.tmpl"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := prompts.Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	lm := &mockLLM{responses: []string{`["query1"]`, `{"topic":"T"}`, "Report", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com"}}, errIdx: -1}
+	db := &promptVersionsDB{}
+	blobs := &bundleBlob{}
+	p := pipeline.New(lm, ms.search, db, blobs)
+	p.SetPrompts(registry)
+
+	if _, err := p.RunWithUpdates(context.Background(), "s1", "Go", func(string, string) {}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lm.prompts[0] != "List 3 queries for Go as a JSON array." {
+		t.Errorf("expected overridden queries prompt, got %q", lm.prompts[0])
+	}
+
+	versions := db.versions["s1"]
+	embedded := prompts.Default().Versions(prompts.Report)
+	if versions[prompts.Queries] != "v2" || versions[prompts.Report] != embedded[prompts.Report] {
+		t.Errorf("unexpected stored versions: %v", versions)
+	}
+	if _, ok := versions[prompts.QA]; ok {
+		t.Error("expected only pipeline prompts to be stored with the session")
+	}
+
+	var bundle artifacts.Bundle
+	if err := json.Unmarshal(blobs.bundle, &bundle); err != nil {
+		t.Fatalf("unmarshal bundle: %v", err)
+	}
+	if bundle.PromptVersions[prompts.Queries] != "v2" {
+		t.Errorf("expected prompt versions in report.json, got %v", bundle.PromptVersions)
+	}
+	if !strings.Contains(lm.prompts[1], "Convert the search results") {
+		t.Error("expected the embedded structure prompt to be used")
+	}
+}
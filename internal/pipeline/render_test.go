@@ -0,0 +1,76 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// rendersDB records the render keys saved for each session.
+type rendersDB struct {
+	mockDB
+	mu      sync.Mutex
+	renders map[string]map[string]string
+}
+
+func (m *rendersDB) SaveRenders(id string, renders map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.renders == nil {
+		m.renders = map[string]map[string]string{}
+	}
+	m.renders[id] = renders
+	return nil
+}
+
+// TestPipeline_Renderers verifies that the renderers option saves the report
+// in each requested format and records the blob keys in the result, the
+// bundle and the database.
+func TestPipeline_Renderers(t *testing.T) {
+	lm := &mockLLM{responses: []string{`["query1"]`, `{"topic":"T"}`, "Report citing [1].", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com", Title: "A"}}, errIdx: -1}
+	db := &rendersDB{}
+	blobs := &recordingBlob{}
+	p := pipeline.New(lm, ms.search, db, blobs)
+
+	opts := pipeline.Options{Renderers: []string{"html", "epub"}}
+	result, err := p.RunWithOptions(context.Background(), "s1", "Go", opts, func(string, string) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{"html": "mock-key.html", "epub": "mock-key.epub"}
+	for name, key := range want {
+		if result.Renders[name] != key {
+			t.Errorf("expected result render %s=%s, got %v", name, key, result.Renders)
+		}
+		if db.renders["s1"][name] != key {
+			t.Errorf("expected stored render %s=%s, got %v", name, key, db.renders["s1"])
+		}
+		if blobs.bundle(t).Renders[name] != key {
+			t.Errorf("expected bundle render %s=%s, got %v", name, key, blobs.bundle(t).Renders)
+		}
+	}
+	if page := string(blobs.blobs["html"]); !strings.Contains(page, `<a href="http://a.com">A</a>`) {
+		t.Errorf("expected the HTML report to link its source, got:\n%s", page)
+	}
+	if len(blobs.blobs["epub"]) == 0 {
+		t.Error("expected an EPUB blob")
+	}
+}
+
+func TestParseOptions_Renderers(t *testing.T) {
+	opts, err := pipeline.ParseOptions(map[string]any{"renderers": []any{"html", "epub"}})
+	if err != nil {
+		t.Fatalf("ParseOptions: %v", err)
+	}
+	if len(opts.Renderers) != 2 || opts.Renderers[0] != "html" || opts.Renderers[1] != "epub" {
+		t.Errorf("unexpected renderers: %v", opts.Renderers)
+	}
+	if _, err := pipeline.ParseOptions(map[string]any{"renderers": []any{"pdf"}}); err == nil {
+		t.Error("expected an unknown renderer to be rejected")
+	}
+}
@@ -0,0 +1,216 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/user/research-assistant/internal/prompts"
+)
+
+const (
+	// minSectionedFindings is the number of key findings from which detailed
+	// reports are written section by section. Fewer findings fit one call.
+	minSectionedFindings = 4
+	// minSections and maxSections bound the outline of a sectioned report.
+	minSections = 2
+	maxSections = 6
+	// minPolishRatio is the shortest the final pass may make the draft, as
+	// a fraction of its length, before the draft is kept instead.
+	minPolishRatio = 0.6
+)
+
+// outlineSection is one section of the report outline. Findings holds the
+// indices of the key findings the section covers.
+type outlineSection struct {
+	Title    string `json:"title"`
+	Findings []int  `json:"findings"`
+}
+
+// OutlineSchema returns the JSON schema of the report outline.
+func OutlineSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"sections": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"title":    map[string]any{"type": "string"},
+						"findings": map[string]any{"type": "array", "items": map[string]any{"type": "integer"}},
+					},
+					"required": []string{"title"},
+				},
+			},
+		},
+		"required": []string{"sections"},
+	}
+}
+
+// writeSections writes a detailed report outline first: it asks for an
+// outline, writes every section in parallel from the findings assigned to
+// it, and finishes with a pass for consistency and transitions. Each section
+// emits a "writing_section" update when it starts. Sections that fail are
+// left out; an error is returned only when no usable report results.
+func (p *Pipeline) writeSections(ctx context.Context, st *State, input reportData, table string) (string, error) {
+	outline, err := p.outline(ctx, st, input, table)
+	if err != nil {
+		return "", fmt.Errorf("outline: %w", err)
+	}
+
+	titles := make([]string, len(outline))
+	for i, s := range outline {
+		titles[i] = fmt.Sprintf("%d. %s", i+1, s.Title)
+	}
+	sources := numberedSources(st.Sources)
+
+	sections := make([]string, len(outline))
+	var wg sync.WaitGroup
+	for i, s := range outline {
+		wg.Add(1)
+		go func(i int, s outlineSection) {
+			defer wg.Done()
+			st.Update("writing_section", fmt.Sprintf("%d/%d: %s", i+1, len(outline), s.Title))
+			text, err := p.writeSection(ctx, st, input, s, strings.Join(titles, "\n"), sources, table)
+			if err != nil {
+				log.Printf("[PIPELINE] %s section %q failed: %v", st.SessionID, s.Title, err)
+				return
+			}
+			sections[i] = text
+		}(i, s)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var written []string
+	for _, s := range sections {
+		if s != "" {
+			written = append(written, s)
+		}
+	}
+	if len(written) == 0 {
+		return "", fmt.Errorf("no section was written")
+	}
+	draft := strings.Join(written, "\n\n")
+	return p.polish(ctx, st, draft), nil
+}
+
+// outline asks the LLM to plan the report's sections and assign the key
+// findings to them. Unknown finding indices are dropped.
+func (p *Pipeline) outline(ctx context.Context, st *State, input reportData, table string) ([]outlineSection, error) {
+	var findings strings.Builder
+	for i, f := range input.KeyFindings {
+		findings.WriteString(fmt.Sprintf("%d. %s (confidence %.2f)\n", i, f.Finding, f.Confidence))
+	}
+	prompt, err := p.prompts.Render(prompts.Outline, map[string]any{
+		"Topic":         input.Topic,
+		"MinSections":   minSections,
+		"MaxSections":   maxSections,
+		"Language":      st.Language,
+		"Comparison":    table,
+		"Findings":      findings.String(),
+		"Challenges":    input.Challenges,
+		"OpenQuestions": input.OpenQuestions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	raw, err := p.generateJSON(ctx, st, prompt, OutlineSchema())
+	if err != nil {
+		return nil, err
+	}
+	obj, err := extractJSONObject(raw)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Sections []outlineSection `json:"sections"`
+	}
+	if err := json.Unmarshal([]byte(obj), &out); err != nil {
+		return nil, err
+	}
+
+	var sections []outlineSection
+	for _, s := range out.Sections {
+		s.Title = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(s.Title), "#"))
+		if s.Title == "" {
+			continue
+		}
+		var valid []int
+		for _, n := range s.Findings {
+			if n >= 0 && n < len(input.KeyFindings) {
+				valid = append(valid, n)
+			}
+		}
+		s.Findings = valid
+		sections = append(sections, s)
+		if len(sections) == maxSections {
+			break
+		}
+	}
+	if len(sections) < minSections {
+		return nil, fmt.Errorf("outline has %d usable sections, need at least %d", len(sections), minSections)
+	}
+	return sections, nil
+}
+
+// writeSection writes one section from the findings assigned to it. A
+// section without findings, such as a conclusion, sees all of them.
+func (p *Pipeline) writeSection(ctx context.Context, st *State, input reportData, s outlineSection, outline, sources, table string) (string, error) {
+	findings := input.KeyFindings
+	if len(s.Findings) > 0 {
+		findings = make([]reportFinding, 0, len(s.Findings))
+		for _, n := range s.Findings {
+			findings = append(findings, input.KeyFindings[n])
+		}
+	}
+	findingsJSON, _ := json.MarshalIndent(findings, "", "  ")
+	prompt, err := p.prompts.Render(prompts.Section, map[string]any{
+		"Topic":      input.Topic,
+		"Title":      s.Title,
+		"Outline":    outline,
+		"Language":   st.Language,
+		"Comparison": table,
+		"Findings":   string(findingsJSON),
+		"Sources":    sources,
+	})
+	if err != nil {
+		return "", err
+	}
+	text, err := p.generate(ctx, st, prompt)
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("empty section")
+	}
+	if !strings.HasPrefix(text, "#") {
+		text = "## " + s.Title + "\n\n" + text
+	}
+	return text, nil
+}
+
+// polish runs the final pass over the assembled sections. The draft is kept
+// when the pass fails or cuts it down to less than minPolishRatio of its
+// length, since that means content was dropped rather than edited.
+func (p *Pipeline) polish(ctx context.Context, st *State, draft string) string {
+	prompt, err := p.prompts.Render(prompts.Polish, map[string]any{"Language": st.Language, "Draft": draft})
+	if err != nil {
+		log.Printf("[PIPELINE] %s final report pass skipped: %v", st.SessionID, err)
+		return draft
+	}
+	polished, err := p.streamReport(ctx, st, prompt)
+	polished = strings.TrimSpace(polished)
+	if err != nil || float64(len(polished)) < minPolishRatio*float64(len(draft)) {
+		log.Printf("[PIPELINE] %s final report pass unusable, keeping the section draft (err: %v)", st.SessionID, err)
+		return draft
+	}
+	return polished
+}
@@ -0,0 +1,173 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// routeLLM answers by prompt content, for stages that call the LLM
+// concurrently. Unmatched prompts get "unexpected".
+type routeLLM struct {
+	mu      sync.Mutex
+	routes  func(prompt string) string
+	prompts []string
+}
+
+func (m *routeLLM) GenerateContent(_ context.Context, prompt string) (string, error) {
+	m.mu.Lock()
+	m.prompts = append(m.prompts, prompt)
+	m.mu.Unlock()
+	return m.routes(prompt), nil
+}
+
+// promptsWith returns the recorded prompts containing substr.
+func (m *routeLLM) promptsWith(substr string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []string
+	for _, p := range m.prompts {
+		if strings.Contains(p, substr) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+const fourFindings = `{"topic": "T", "key_findings": [
+	{"finding": "Finding zero", "confidence": 0.9},
+	{"finding": "Finding one", "confidence": 0.8},
+	{"finding": "Finding two", "confidence": 0.7},
+	{"finding": "Finding three", "confidence": 0.6}
+]}`
+
+func sectionRoutes(outline, polish func(draft string) string) func(string) string {
+	return func(prompt string) string {
+		switch {
+		case strings.Contains(prompt, "specific search queries"):
+			return `["q"]`
+		case strings.Contains(prompt, "Convert the search results"):
+			return fourFindings
+		case strings.Contains(prompt, "outline the report"):
+			return outline("")
+		case strings.Contains(prompt, "Write only the section"):
+			title := prompt[strings.Index(prompt, `section "`)+len(`section "`):]
+			return "Text of " + title[:strings.Index(title, `"`)] + " [1]."
+		case strings.Contains(prompt, "You are an editor"):
+			return polish(prompt[strings.Index(prompt, "Report:\n")+len("Report:\n"):])
+		case strings.Contains(prompt, "Write a comprehensive report"):
+			return "Single pass report [1]."
+		case strings.Contains(prompt, "executive summary"):
+			return "Summary"
+		}
+		return "unexpected"
+	}
+}
+
+// TestPipeline_SectionedReport verifies that a detailed report is written
+// from an outline, one section per call with the findings assigned to it,
+// and finished by a final pass.
+func TestPipeline_SectionedReport(t *testing.T) {
+	lm := &routeLLM{routes: sectionRoutes(
+		func(string) string {
+			return `{"sections": [{"title": "Early", "findings": [0, 1]}, {"title": "Late", "findings": [2, 3, 9]}, {"title": "Conclusion"}]}`
+		},
+		func(draft string) string { return "Introduction.\n\n" + draft },
+	)}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com", Title: "A"}}, errIdx: -1}
+	blobs := &recordingBlob{}
+	p := pipeline.New(lm, ms.search, &mockDB{}, blobs)
+
+	var mu sync.Mutex
+	var sections []string
+	opts := pipeline.Options{IncludeUnverified: true}
+	_, err := p.RunWithOptions(context.Background(), "s1", "Topic", opts, func(status, detail string) {
+		if status == "writing_section" {
+			mu.Lock()
+			sections = append(sections, detail)
+			mu.Unlock()
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sections) != 3 {
+		t.Fatalf("expected a writing_section update per section, got %v", sections)
+	}
+	for _, want := range []string{"1/3: Early", "2/3: Late", "3/3: Conclusion"} {
+		found := false
+		for _, s := range sections {
+			found = found || s == want
+		}
+		if !found {
+			t.Errorf("missing writing_section update %q in %v", want, sections)
+		}
+	}
+
+	early := lm.promptsWith(`Write only the section "Early"`)
+	if len(early) != 1 || !strings.Contains(early[0], "Finding one") || strings.Contains(early[0], "Finding two") {
+		t.Errorf("expected the Early section to see only its findings, got %v", early)
+	}
+	conclusion := lm.promptsWith(`Write only the section "Conclusion"`)
+	if len(conclusion) != 1 || !strings.Contains(conclusion[0], "Finding zero") || !strings.Contains(conclusion[0], "Finding three") {
+		t.Errorf("expected the Conclusion to see every finding, got %v", conclusion)
+	}
+	if n := len(lm.promptsWith("Write a comprehensive report")); n != 0 {
+		t.Errorf("expected no single-pass report prompt, got %d", n)
+	}
+
+	report := blobs.bundle(t).Report
+	want := "Introduction.\n\n## Early\n\nText of Early [1].\n\n## Late\n\nText of Late [1].\n\n## Conclusion\n\nText of Conclusion [1]."
+	if !strings.Contains(report, want) {
+		t.Errorf("expected the polished sections in order, got:\n%s", report)
+	}
+}
+
+func TestPipeline_SectionedReportFallbacks(t *testing.T) {
+	tests := []struct {
+		name    string
+		outline string
+		polish  func(string) string
+		want    string
+	}{
+		{
+			name:    "invalid outline writes a single-pass report",
+			outline: "no outline",
+			polish:  func(d string) string { return d },
+			want:    "Single pass report [1].",
+		},
+		{
+			name:    "one-section outline writes a single-pass report",
+			outline: `{"sections": [{"title": "Only"}]}`,
+			polish:  func(d string) string { return d },
+			want:    "Single pass report [1].",
+		},
+		{
+			name:    "truncating final pass keeps the draft",
+			outline: `{"sections": [{"title": "A"}, {"title": "B"}]}`,
+			polish:  func(string) string { return "Short." },
+			want:    "## A\n\nText of A [1].\n\n## B\n\nText of B [1].",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outline := tt.outline
+			lm := &routeLLM{routes: sectionRoutes(func(string) string { return outline }, tt.polish)}
+			ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com", Title: "A"}}, errIdx: -1}
+			blobs := &recordingBlob{}
+			p := pipeline.New(lm, ms.search, &mockDB{}, blobs)
+
+			opts := pipeline.Options{IncludeUnverified: true}
+			if _, err := p.RunWithOptions(context.Background(), "s1", "Topic", opts, func(string, string) {}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if report := blobs.bundle(t).Report; !strings.Contains(report, tt.want) {
+				t.Errorf("expected report to contain %q, got:\n%s", tt.want, report)
+			}
+		})
+	}
+}
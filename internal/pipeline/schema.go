@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+)
+
+// ValidateSchema checks value against a JSON-schema subset (type, properties,
+// required, additionalProperties, items, minItems, maxItems, enum, pattern,
+// minimum and maximum) and returns one message per violation. Values are expected in the
+// shape produced by encoding/json, although Go integer types are accepted for
+// numbers as well.
+func ValidateSchema(schema map[string]any, value any) []string {
+	var problems []string
+	validateSchema(schema, value, "$", &problems)
+	return problems
+}
+
+func validateSchema(schema map[string]any, value any, path string, problems *[]string) {
+	add := func(format string, args ...any) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			add("expected object")
+			return
+		}
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]string); ok {
+			for _, key := range required {
+				if _, present := obj[key]; !present {
+					add("missing required field %q", key)
+				}
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sub, known := props[key].(map[string]any)
+			if !known {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					add("unknown field %q", key)
+				}
+				continue
+			}
+			validateSchema(sub, obj[key], path+"."+key, problems)
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			if strs, isStrs := value.([]string); isStrs {
+				arr = make([]any, len(strs))
+				for i, s := range strs {
+					arr[i] = s
+				}
+			} else {
+				add("expected array")
+				return
+			}
+		}
+		if minItems, ok := schemaNumber(schema["minItems"]); ok && float64(len(arr)) < minItems {
+			add("expected at least %v items, got %d", minItems, len(arr))
+		}
+		if maxItems, ok := schemaNumber(schema["maxItems"]); ok && float64(len(arr)) > maxItems {
+			add("expected at most %v items, got %d", maxItems, len(arr))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range arr {
+				validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			add("expected string")
+			return
+		}
+		if enum, ok := schema["enum"].([]string); ok && !containsString(enum, s) {
+			add("expected one of %v, got %q", enum, s)
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			add("%q does not match %s", s, pattern)
+		}
+	case "integer", "number":
+		n, ok := schemaNumber(value)
+		if !ok {
+			add("expected %s", schema["type"])
+			return
+		}
+		if schema["type"] == "integer" && n != math.Trunc(n) {
+			add("expected integer, got %v", n)
+		}
+		if min, ok := schemaNumber(schema["minimum"]); ok && n < min {
+			add("must be >= %v, got %v", min, n)
+		}
+		if max, ok := schemaNumber(schema["maximum"]); ok && n > max {
+			add("must be <= %v, got %v", max, n)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			add("expected boolean")
+		}
+	}
+}
+
+func schemaNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
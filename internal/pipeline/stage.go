@@ -0,0 +1,412 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/event"
+)
+
+// Names of the built-in stages, in their default execution order.
+const (
+	StageModerate  = "moderate"
+	StageQueries   = "queries"
+	StageSearch    = "search"
+	StageDedup     = "dedup"
+	StageFetch     = "fetch"
+	StageStructure = "structure"
+	StageDeepen    = "deepen"
+	StageVerify    = "verify"
+	StageCompare   = "compare"
+	StageReport    = "report"
+	StageCite      = "cite"
+	StageSummary   = "summary"
+	StagePersist   = "persist"
+)
+
+// State is the typed working set shared by all stages of a single run. Each
+// stage reads the fields produced by the stages before it and fills in its
+// own outputs.
+type State struct {
+	SessionID string
+	Topic     string
+	Options   Options
+	// PromptVersions records the version of each prompt template the run
+	// renders, keyed by template name.
+	PromptVersions map[string]string
+	// TopicLanguage is the language detected from the topic, or "".
+	TopicLanguage string
+	// Language is the language the report and summary are written in.
+	Language string
+
+	// Queries is produced by the queries stage.
+	Queries []string
+	// QueryLanguages maps queries to the language they are searched in.
+	// Queries without an entry are searched without a language restriction.
+	QueryLanguages map[string]string
+	// Sources is produced by the search stage.
+	Sources []event.SearchSource
+	// Structured is produced by the structure stage and extended by the
+	// deepen stage.
+	Structured event.StructuredResearch
+	// Comparison is the subject × criterion matrix produced by the compare
+	// stage of a comparison run.
+	Comparison *event.ComparisonMatrix
+	// Rounds is the number of research rounds completed.
+	Rounds int
+	// Report is the report body produced by the report stage. The cite stage
+	// renumbers its citations and appends the references section.
+	Report string
+	// Citations maps the report's citation numbers to sources.
+	Citations []artifacts.Citation
+	// RemovedCitations lists citations to unknown sources dropped by the cite stage.
+	RemovedCitations []int
+	// Summary is the executive summary produced by the summary stage.
+	Summary string
+
+	// ReportMDKey and ReportJSONKey are produced by the persist stage.
+	ReportMDKey   string
+	ReportJSONKey string
+	// Renders maps renderer names to the blob keys of the report rendered
+	// in that format by the persist stage.
+	Renders map[string]string
+	// SkippedStages lists optional stages the run skipped, or cut short, to
+	// stay within its token budget or deadline.
+	SkippedStages []string
+	// Deadline is when the run should have delivered its result, derived
+	// from Options.Deadline when the run starts. Zero means none.
+	Deadline time.Time
+	// StructureRepairs counts the re-prompts sent because structured output
+	// failed to parse or validate.
+	StructureRepairs int
+
+	// structuringFailed records that the structure stage fell back to the raw
+	// sources, in which case there are no real gaps to research further.
+	structuringFailed bool
+	// reportStreamed records that report text was streamed to the caller.
+	reportStreamed bool
+	// started is when the run, or its resumption, began.
+	started time.Time
+
+	mu       sync.Mutex
+	onUpdate func(status, detail string)
+
+	usageMu    sync.Mutex
+	stage      string // stage currently running, charged for LLM calls
+	usage      map[string]*event.TokenUsage
+	usageOrder []string
+}
+
+// Update forwards a progress update to the caller's onUpdate callback. Stages
+// that report finer-grained progress than their boundary status (e.g. one
+// update per search query) call it directly. It is safe for concurrent use.
+func (s *State) Update(status, detail string) {
+	if s.onUpdate == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onUpdate(status, detail)
+}
+
+// TokensUsed returns the tokens spent on LLM calls so far in this run.
+func (s *State) TokensUsed() int {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	total := 0
+	for _, u := range s.usage {
+		total += u.TotalTokens
+	}
+	return total
+}
+
+// Usage returns the LLM usage of the run per stage, in the order the stages
+// first called the LLM.
+func (s *State) Usage() []event.TokenUsage {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	out := make([]event.TokenUsage, 0, len(s.usageOrder))
+	for _, name := range s.usageOrder {
+		out = append(out, *s.usage[name])
+	}
+	return out
+}
+
+func (s *State) setStage(name string) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	s.stage = name
+}
+
+// addUsage charges one LLM call to the running stage.
+func (s *State) addUsage(prompt, completion int, cost float64, estimated bool) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	if s.usage == nil {
+		s.usage = make(map[string]*event.TokenUsage)
+	}
+	u, ok := s.usage[s.stage]
+	if !ok {
+		u = &event.TokenUsage{Stage: s.stage}
+		s.usage[s.stage] = u
+		s.usageOrder = append(s.usageOrder, s.stage)
+	}
+	u.Calls++
+	u.PromptTokens += prompt
+	u.CompletionTokens += completion
+	u.TotalTokens += prompt + completion
+	u.CostUSD += cost
+	u.Estimated = u.Estimated || estimated
+}
+
+// restoreUsage replaces the run's usage with a snapshot taken by Usage.
+func (s *State) restoreUsage(usage []event.TokenUsage) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+	s.usage = make(map[string]*event.TokenUsage, len(usage))
+	s.usageOrder = nil
+	for i := range usage {
+		u := usage[i]
+		s.usage[u.Stage] = &u
+		s.usageOrder = append(s.usageOrder, u.Stage)
+	}
+}
+
+func (s *State) tagQuery(query, lang string) {
+	if lang == "" {
+		return
+	}
+	if s.QueryLanguages == nil {
+		s.QueryLanguages = make(map[string]string)
+	}
+	s.QueryLanguages[query] = lang
+}
+
+func (s *State) queryLanguage(query string) string {
+	return s.QueryLanguages[query]
+}
+
+// primarySearchLanguage is the language follow-up queries are written and
+// searched in.
+func (s *State) primarySearchLanguage() string {
+	if langs := s.Options.searchLanguages(s.TopicLanguage); len(langs) > 0 {
+		return langs[0]
+	}
+	return ""
+}
+
+// overBudget reports whether the run has spent its token budget.
+func (s *State) overBudget() bool {
+	return s.Options.TokenBudget > 0 && s.TokensUsed() >= s.Options.TokenBudget
+}
+
+// deadlineReserve is the share of the deadline kept for writing, citing and
+// persisting the report. Optional work is cut once less than that remains.
+const deadlineReserve = 0.25
+
+// spareTime returns the time left before the reserve for the report is
+// reached, which may be negative. ok is false for runs without a deadline.
+func (s *State) spareTime() (spare time.Duration, ok bool) {
+	if s.Deadline.IsZero() {
+		return 0, false
+	}
+	reserve := time.Duration(float64(s.Options.Deadline) * deadlineReserve)
+	return time.Until(s.Deadline) - reserve, true
+}
+
+// nearDeadline reports whether the run has eaten into the time reserved for
+// the report.
+func (s *State) nearDeadline() bool {
+	spare, ok := s.spareTime()
+	return ok && spare <= 0
+}
+
+// skip records a stage skipped or cut short.
+func (s *State) skip(stage string) {
+	if !containsString(s.SkippedStages, stage) {
+		s.SkippedStages = append(s.SkippedStages, stage)
+	}
+}
+
+// Stage is a single named step of the research pipeline.
+type Stage interface {
+	// Name identifies the stage within a Registry.
+	Name() string
+	// Status is emitted through onUpdate and persisted as the session status
+	// when the stage starts. Stages that return "" emit their own updates.
+	Status() string
+	// Run executes the stage against the shared run state.
+	Run(ctx context.Context, st *State) error
+}
+
+// OptionalStage is implemented by stages that a run may skip to stay within
+// its token budget. The run still produces a report without them.
+type OptionalStage interface {
+	Stage
+	Optional() bool
+}
+
+type funcStage struct {
+	name     string
+	status   string
+	optional bool
+	run      func(ctx context.Context, st *State) error
+}
+
+func (s *funcStage) Name() string                             { return s.name }
+func (s *funcStage) Status() string                           { return s.status }
+func (s *funcStage) Optional() bool                           { return s.optional }
+func (s *funcStage) Run(ctx context.Context, st *State) error { return s.run(ctx, st) }
+
+// NewStage adapts a plain function into a Stage.
+func NewStage(name, status string, run func(ctx context.Context, st *State) error) Stage {
+	return &funcStage{name: name, status: status, run: run}
+}
+
+// NewOptionalStage is NewStage for a stage the run may skip; see OptionalStage.
+func NewOptionalStage(name, status string, run func(ctx context.Context, st *State) error) Stage {
+	return &funcStage{name: name, status: status, optional: true, run: run}
+}
+
+func isOptional(s Stage) bool {
+	o, ok := s.(OptionalStage)
+	return ok && o.Optional()
+}
+
+// StageError carries a user-facing failure detail alongside the underlying
+// error. The detail is reported through onUpdate("failed", detail) and stored
+// on the session row.
+type StageError struct {
+	Detail string
+	Err    error
+}
+
+func (e *StageError) Error() string {
+	return e.Detail
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// stageFailure wraps err with a user-facing detail message.
+func stageFailure(detail string, err error) error {
+	return &StageError{Detail: detail, Err: err}
+}
+
+// Registry holds the ordered list of stages executed by a Pipeline. It is safe
+// for concurrent use; runs take a snapshot of the stage list when they start.
+type Registry struct {
+	mu     sync.RWMutex
+	stages []Stage
+}
+
+// NewRegistry creates a Registry with the given stages in order.
+func NewRegistry(stages ...Stage) *Registry {
+	return &Registry{stages: append([]Stage(nil), stages...)}
+}
+
+// Stages returns a snapshot of the registered stages in execution order.
+func (r *Registry) Stages() []Stage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Stage(nil), r.stages...)
+}
+
+// Names returns the names of the registered stages in execution order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, len(r.stages))
+	for i, s := range r.stages {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// Get returns the stage registered under name.
+func (r *Registry) Get(name string) (Stage, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if i := r.indexLocked(name); i >= 0 {
+		return r.stages[i], true
+	}
+	return nil, false
+}
+
+// Append adds a stage to the end of the pipeline.
+func (r *Registry) Append(s Stage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.indexLocked(s.Name()) >= 0 {
+		return fmt.Errorf("stage %q already registered", s.Name())
+	}
+	r.stages = append(r.stages, s)
+	return nil
+}
+
+// InsertBefore adds s immediately before the stage named target.
+func (r *Registry) InsertBefore(target string, s Stage) error {
+	return r.insert(target, 0, s)
+}
+
+// InsertAfter adds s immediately after the stage named target.
+func (r *Registry) InsertAfter(target string, s Stage) error {
+	return r.insert(target, 1, s)
+}
+
+func (r *Registry) insert(target string, offset int, s Stage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.indexLocked(s.Name()) >= 0 {
+		return fmt.Errorf("stage %q already registered", s.Name())
+	}
+	i := r.indexLocked(target)
+	if i < 0 {
+		return fmt.Errorf("stage %q not found", target)
+	}
+	i += offset
+	r.stages = append(r.stages, nil)
+	copy(r.stages[i+1:], r.stages[i:])
+	r.stages[i] = s
+	return nil
+}
+
+// Replace swaps the stage named target for s, keeping its position.
+func (r *Registry) Replace(target string, s Stage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.indexLocked(target)
+	if i < 0 {
+		return fmt.Errorf("stage %q not found", target)
+	}
+	if j := r.indexLocked(s.Name()); j >= 0 && j != i {
+		return fmt.Errorf("stage %q already registered", s.Name())
+	}
+	r.stages[i] = s
+	return nil
+}
+
+// Remove drops the stage named target so that runs skip it.
+func (r *Registry) Remove(target string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.indexLocked(target)
+	if i < 0 {
+		return fmt.Errorf("stage %q not found", target)
+	}
+	r.stages = append(r.stages[:i], r.stages[i+1:]...)
+	return nil
+}
+
+func (r *Registry) indexLocked(name string) int {
+	for i, s := range r.stages {
+		if s.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,144 @@
+package pipeline_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+func noopStage(name string) pipeline.Stage {
+	return pipeline.NewStage(name, "", func(context.Context, *pipeline.State) error { return nil })
+}
+
+func TestRegistry_InsertReplaceRemove(t *testing.T) {
+	r := pipeline.NewRegistry(noopStage("a"), noopStage("c"))
+
+	if err := r.InsertAfter("a", noopStage("b")); err != nil {
+		t.Fatalf("InsertAfter: %v", err)
+	}
+	if err := r.InsertBefore("a", noopStage("start")); err != nil {
+		t.Fatalf("InsertBefore: %v", err)
+	}
+	if err := r.Append(noopStage("end")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	want := []string{"start", "a", "b", "c", "end"}
+	if got := r.Names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("names after inserts: want %v, got %v", want, got)
+	}
+
+	if err := r.InsertAfter("missing", noopStage("x")); err == nil {
+		t.Error("expected error inserting after unknown stage")
+	}
+	if err := r.Append(noopStage("a")); err == nil {
+		t.Error("expected error registering duplicate stage name")
+	}
+
+	replacement := pipeline.NewStage("b", "checking", func(context.Context, *pipeline.State) error { return nil })
+	if err := r.Replace("b", replacement); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if s, ok := r.Get("b"); !ok || s.Status() != "checking" {
+		t.Errorf("expected replaced stage b with status 'checking', got %v", s)
+	}
+
+	if err := r.Remove("start"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	want = []string{"a", "b", "c", "end"}
+	if got := r.Names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("names after remove: want %v, got %v", want, got)
+	}
+}
+
+// TestPipeline_CustomStageInserted verifies that a stage inserted into the
+// registry runs between its neighbours, sees their typed outputs, and has its
+// status emitted automatically at the stage boundary.
+func TestPipeline_CustomStageInserted(t *testing.T) {
+	lm := &mockLLM{
+		responses: []string{
+			`["query1"]`,
+			`{"topic":"T","key_findings":[{"finding":"F","evidence_urls":["http://a.com"],"confidence":0.9}],"challenges":[],"open_questions":[],"sources":[{"url":"http://a.com","query":"query1","snippet":"s"}],"error":""}`,
+			`[{"index":0,"verdict":"supported","strength":0.9,"reason":"r"}]`,
+			"Report",
+			"Summary",
+		},
+	}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "content", URL: "http://a.com"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+
+	var seenFindings int
+	audit := pipeline.NewStage("audit", "auditing", func(_ context.Context, st *pipeline.State) error {
+		seenFindings = len(st.Structured.KeyFindings)
+		return nil
+	})
+	if err := p.Stages().InsertAfter(pipeline.StageStructure, audit); err != nil {
+		t.Fatalf("InsertAfter: %v", err)
+	}
+
+	cb, statuses, mu := collectStatuses(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := p.RunWithUpdates(ctx, uuid.New().String(), "Test Topic", cb); err != nil {
+		t.Fatalf("RunWithUpdates: %v", err)
+	}
+	if seenFindings != 1 {
+		t.Errorf("custom stage should see 1 structured finding, saw %d", seenFindings)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	iStruct := firstIndexOf(*statuses, "structuring")
+	iAudit := firstIndexOf(*statuses, "auditing")
+	iReport := firstIndexOf(*statuses, "writing_report")
+	if iAudit < 0 || iStruct >= iAudit || iAudit >= iReport {
+		t.Errorf("expected structuring < auditing < writing_report; statuses: %v", *statuses)
+	}
+}
+
+// TestPipeline_RemovedStageIsSkipped verifies that removing a stage skips it
+// and that a failing stage reports its error through the "failed" status.
+func TestPipeline_RemovedStageIsSkipped(t *testing.T) {
+	lm := &mockLLM{responses: []string{`["query1"]`}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "content", URL: "http://a.com"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+
+	if err := p.Stages().Remove(pipeline.StageSearch); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	boom := pipeline.NewStage(pipeline.StageStructure, "", func(context.Context, *pipeline.State) error {
+		return fmt.Errorf("boom")
+	})
+	if err := p.Stages().Replace(pipeline.StageStructure, boom); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+
+	var failDetail string
+	cb, statuses, mu := collectStatuses(func(status, detail string) {
+		if status == "failed" {
+			failDetail = detail
+		}
+	})
+	result, err := p.RunWithUpdates(context.Background(), uuid.New().String(), "Test Topic", cb)
+	if err == nil || result != nil {
+		t.Fatalf("expected failure from replaced stage; result=%v err=%v", result, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if countOf(*statuses, "searching") != 0 {
+		t.Errorf("removed search stage should not emit 'searching'; statuses: %v", *statuses)
+	}
+	if failDetail != "structure: boom" {
+		t.Errorf("unexpected failure detail %q", failDetail)
+	}
+	if ms.calls != 0 {
+		t.Errorf("expected no search calls, got %d", ms.calls)
+	}
+}
@@ -0,0 +1,534 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/credibility"
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/prompts"
+	"github.com/user/research-assistant/internal/render"
+)
+
+// defaultStages returns the built-in stages in execution order.
+func (p *Pipeline) defaultStages() []Stage {
+	return []Stage{
+		NewStage(StageModerate, "", p.moderateTopic),
+		NewStage(StageQueries, "", p.generateQueries),
+		NewStage(StageSearch, "", p.runSearches),
+		NewStage(StageDedup, "", p.dedupSources),
+		NewStage(StageFetch, "", p.fetchPages),
+		NewStage(StageStructure, "structuring", p.structureFindings),
+		NewStage(StageDeepen, "", p.deepen),
+		NewOptionalStage(StageVerify, "verifying", p.verifyFindings),
+		NewStage(StageCompare, "", p.compareSubjects),
+		NewStage(StageReport, "writing_report", p.writeReport),
+		NewStage(StageCite, "", p.citeSources),
+		NewOptionalStage(StageSummary, "", p.writeSummary),
+		NewStage(StagePersist, "", p.persist),
+	}
+}
+
+// charsPerToken approximates the token cost of prompt and response text for
+// clients that do not report usage.
+const charsPerToken = 4
+
+// generate sends prompt to the LLM and charges the tokens of the exchange to
+// the running stage, using the usage reported by the client when available.
+func (p *Pipeline) generate(ctx context.Context, st *State, prompt string) (string, error) {
+	return p.charge(st, prompt, func() (string, llm.Usage, error) {
+		if c, ok := p.llm.(UsageLLMClient); ok {
+			return c.GenerateContentWithUsage(ctx, prompt)
+		}
+		resp, err := p.llm.GenerateContent(ctx, prompt)
+		return resp, llm.Usage{}, err
+	})
+}
+
+// generateJSON is generate for a response that must match schema. Clients
+// with a JSON mode are asked to constrain their output to the schema; others
+// get the plain prompt, which must describe the schema itself.
+func (p *Pipeline) generateJSON(ctx context.Context, st *State, prompt string, schema map[string]any) (string, error) {
+	c, ok := p.llm.(JSONLLMClient)
+	if !ok {
+		return p.generate(ctx, st, prompt)
+	}
+	return p.charge(st, prompt, func() (string, llm.Usage, error) {
+		return c.GenerateJSON(ctx, prompt, schema)
+	})
+}
+
+// Updates carrying report text while it is written. Chunks extend the text
+// streamed so far, a restart replaces it with the start of a new attempt, and
+// done carries the finished report with its references once the cite stage
+// ran. Done is only sent when something was streamed.
+const (
+	StatusReportChunk   = "report_chunk"
+	StatusReportRestart = "report_restart"
+	StatusReportDone    = "report_done"
+)
+
+// streamReport is generate for report text. With a streaming client every
+// chunk is forwarded as a report update while it is generated.
+func (p *Pipeline) streamReport(ctx context.Context, st *State, prompt string) (string, error) {
+	c, ok := p.llm.(StreamingLLMClient)
+	if !ok {
+		return p.generate(ctx, st, prompt)
+	}
+	status := StatusReportRestart
+	return p.charge(st, prompt, func() (string, llm.Usage, error) {
+		return c.GenerateContentStream(ctx, prompt, func(chunk string) {
+			st.reportStreamed = true
+			st.Update(status, chunk)
+			status = StatusReportChunk
+		})
+	})
+}
+
+// charge runs an LLM call and charges its usage to the running stage,
+// estimating it from the text length when the client reported none.
+func (p *Pipeline) charge(st *State, prompt string, call func() (string, llm.Usage, error)) (string, error) {
+	resp, usage, err := call()
+	estimated := usage.TotalTokens == 0
+	if estimated {
+		usage.PromptTokens = len(prompt) / charsPerToken
+		usage.CompletionTokens = len(resp) / charsPerToken
+	}
+	st.addUsage(usage.PromptTokens, usage.CompletionTokens, p.pricing.Cost(usage.PromptTokens, usage.CompletionTokens), estimated)
+	return resp, err
+}
+
+// generateQueries asks the LLM for search queries covering the topic, once
+// per search language and, in comparison mode, per subject. Each query is
+// searched in the language it was written for.
+func (p *Pipeline) generateQueries(ctx context.Context, st *State) error {
+	langs := st.Options.searchLanguages(st.TopicLanguage)
+	if len(langs) == 0 {
+		langs = []string{""}
+	}
+	// A comparison generates the same number of queries for every subject.
+	subjects := []string{""}
+	if st.Options.comparing() {
+		subjects = st.Options.Subjects
+	}
+	var all []string
+	for _, lang := range langs {
+		for _, subject := range subjects {
+			queries, err := p.queriesIn(ctx, st, lang, subject)
+			if err != nil {
+				return err
+			}
+			for _, q := range queries {
+				if _, dup := st.QueryLanguages[q]; dup || containsString(all, q) {
+					continue
+				}
+				all = append(all, q)
+				st.tagQuery(q, lang)
+			}
+		}
+	}
+	st.Queries = all
+	return nil
+}
+
+// queriesIn generates the topic's search queries in lang, or in the LLM's
+// choice of language when lang is empty. A non-empty subject restricts them
+// to one subject of a comparison.
+func (p *Pipeline) queriesIn(ctx context.Context, st *State, lang, subject string) ([]string, error) {
+	name, data := prompts.Queries, map[string]any{"NumQueries": st.Options.NumQueries, "Language": lang, "Topic": st.Topic}
+	if subject != "" {
+		name = prompts.CompareQueries
+		data["Subject"] = subject
+		data["Subjects"] = st.Options.Subjects
+		data["Criteria"] = st.Options.Criteria
+	}
+	queryPrompt, err := p.prompts.Render(name, data)
+	if err != nil {
+		return nil, stageFailure(fmt.Sprintf("An error occurred: %v", err), err)
+	}
+	rawQueries, err := p.generate(ctx, st, queryPrompt)
+	if err != nil {
+		return nil, stageFailure(fmt.Sprintf("An error occurred: %v", err), err)
+	}
+	queries := extractJSONStringArray(rawQueries)
+	if len(queries) == 0 {
+		// Without a JSON array, search for the topic itself.
+		queries = []string{st.Topic}
+		if subject != "" {
+			queries = []string{subject}
+		}
+	}
+	if n := st.Options.NumQueries; n > 0 && len(queries) > n {
+		queries = queries[:n]
+	}
+	return queries, nil
+}
+
+// runSearches runs the generated queries and records the first round.
+func (p *Pipeline) runSearches(ctx context.Context, st *State) error {
+	st.Sources = p.searchQueries(ctx, st, st.Queries)
+	st.Rounds = 1
+	return nil
+}
+
+// freshnessCutoff returns the earliest publication date the run's freshness
+// window admits, by the pipeline's clock, or the zero time without a window.
+func (p *Pipeline) freshnessCutoff(st *State) time.Time {
+	if st.Options.Freshness <= 0 {
+		return time.Time{}
+	}
+	return p.now().Add(-st.Options.Freshness)
+}
+
+// stale reports whether a page published at published falls before cutoff.
+// Pages with an unknown date, and any page without a cutoff, are not stale.
+func stale(published, cutoff time.Time) bool {
+	return !cutoff.IsZero() && !published.IsZero() && published.Before(cutoff)
+}
+
+// searchQueries runs every query in parallel, emitting a "searching" update
+// per query and a "search_cached" update per query answered from the search
+// cache, and returns one source per search hit in query and rank order, scored
+// by the credibility policy. With a freshness window, hits published before
+// it are dropped; the window is measured by the pipeline's clock. Failed
+// searches are logged and skipped.
+func (p *Pipeline) searchQueries(ctx context.Context, st *State, queries []string) []event.SearchSource {
+	cutoff := p.freshnessCutoff(st)
+	perQuery := make([][]event.SearchSource, len(queries))
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func(i int, q string) {
+			defer wg.Done()
+			st.Update("searching", q)
+			searchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+			items, err := p.search(searchCtx, q, st.Options.searchOptions(st.queryLanguage(q)))
+			if err != nil {
+				log.Printf("[PIPELINE] search failed for %q: %v", q, err)
+				return
+			}
+			if len(items) > 0 && items[0].Cached {
+				st.Update("search_cached", q)
+			}
+			for rank, r := range items {
+				url := strings.TrimSpace(r.URL)
+				if url == "" || !domainAllowed(url, st.Options.IncludeDomains, st.Options.ExcludeDomains) {
+					continue
+				}
+				if stale(r.Published, cutoff) {
+					continue
+				}
+				src := event.SearchSource{
+					Query:     q,
+					Queries:   []string{q},
+					URL:       url,
+					Title:     strings.TrimSpace(r.Title),
+					Snippet:   strings.TrimSpace(r.Content),
+					Rank:      rank + 1,
+					Provider:  r.Provider,
+					Cached:    r.Cached,
+					Published: r.Published,
+				}
+				src.Credibility = p.credibility.Score(src)
+				perQuery[i] = append(perQuery[i], src)
+			}
+		}(i, q)
+	}
+	wg.Wait()
+
+	var sources []event.SearchSource
+	for _, hits := range perQuery {
+		sources = append(sources, hits...)
+	}
+	return sources
+}
+
+// structureFindings converts the raw sources into StructuredResearch. Parse
+// failures fall back to a stub result built from the raw sources.
+func (p *Pipeline) structureFindings(ctx context.Context, st *State) error {
+	structured, err := p.structure(ctx, st, st.Sources)
+	if err != nil {
+		log.Printf("[PIPELINE] structuring failed: %v", err)
+		st.structuringFailed = true
+		structured = event.StructuredResearch{
+			SessionID:     st.SessionID,
+			Topic:         st.Topic,
+			Sources:       st.Sources,
+			OpenQuestions: []string{"Structured extraction failed; using raw sources."},
+		}
+	}
+
+	if strings.TrimSpace(structured.Error) != "" {
+		return stageFailure(fmt.Sprintf("Research blocked: %s", structured.Error), fmt.Errorf("structured error: %s", structured.Error))
+	}
+	st.Structured = structured
+	return nil
+}
+
+// structure asks the LLM to convert sources into StructuredResearch, showing
+// it each source's credibility, and weighs the confidence of the findings by
+// the credibility of their evidence.
+func (p *Pipeline) structure(ctx context.Context, st *State, sources []event.SearchSource) (event.StructuredResearch, error) {
+	type promptSource struct {
+		URL, Title, Query, Snippet, Content string
+		Credibility                         float64
+		Published                           string
+	}
+	promptSources := make([]promptSource, 0, len(sources))
+	for _, s := range sources {
+		query := s.Query
+		if len(s.Queries) > 0 {
+			query = strings.Join(s.Queries, "; ")
+		}
+		promptSources = append(promptSources, promptSource{s.URL, s.Title, query, s.Snippet, truncate(s.Content, promptContentChars), s.Credibility, publishedDate(s)})
+	}
+	structPrompt, err := p.prompts.Render(prompts.Structure, map[string]any{"Topic": st.Topic, "Sources": promptSources})
+	if err != nil {
+		return event.StructuredResearch{}, err
+	}
+
+	structured, err := p.generateStructured(ctx, st, structPrompt, sources)
+	if err != nil {
+		return event.StructuredResearch{}, err
+	}
+	structured.SessionID = st.SessionID
+	weighByCredibility(structured.KeyFindings, sources)
+	return structured, nil
+}
+
+// weighByCredibility scales the confidence of each finding by the most
+// credible of its evidence sources: evidence of neutral credibility leaves
+// it unchanged, more credible evidence raises it and less credible evidence
+// lowers it, within [0,1]. Findings without known evidence are left alone.
+func weighByCredibility(findings []event.StructuredFinding, sources []event.SearchSource) {
+	scores := make(map[string]float64, len(sources))
+	for _, s := range sources {
+		scores[s.URL] = max(scores[s.URL], s.Credibility)
+	}
+	for i := range findings {
+		best, found := 0.0, false
+		for _, u := range findings[i].EvidenceURLs {
+			if c, ok := scores[u]; ok {
+				best, found = max(best, c), true
+			}
+		}
+		if found {
+			factor := 1 + best - credibility.Neutral
+			findings[i].Confidence = min(1, findings[i].Confidence*factor)
+		}
+	}
+}
+
+// writeReport generates the report body from the structured findings. The
+// sources are numbered as in st.Sources, and the LLM is asked to cite them
+// inline by number. Detailed reports on enough findings are written outline
+// first, one section per LLM call; when that fails, and for other reports,
+// the report is written in a single call.
+func (p *Pipeline) writeReport(ctx context.Context, st *State) error {
+	structured := st.Structured
+	structured.KeyFindings = reportedFindings(structured.KeyFindings, st.Options.IncludeUnverified)
+	input := reportInput(structured, st.Sources)
+	table := ""
+	if st.Comparison != nil {
+		table = comparisonTable(st.Comparison, st.Sources)
+	}
+
+	report := ""
+	if st.Options.ReportFormat == ReportFormatDetailed && len(input.KeyFindings) >= minSectionedFindings {
+		var err error
+		report, err = p.writeSections(ctx, st, input, table)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("[PIPELINE] %s sectioned report failed, writing it in one pass: %v", st.SessionID, err)
+		}
+	}
+	if report == "" {
+		structuredJSON, _ := json.MarshalIndent(input, "", "  ")
+		reportPrompt, err := p.prompts.Render(prompts.Report, map[string]any{
+			"Format":     st.Options.ReportFormat,
+			"Language":   st.Language,
+			"Comparison": table,
+			"Structured": string(structuredJSON),
+			"Sources":    numberedSources(st.Sources),
+		})
+		if err != nil {
+			return stageFailure(fmt.Sprintf("generate report: %v", err), err)
+		}
+		report, err = p.streamReport(ctx, st, reportPrompt)
+		if err != nil {
+			return stageFailure(fmt.Sprintf("generate report: %v", err), err)
+		}
+	}
+	if table != "" {
+		report = strings.TrimRight(report, "\n") + "\n\n" + ComparisonHeading + "\n\n" + table
+	}
+	st.Report = report
+	return nil
+}
+
+// reportFinding is a key finding as the report prompt sees it, with its
+// evidence given as citation numbers.
+type reportFinding struct {
+	Finding    string  `json:"finding"`
+	Confidence float64 `json:"confidence"`
+	Sources    []int   `json:"sources,omitempty"`
+}
+
+// reportData is the structured research as the report prompts see it.
+type reportData struct {
+	Topic         string          `json:"topic"`
+	KeyFindings   []reportFinding `json:"key_findings"`
+	Challenges    []string        `json:"challenges,omitempty"`
+	OpenQuestions []string        `json:"open_questions,omitempty"`
+}
+
+// reportInput strips the structured research down to what the report needs,
+// replacing evidence URLs by citation numbers and leaving out the sources,
+// which are listed separately.
+func reportInput(sr event.StructuredResearch, sources []event.SearchSource) reportData {
+	nums := sourceNumbers(sources)
+	findings := make([]reportFinding, 0, len(sr.KeyFindings))
+	for _, f := range sr.KeyFindings {
+		rf := reportFinding{Finding: f.Finding, Confidence: f.Confidence}
+		for _, u := range f.EvidenceURLs {
+			if n, ok := nums[u]; ok {
+				rf.Sources = append(rf.Sources, n)
+			}
+		}
+		findings = append(findings, rf)
+	}
+	return reportData{sr.Topic, findings, sr.Challenges, sr.OpenQuestions}
+}
+
+// writeSummary produces a short executive summary of the report. Generation
+// errors degrade to a placeholder rather than failing the run.
+func (p *Pipeline) writeSummary(ctx context.Context, st *State) error {
+	summaryPrompt, err := p.prompts.Render(prompts.Summary, map[string]any{"Language": st.Language, "Report": st.Report})
+	if err != nil {
+		return err
+	}
+	summary, err := p.generate(ctx, st, summaryPrompt)
+	if err != nil {
+		summary = "Executive summary unavailable due to generation error."
+	}
+	st.Summary = summary
+	return nil
+}
+
+// persist writes the report blobs and structured rows and marks the session
+// complete. Individual persistence errors are logged, not returned.
+func (p *Pipeline) persist(ctx context.Context, st *State) error {
+	bundle := artifacts.Bundle{
+		Topic:      st.Topic,
+		Summary:    st.Summary,
+		Report:     artifacts.ReportTitle + st.Report,
+		Sources:    st.Sources,
+		Structured: st.Structured,
+		Citations:  st.Citations,
+		Comparison: st.Comparison,
+
+		RemovedCitations: st.RemovedCitations,
+		PromptVersions:   st.PromptVersions,
+		Language:         st.Language,
+	}
+
+	reportMDKey, err := p.blobs.SaveBlob("report", []byte(bundle.Report), "md")
+	if err != nil {
+		log.Printf("[PIPELINE] save report.md failed: %v", err)
+	}
+	st.ReportMDKey = reportMDKey
+
+	bundle.Renders = p.renderReport(st, bundle)
+
+	bundleBytes, _ := json.MarshalIndent(bundle, "", "  ")
+	reportJSONKey, err := p.blobs.SaveBlob("report", bundleBytes, "json")
+	if err != nil {
+		log.Printf("[PIPELINE] save report.json failed: %v", err)
+	}
+	st.ReportJSONKey = reportJSONKey
+	if err := ctx.Err(); err != nil {
+		// Canceled while writing blobs: the runner removes them.
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var dbErrors []string
+	var dbMu sync.Mutex
+	addDbErr := func(op string, err error) {
+		if err != nil {
+			dbMu.Lock()
+			dbErrors = append(dbErrors, fmt.Sprintf("%s: %v", op, err))
+			dbMu.Unlock()
+		}
+	}
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		addDbErr("SaveFindings", p.db.SaveFindings(st.SessionID, st.Structured.KeyFindings))
+	}()
+	go func() {
+		defer wg.Done()
+		addDbErr("SaveOpenQuestions", p.db.SaveOpenQuestions(st.SessionID, st.Structured.OpenQuestions))
+	}()
+	go func() {
+		defer wg.Done()
+		addDbErr("SaveSources", p.db.SaveSources(st.SessionID, st.Sources))
+	}()
+	wg.Wait()
+
+	if len(st.Renders) > 0 {
+		addDbErr("SaveRenders", p.db.SaveRenders(st.SessionID, st.Renders))
+	}
+	if err := p.db.MarkSessionComplete(st.SessionID, reportMDKey, reportJSONKey, st.Summary); err != nil {
+		addDbErr("MarkSessionComplete", err)
+	}
+
+	if len(dbErrors) > 0 {
+		log.Printf("[PIPELINE] %s DB persistence errors: %s", st.SessionID, strings.Join(dbErrors, "; "))
+		// We still consider it a "complete" status for the user if artifacts are safe,
+		// but the Q&A might be degraded.
+	}
+	return nil
+}
+
+// renderReport saves the report in each format requested by the run's
+// Renderers option and returns the blob keys by renderer name. Markdown is
+// always saved by persist itself. A failing renderer is logged and skipped.
+func (p *Pipeline) renderReport(st *State, bundle artifacts.Bundle) map[string]string {
+	for _, name := range st.Options.Renderers {
+		if name == (render.Markdown{}).Name() {
+			continue
+		}
+		r, ok := render.Lookup(name)
+		if !ok {
+			log.Printf("[PIPELINE] %s unknown renderer %q", st.SessionID, name)
+			continue
+		}
+		data, err := r.Render(bundle)
+		if err != nil {
+			log.Printf("[PIPELINE] %s render %s failed: %v", st.SessionID, name, err)
+			continue
+		}
+		key, err := p.blobs.SaveBlob("report", data, r.Extension())
+		if err != nil {
+			log.Printf("[PIPELINE] %s save report.%s failed: %v", st.SessionID, r.Extension(), err)
+			continue
+		}
+		if st.Renders == nil {
+			st.Renders = make(map[string]string)
+		}
+		st.Renders[name] = key
+	}
+	return st.Renders
+}
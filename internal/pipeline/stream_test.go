@@ -0,0 +1,74 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// streamingLLM is a routeLLM that streams its answers word by word.
+type streamingLLM struct {
+	routeLLM
+	streamed []string
+}
+
+func (m *streamingLLM) GenerateContentStream(ctx context.Context, prompt string, onChunk func(string)) (string, llm.Usage, error) {
+	text, err := m.GenerateContent(ctx, prompt)
+	m.mu.Lock()
+	m.streamed = append(m.streamed, prompt)
+	m.mu.Unlock()
+	for _, word := range strings.SplitAfter(text, " ") {
+		onChunk(word)
+	}
+	return text, llm.Usage{}, err
+}
+
+// TestPipeline_StreamsReport verifies that report text is forwarded chunk by
+// chunk while it is written, and replaced by the cited report at the end.
+func TestPipeline_StreamsReport(t *testing.T) {
+	lm := &streamingLLM{routeLLM: routeLLM{routes: sectionRoutes(
+		func(string) string { return "" },
+		func(draft string) string { return draft },
+	)}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com", Title: "A"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &recordingBlob{})
+
+	var statuses []string
+	var streamed strings.Builder
+	final := ""
+	_, err := p.RunWithOptions(context.Background(), "s1", "Topic", pipeline.Options{IncludeUnverified: true}, func(status, detail string) {
+		switch status {
+		case pipeline.StatusReportRestart:
+			streamed.Reset()
+			streamed.WriteString(detail)
+		case pipeline.StatusReportChunk:
+			streamed.WriteString(detail)
+		case pipeline.StatusReportDone:
+			final = detail
+		default:
+			return
+		}
+		statuses = append(statuses, status)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(lm.streamed) != 1 || !strings.Contains(lm.streamed[0], "Write a comprehensive report") {
+		t.Fatalf("expected only the report prompt to be streamed, got %d prompts", len(lm.streamed))
+	}
+	want := []string{pipeline.StatusReportRestart, pipeline.StatusReportChunk, pipeline.StatusReportChunk, pipeline.StatusReportChunk, pipeline.StatusReportDone}
+	if strings.Join(statuses, ",") != strings.Join(want, ",") {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if got := streamed.String(); got != "Single pass report [1]." {
+		t.Errorf("streamed text = %q", got)
+	}
+	if !strings.HasPrefix(final, "Single pass report [1].") || !strings.Contains(final, artifacts.ReferencesHeading) {
+		t.Errorf("expected the cited report when done, got %q", final)
+	}
+}
@@ -0,0 +1,95 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/prompts"
+)
+
+// maxStructureRepairs bounds the re-prompts sent when structured output fails
+// to parse or validate.
+const maxStructureRepairs = 2
+
+// StructuredResearchSchema returns the JSON schema the structure stage asks the
+// LLM to follow. Confidence is not range-checked; out-of-range scores are
+// clamped by ValidateStructuredResearch instead of costing a repair.
+func StructuredResearchSchema() map[string]any {
+	strs := map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"topic"},
+		"properties": map[string]any{
+			"topic": map[string]any{"type": "string"},
+			"key_findings": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type":     "object",
+					"required": []string{"finding", "confidence"},
+					"properties": map[string]any{
+						"finding":       map[string]any{"type": "string"},
+						"evidence_urls": strs,
+						"confidence":    map[string]any{"type": "number"},
+					},
+				},
+			},
+			"challenges":     strs,
+			"open_questions": strs,
+			"error":          map[string]any{"type": "string"},
+		},
+	}
+}
+
+// structuredProblems lists why raw is not a valid structured research
+// response, or returns nil when it is.
+func structuredProblems(raw string) []string {
+	obj, err := extractJSONObject(raw)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var value any
+	if err := json.Unmarshal([]byte(obj), &value); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	return ValidateSchema(StructuredResearchSchema(), value)
+}
+
+// generateStructured sends prompt through the LLM's JSON mode and parses the
+// response. Output that fails to parse or validate is sent back with the
+// problems found, up to maxStructureRepairs times; each re-prompt is counted
+// in st.StructureRepairs.
+func (p *Pipeline) generateStructured(ctx context.Context, st *State, prompt string, sources []event.SearchSource) (event.StructuredResearch, error) {
+	schema := StructuredResearchSchema()
+	next := prompt
+	for attempt := 0; ; attempt++ {
+		raw, err := p.generateJSON(ctx, st, next, schema)
+		if err != nil {
+			return event.StructuredResearch{}, err
+		}
+		problems := structuredProblems(raw)
+		if len(problems) == 0 {
+			structured, err := ParseStructuredResearchWithSources(raw, sources)
+			if err == nil {
+				return structured, nil
+			}
+			problems = []string{err.Error()}
+		}
+		if attempt == maxStructureRepairs {
+			return event.StructuredResearch{}, fmt.Errorf("JSON parse failed after %d repairs: %s", attempt, strings.Join(problems, "; "))
+		}
+		st.StructureRepairs++
+		log.Printf("[PIPELINE] %s structured output invalid (%d problems), repair %d/%d", st.SessionID, len(problems), attempt+1, maxStructureRepairs)
+		next, err = p.prompts.Render(prompts.Repair, map[string]any{
+			"Prompt":   prompt,
+			"Previous": truncate(raw, promptContentChars),
+			"Problems": problems,
+		})
+		if err != nil {
+			return event.StructuredResearch{}, err
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// jsonLLM is a mockLLM with a JSON mode; it records the schemas it was given.
+type jsonLLM struct {
+	mockLLM
+	schemas []map[string]any
+}
+
+func (m *jsonLLM) GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, llm.Usage, error) {
+	m.mu.Lock()
+	m.schemas = append(m.schemas, schema)
+	m.mu.Unlock()
+	resp, err := m.GenerateContent(ctx, prompt)
+	return resp, llm.Usage{}, err
+}
+
+// TestPipeline_StructureRepair verifies that output failing schema validation
+// is sent back with the validation errors and that the repair is counted.
+func TestPipeline_StructureRepair(t *testing.T) {
+	lm := &jsonLLM{mockLLM: mockLLM{responses: []string{
+		`["query1"]`,
+		`{"topic": "T", "key_findings": [{"finding": 42}]}`,
+		`{"topic": "T", "key_findings": [{"finding": "Go is fast", "confidence": 0.9, "evidence_urls": ["http://a.com"]}]}`,
+		"Report",
+		"Summary",
+	}}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "Go is fast", URL: "http://a.com"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+
+	res, err := p.RunWithOptions(context.Background(), "s1", "Test Topic", pipeline.Options{IncludeUnverified: true}, func(string, string) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.StructureRepairs != 1 {
+		t.Errorf("expected 1 structure repair, got %d", res.StructureRepairs)
+	}
+	if len(lm.schemas) != 2 || lm.schemas[0]["type"] != "object" {
+		t.Errorf("expected both structuring calls to use JSON mode, got %d schemas", len(lm.schemas))
+	}
+	repair := lm.prompts[2]
+	for _, want := range []string{"did not match the schema", `$.key_findings[0].finding: expected string`, `missing required field "confidence"`} {
+		if !strings.Contains(repair, want) {
+			t.Errorf("repair prompt missing %q:\n%s", want, repair)
+		}
+	}
+	if !strings.Contains(lm.prompts[3], "Go is fast") {
+		t.Error("expected the repaired findings to reach the report prompt")
+	}
+}
+
+// TestPipeline_StructureRepairBounded verifies that the repair loop gives up
+// after a bounded number of attempts and falls back to the raw sources.
+func TestPipeline_StructureRepairBounded(t *testing.T) {
+	lm := &mockLLM{responses: []string{`["query1"]`, "not json", "still not json", `{"topic": 7}`, "Report", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+
+	res, err := p.RunWithUpdates(context.Background(), "s1", "Test Topic", func(string, string) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.StructureRepairs != 2 {
+		t.Errorf("expected 2 structure repairs, got %d", res.StructureRepairs)
+	}
+	if !strings.Contains(lm.prompts[4], "Structured extraction failed") {
+		t.Errorf("expected the report to fall back to raw sources, got prompt:\n%s", lm.prompts[4])
+	}
+}
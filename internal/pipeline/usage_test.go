@@ -0,0 +1,94 @@
+package pipeline_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/llm"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+// usageLLM is a mockLLM that reports provider token counts for each call.
+type usageLLM struct {
+	mockLLM
+	usage llm.Usage
+}
+
+func (m *usageLLM) GenerateContentWithUsage(ctx context.Context, prompt string) (string, llm.Usage, error) {
+	resp, err := m.GenerateContent(ctx, prompt)
+	return resp, m.usage, err
+}
+
+// usageDB records the token usage saved for each session.
+type usageDB struct {
+	mockDB
+	mu    sync.Mutex
+	saved map[string][]event.TokenUsage
+}
+
+func (m *usageDB) SaveTokenUsage(id string, usage []event.TokenUsage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.saved == nil {
+		m.saved = map[string][]event.TokenUsage{}
+	}
+	m.saved[id] = usage
+	return nil
+}
+
+// TestPipeline_UsagePerStage verifies that provider-reported token counts are
+// charged to the stage that made the call, priced, returned and stored.
+func TestPipeline_UsagePerStage(t *testing.T) {
+	lm := &usageLLM{
+		mockLLM: mockLLM{responses: []string{`["query1"]`, `{"topic":"T"}`, "Report", "Summary"}},
+		usage:   llm.Usage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100},
+	}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com"}}, errIdx: -1}
+	db := &usageDB{}
+	p := pipeline.New(lm, ms.search, db, &mockBlob{})
+	p.SetPricing(pipeline.Pricing{InputPerMTok: 1, OutputPerMTok: 10})
+
+	res, err := p.RunWithUpdates(context.Background(), "s1", "Test Topic", func(string, string) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{pipeline.StageQueries, pipeline.StageStructure, pipeline.StageReport, pipeline.StageSummary}
+	if len(res.Usage) != len(want) {
+		t.Fatalf("expected usage for %v, got %+v", want, res.Usage)
+	}
+	for i, u := range res.Usage {
+		if u.Stage != want[i] || u.Calls != 1 || u.TotalTokens != 1100 || u.Estimated {
+			t.Errorf("unexpected usage for stage %d: %+v", i, u)
+		}
+		if !approx(u.CostUSD, 0.002) {
+			t.Errorf("expected cost 0.002 for %s, got %v", u.Stage, u.CostUSD)
+		}
+	}
+	if got := db.saved["s1"]; len(got) != len(want) {
+		t.Errorf("expected stored usage for %d stages, got %+v", len(want), got)
+	}
+}
+
+// TestPipeline_UsageEstimated verifies that calls through a client that does
+// not report usage are estimated from the prompt and response lengths.
+func TestPipeline_UsageEstimated(t *testing.T) {
+	lm := &mockLLM{responses: []string{`["query1"]`, `{"topic":"T"}`, "Report", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet", URL: "http://a.com"}}, errIdx: -1}
+	p := pipeline.New(lm, ms.search, &mockDB{}, &mockBlob{})
+
+	res, err := p.RunWithUpdates(context.Background(), "s1", "Test Topic", func(string, string) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Usage) == 0 {
+		t.Fatal("expected estimated usage")
+	}
+	for _, u := range res.Usage {
+		if !u.Estimated || u.PromptTokens == 0 || u.TotalTokens != u.PromptTokens+u.CompletionTokens {
+			t.Errorf("unexpected estimated usage: %+v", u)
+		}
+	}
+}
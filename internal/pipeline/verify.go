@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/prompts"
+)
+
+// Verdicts assigned to findings by the verify stage.
+const (
+	VerdictSupported    = "supported"
+	VerdictContradicted = "contradicted"
+	VerdictUnsupported  = "unsupported"
+)
+
+const (
+	// evidenceChars caps the evidence text per source in the verify prompt.
+	evidenceChars = 1500
+	// unsupportedConfidenceCap bounds the confidence of unsupported findings.
+	unsupportedConfidenceCap = 0.3
+)
+
+// verification is the LLM's judgement of a single finding.
+type verification struct {
+	Index    int     `json:"index"`
+	Verdict  string  `json:"verdict"`
+	Strength float64 `json:"strength"`
+	Reason   string  `json:"reason"`
+}
+
+// verifyFindings checks every key finding against the text of its evidence
+// sources and recalibrates its confidence. Findings without usable evidence
+// are marked unsupported without asking the LLM. When the LLM call fails,
+// the findings are left unverified.
+func (p *Pipeline) verifyFindings(ctx context.Context, st *State) error {
+	if st.structuringFailed || len(st.Structured.KeyFindings) == 0 {
+		return nil
+	}
+	bySource := make(map[string]event.SearchSource, len(st.Sources))
+	for _, s := range st.Sources {
+		bySource[s.URL] = s
+	}
+
+	findings := append([]event.StructuredFinding(nil), st.Structured.KeyFindings...)
+	var sb strings.Builder
+	var pending int
+	for i := range findings {
+		evidence := evidenceText(findings[i], bySource)
+		if evidence == "" {
+			applyVerdict(&findings[i], verification{Verdict: VerdictUnsupported, Reason: "No evidence source text was available."})
+			continue
+		}
+		pending++
+		sb.WriteString(fmt.Sprintf("Finding %d: %s\nEvidence:\n%s\n", i, findings[i].Finding, evidence))
+	}
+
+	if pending > 0 {
+		verdicts, err := p.judgeFindings(ctx, st, sb.String())
+		if err != nil {
+			log.Printf("[PIPELINE] %s verification failed, findings left unverified: %v", st.SessionID, err)
+			return nil
+		}
+		for _, v := range verdicts {
+			if v.Index < 0 || v.Index >= len(findings) || findings[v.Index].Verdict != "" {
+				continue
+			}
+			applyVerdict(&findings[v.Index], v)
+		}
+	}
+	st.Structured.KeyFindings = findings
+	return nil
+}
+
+// judgeFindings asks the LLM for a verdict on each finding in the listing.
+func (p *Pipeline) judgeFindings(ctx context.Context, st *State, listing string) ([]verification, error) {
+	prompt, err := p.prompts.Render(prompts.Verify, map[string]any{"Findings": listing})
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := p.generate(ctx, st, prompt)
+	if err != nil {
+		return nil, err
+	}
+	start, end := strings.Index(raw, "["), strings.LastIndex(raw, "]")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON array in verifier output")
+	}
+	var verdicts []verification
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &verdicts); err != nil {
+		return nil, err
+	}
+	return verdicts, nil
+}
+
+// applyVerdict records v on f and recalibrates its confidence: supported
+// findings average their confidence with the evidence strength, unsupported
+// findings are capped at unsupportedConfidenceCap, and contradicted findings
+// drop to zero. Unknown verdicts count as unsupported.
+func applyVerdict(f *event.StructuredFinding, v verification) {
+	verdict := strings.ToLower(strings.TrimSpace(v.Verdict))
+	switch verdict {
+	case VerdictSupported:
+		strength := v.Strength
+		if strength <= 0 || strength > 1 {
+			strength = 1
+		}
+		f.Confidence = (f.Confidence + strength) / 2
+	case VerdictContradicted:
+		f.Confidence = 0
+	default:
+		verdict = VerdictUnsupported
+		if f.Confidence > unsupportedConfidenceCap {
+			f.Confidence = unsupportedConfidenceCap
+		}
+	}
+	f.Verdict = verdict
+	f.VerdictReason = strings.TrimSpace(v.Reason)
+}
+
+// evidenceText joins the fetched text, or the snippet, of each evidence source.
+func evidenceText(f event.StructuredFinding, bySource map[string]event.SearchSource) string {
+	var sb strings.Builder
+	for _, u := range f.EvidenceURLs {
+		s, ok := bySource[u]
+		if !ok {
+			continue
+		}
+		text := s.Content
+		if text == "" {
+			text = s.Snippet
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", u, truncate(text, evidenceChars)))
+		}
+	}
+	return sb.String()
+}
+
+// reportedFindings returns the findings the report may use: with
+// includeUnverified false, findings the verify stage did not mark supported
+// are left out. Findings that were never verified are always kept.
+func reportedFindings(findings []event.StructuredFinding, includeUnverified bool) []event.StructuredFinding {
+	if includeUnverified {
+		return findings
+	}
+	var out []event.StructuredFinding
+	for _, f := range findings {
+		if f.Verdict == "" || f.Verdict == VerdictSupported {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package pipeline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/user/research-assistant/internal/pipeline"
+)
+
+const verifyStructured = `{"topic":"T","key_findings":[
+	{"finding":"Backed","evidence_urls":["http://a.com"],"confidence":0.7},
+	{"finding":"Refuted","evidence_urls":["http://a.com"],"confidence":0.8},
+	{"finding":"Orphan","evidence_urls":[],"confidence":0.9}]}`
+
+// runVerify runs the pipeline with a verify response for the first two
+// findings of verifyStructured; the third has no evidence and is never sent
+// to the verifier.
+func runVerify(t *testing.T, verdicts string, override pipeline.Options) (*mockLLM, *recordingBlob) {
+	t.Helper()
+	lm := &mockLLM{responses: []string{`["query1"]`, verifyStructured, verdicts, "Report", "Summary"}}
+	ms := &mockSearcher{results: []pipeline.SearchResult{{Content: "snippet a", URL: "http://a.com"}}, errIdx: -1}
+	blobs := &recordingBlob{}
+	p := pipeline.New(lm, ms.search, &mockDB{}, blobs)
+	if _, err := p.RunWithOptions(context.Background(), uuid.New().String(), "Test Topic", override, func(string, string) {}); err != nil {
+		t.Fatalf("RunWithOptions: %v", err)
+	}
+	return lm, blobs
+}
+
+// TestPipeline_VerifyRecalibratesConfidence verifies that verdicts adjust
+// confidence and that only supported findings reach the report prompt.
+func TestPipeline_VerifyRecalibratesConfidence(t *testing.T) {
+	lm, blobs := runVerify(t, `[{"index":0,"verdict":"supported","strength":0.9,"reason":"a.com says so"},
+		{"index":1,"verdict":"contradicted","strength":0,"reason":"a.com says otherwise"}]`, pipeline.Options{})
+
+	verifyPrompt := lm.prompts[2]
+	if !strings.Contains(verifyPrompt, "Finding 0: Backed") || strings.Contains(verifyPrompt, "Orphan") {
+		t.Errorf("unexpected verify prompt:\n%s", verifyPrompt)
+	}
+
+	got := blobs.bundle(t).Structured.KeyFindings
+	if len(got) != 3 {
+		t.Fatalf("expected 3 findings in report.json, got %d", len(got))
+	}
+	want := []struct {
+		verdict    string
+		confidence float64
+	}{
+		{pipeline.VerdictSupported, 0.8},
+		{pipeline.VerdictContradicted, 0},
+		{pipeline.VerdictUnsupported, 0.3},
+	}
+	for i, w := range want {
+		if got[i].Verdict != w.verdict || !approx(got[i].Confidence, w.confidence) {
+			t.Errorf("finding %d: want %s/%.2f, got %s/%.2f", i, w.verdict, w.confidence, got[i].Verdict, got[i].Confidence)
+		}
+	}
+	if got[0].VerdictReason != "a.com says so" {
+		t.Errorf("verdict reason not recorded: %q", got[0].VerdictReason)
+	}
+
+	reportPrompt := lm.prompts[3]
+	if !strings.Contains(reportPrompt, "Backed") || strings.Contains(reportPrompt, "Refuted") || strings.Contains(reportPrompt, "Orphan") {
+		t.Errorf("report prompt should only use supported findings:\n%s", reportPrompt)
+	}
+}
+
+// TestPipeline_VerifyIncludeUnverified verifies that include_unverified keeps
+// every finding in the report prompt.
+func TestPipeline_VerifyIncludeUnverified(t *testing.T) {
+	lm, _ := runVerify(t, `[{"index":0,"verdict":"supported"},{"index":1,"verdict":"unsupported"}]`,
+		pipeline.Options{IncludeUnverified: true})
+
+	reportPrompt := lm.prompts[3]
+	for _, f := range []string{"Backed", "Refuted", "Orphan"} {
+		if !strings.Contains(reportPrompt, f) {
+			t.Errorf("report prompt missing %q:\n%s", f, reportPrompt)
+		}
+	}
+}
+
+// TestPipeline_VerifyUnparseableVerdicts verifies that a verifier reply that
+// is not JSON leaves the findings unverified and the run succeeds.
+func TestPipeline_VerifyUnparseableVerdicts(t *testing.T) {
+	lm, blobs := runVerify(t, "I cannot judge these.", pipeline.Options{})
+
+	for _, f := range blobs.bundle(t).Structured.KeyFindings {
+		if f.Verdict != "" {
+			t.Errorf("expected %q to stay unverified, got %q", f.Finding, f.Verdict)
+		}
+	}
+	if !strings.Contains(lm.prompts[3], "Refuted") {
+		t.Errorf("unverified findings should reach the report prompt:\n%s", lm.prompts[3])
+	}
+}
+
+func approx(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
@@ -0,0 +1,222 @@
+// Package prompts holds the LLM prompt templates used by the research
+// pipeline and the Concierge. Templates are text/template files embedded in
+// the binary; a directory of same-named files can override any of them.
+//
+// Each template declares its version in a leading comment:
+//
+//	{{- /* version: v2 */ -}}
+//
+// Templates without one are versioned by a hash of their content, so that
+// every rendered prompt can be traced back to the exact template text.
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Names of the built-in templates.
+const (
+	Queries        = "queries"
+	CompareQueries = "compare_queries"
+	FollowUp       = "follow_up"
+	Structure      = "structure"
+	Repair         = "repair"
+	Verify         = "verify"
+	Compare        = "compare"
+	Moderate       = "moderate"
+	Outline        = "outline"
+	Section        = "section"
+	Polish         = "polish"
+	Report         = "report"
+	Summary        = "summary"
+	QA             = "qa"
+)
+
+const ext = ".tmpl"
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+var versionRe = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\S+)\s*\*/\s*-?\}\}`)
+
+// Template is a parsed prompt template and its version.
+type Template struct {
+	Name    string
+	Version string
+	text    string
+	tmpl    *template.Template
+}
+
+// Registry maps template names to templates. It is read-only once built and
+// safe for concurrent use.
+type Registry struct {
+	templates map[string]*Template
+}
+
+// Default returns the registry of embedded templates.
+func Default() *Registry {
+	r, err := load(nil)
+	if err != nil {
+		panic(fmt.Sprintf("prompts: embedded templates: %v", err))
+	}
+	return r
+}
+
+// Load returns the embedded templates with every *.tmpl file in dir replacing
+// the embedded template of the same name. Files that match no embedded
+// template are added as new templates.
+func Load(dir string) (*Registry, error) {
+	return load(os.DirFS(dir))
+}
+
+func load(overrides fs.FS) (*Registry, error) {
+	r := &Registry{templates: make(map[string]*Template)}
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.addDir(sub); err != nil {
+		return nil, err
+	}
+	if overrides != nil {
+		if err := r.addDir(overrides); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Registry) addDir(fsys fs.FS) error {
+	paths, err := fs.Glob(fsys, "*"+ext)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return fmt.Errorf("read prompt %s: %w", path, err)
+		}
+		t, err := Parse(strings.TrimSuffix(filepath.Base(path), ext), string(data))
+		if err != nil {
+			return err
+		}
+		r.templates[t.Name] = t
+	}
+	return nil
+}
+
+// Parse parses text as the template called name.
+func Parse(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt %s: %w", name, err)
+	}
+	version := ""
+	if m := versionRe.FindStringSubmatch(text); m != nil {
+		version = m[1]
+	} else {
+		sum := sha256.Sum256([]byte(text))
+		version = "sha-" + hex.EncodeToString(sum[:6])
+	}
+	return &Template{Name: name, Version: version, text: text, tmpl: tmpl}, nil
+}
+
+// FromTexts returns the embedded templates with the given template texts,
+// keyed by name, replacing the embedded template of the same name. Names that
+// match no embedded template are added as new templates.
+func FromTexts(texts map[string]string) (*Registry, error) {
+	r, err := load(nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, text := range texts {
+		t, err := Parse(name, text)
+		if err != nil {
+			return nil, err
+		}
+		r.templates[name] = t
+	}
+	return r, nil
+}
+
+// ID identifies the template and its version, e.g. "report@v1".
+func (t *Template) ID() string {
+	return t.Name + "@" + t.Version
+}
+
+// Render executes the template with data. Leading and trailing whitespace is
+// trimmed so that templates may end with a newline.
+func (t *Template) Render(data any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render prompt %s: %w", t.ID(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Get returns the template called name.
+func (r *Registry) Get(name string) (*Template, bool) {
+	t, ok := r.templates[name]
+	return t, ok
+}
+
+// Render executes the template called name with data.
+func (r *Registry) Render(name string, data any) (string, error) {
+	t, ok := r.templates[name]
+	if !ok {
+		return "", fmt.Errorf("prompt %q not found", name)
+	}
+	return t.Render(data)
+}
+
+// Versions returns the version of each named template, or of every template
+// when no names are given. Unknown names are left out.
+func (r *Registry) Versions(names ...string) map[string]string {
+	if len(names) == 0 {
+		names = r.Names()
+	}
+	out := make(map[string]string, len(names))
+	for _, name := range names {
+		if t, ok := r.templates[name]; ok {
+			out[name] = t.Version
+		}
+	}
+	return out
+}
+
+// Texts returns the text of each named template, or of every template when
+// no names are given, so that the registry can be rebuilt by FromTexts.
+// Unknown names are left out.
+func (r *Registry) Texts(names ...string) map[string]string {
+	if len(names) == 0 {
+		names = r.Names()
+	}
+	out := make(map[string]string, len(names))
+	for _, name := range names {
+		if t, ok := r.templates[name]; ok {
+			out[name] = t.text
+		}
+	}
+	return out
+}
+
+// Names returns the names of the registered templates in sorted order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
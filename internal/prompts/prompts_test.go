@@ -0,0 +1,77 @@
+package prompts_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/user/research-assistant/internal/prompts"
+)
+
+func TestDefault_RendersEveryTemplate(t *testing.T) {
+	r := prompts.Default()
+	data := map[string]any{
+		"NumQueries": 3, "MaxQueries": 3, "Topic": "Go", "Gaps": []string{"gap"},
+		"Sources": []map[string]any{{"URL": "http://a.com", "Title": "A", "Query": "go", "Snippet": "s", "Content": "", "Credibility": 0.5, "Published": "2024-03-05"}}, "Prompt": "p", "Previous": "{}",
+		"Problems": []string{"x"}, "Findings": []map[string]any{}, "Format": "brief", "Language": "",
+		"Structured": "{}", "Report": "r", "Question": "q",
+		"Subject": "Go", "Subjects": []string{"Go", "Rust"}, "Criteria": []string{"speed"}, "NumCriteria": 5, "Comparison": "",
+		"MinSections": 2, "MaxSections": 6, "Challenges": []string{"c"}, "OpenQuestions": []string{"o"}, "Title": "t", "Outline": "1. t", "Draft": "d",
+		"Categories": []map[string]string{{"Name": "weapons", "Description": "d"}},
+	}
+	want := []string{prompts.Compare, prompts.CompareQueries, prompts.FollowUp, prompts.Moderate, prompts.Outline, prompts.Polish, prompts.QA, prompts.Queries, prompts.Repair, prompts.Report, prompts.Section, prompts.Structure, prompts.Summary, prompts.Verify}
+	if got := r.Names(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected templates %v, got %v", want, got)
+	}
+	for _, name := range want {
+		out, err := r.Render(name, data)
+		if err != nil {
+			t.Errorf("render %s: %v", name, err)
+			continue
+		}
+		if out == "" || strings.Contains(out, "version:") {
+			t.Errorf("unexpected %s output: %q", name, out)
+		}
+		if v := r.Versions(name)[name]; !strings.HasPrefix(v, "v") {
+			t.Errorf("expected a declared version for %s, got %q", name, v)
+		}
+	}
+}
+
+func TestLoad_OverridesFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "summary.tmpl"), []byte("{{- /* version: v2-short */ -}}\nSummarise: {{.Report}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "qa.tmpl"), []byte("Answer {{.Question}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := prompts.Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	out, err := r.Render(prompts.Summary, map[string]any{"Report": "text"})
+	if err != nil || out != "Summarise: text" {
+		t.Errorf("expected overridden summary, got %q, %v", out, err)
+	}
+	versions := r.Versions(prompts.Summary, prompts.QA, prompts.Report)
+	embedded := prompts.Default().Versions(prompts.Report)
+	if versions[prompts.Summary] != "v2-short" || versions[prompts.Report] != embedded[prompts.Report] {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+	if !strings.HasPrefix(versions[prompts.QA], "sha-") {
+		t.Errorf("expected content hash version for unversioned template, got %q", versions[prompts.QA])
+	}
+}
+
+func TestLoad_InvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.tmpl"), []byte("{{.Broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prompts.Load(dir); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
@@ -0,0 +1,148 @@
+package render
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"html/template"
+	"io"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/user/research-assistant/internal/artifacts"
+)
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+var packageTemplate = texttemplate.Must(texttemplate.New("opf").Funcs(texttemplate.FuncMap{"xml": xmlEscape}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="id" xml:lang="{{xml .Language}}">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="id">{{xml .Identifier}}</dc:identifier>
+    <dc:title>{{xml .Title}}</dc:title>
+    <dc:language>{{xml .Language}}</dc:language>
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="report" href="report.xhtml" media-type="application/xhtml+xml"/>
+    <item id="style" href="style.css" media-type="text/css"/>
+  </manifest>
+  <spine>
+    <itemref idref="report"/>
+  </spine>
+</package>
+`))
+
+func init() {
+	template.Must(templates.New("chapter").Parse(`<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="{{.Language}}" lang="{{.Language}}">
+<head>
+<title>{{.Title}}</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+{{template "content" .}}
+</body>
+</html>
+`))
+	template.Must(templates.New("nav").Parse(`<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="{{.Language}}" lang="{{.Language}}">
+<head><title>{{.Title}}</title></head>
+<body>
+<nav epub:type="toc" id="toc">
+<ol><li><a href="report.xhtml">{{.Title}}</a></li></ol>
+</nav>
+</body>
+</html>
+`))
+}
+
+// EPUB renders an EPUB 3 book with the same content as the HTML page.
+type EPUB struct {
+	// Modified is recorded as the book's modification time; zero means now.
+	Modified time.Time
+}
+
+func (EPUB) Name() string      { return "epub" }
+func (EPUB) Extension() string { return "epub" }
+
+func (e EPUB) Render(b artifacts.Bundle) ([]byte, error) {
+	doc := newDocument(b)
+	modified := e.Modified
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+
+	var chapter, nav, opf bytes.Buffer
+	if err := templates.ExecuteTemplate(&chapter, "chapter", doc); err != nil {
+		return nil, err
+	}
+	if err := templates.ExecuteTemplate(&nav, "nav", doc); err != nil {
+		return nil, err
+	}
+	if err := packageTemplate.Execute(&opf, map[string]string{
+		"Identifier": bookIdentifier(b),
+		"Title":      doc.Title,
+		"Language":   doc.Language,
+		"Modified":   modified.UTC().Format("2006-01-02T15:04:05Z"),
+	}); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	// The mimetype entry must come first and be stored uncompressed.
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, "application/epub+zip"); err != nil {
+		return nil, err
+	}
+	const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+	files := []struct{ name, content string }{
+		{"META-INF/container.xml", containerXML},
+		{"OEBPS/content.opf", opf.String()},
+		{"OEBPS/nav.xhtml", xmlHeader + nav.String()},
+		{"OEBPS/report.xhtml", xmlHeader + chapter.String()},
+		{"OEBPS/style.css", style},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, f.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// bookIdentifier names the book after its research session, or after its
+// content when the bundle has no session ID.
+func bookIdentifier(b artifacts.Bundle) string {
+	if b.Structured.SessionID != "" {
+		return "urn:research-assistant:" + b.Structured.SessionID
+	}
+	sum := sha256.Sum256([]byte(b.Topic + "\n" + b.Report))
+	return "urn:research-assistant:" + hex.EncodeToString(sum[:8])
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
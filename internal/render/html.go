@@ -0,0 +1,90 @@
+package render
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/user/research-assistant/internal/artifacts"
+)
+
+// templates holds the document body shared by the HTML page and the EPUB
+// chapter. The markup is XHTML-compatible so both can use it.
+var templates = template.Must(template.New("content").Parse(`
+<h1>{{.Title}}</h1>
+{{- if .Summary}}
+<section class="summary">
+<h2>Executive summary</h2>
+{{.Summary}}
+</section>
+{{- end}}
+{{- if .Findings}}
+<section class="findings">
+<h2>Key findings</h2>
+<table>
+<thead><tr><th>Finding</th><th>Confidence</th><th>Verification</th></tr></thead>
+<tbody>
+{{- range .Findings}}
+<tr>
+<td>{{.Text}}</td>
+<td><div class="bar"><span style="width: {{.Percent}}%"></span></div> {{.Percent}}%</td>
+<td>{{if .Verdict}}{{.Verdict}}{{else}}unverified{{end}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+</section>
+{{- end}}
+<section class="report">
+{{.Body}}
+</section>
+{{- if .Sources}}
+<section class="sources">
+<h2>Sources</h2>
+<ol>
+{{- range .Sources}}
+<li id="ref-{{.Number}}" value="{{.Number}}"><a href="{{.URL}}">{{.Title}}</a>{{if .Published}} <span class="published">published {{.Published}}</span>{{end}}{{if .Credibility}} <span class="credibility">credibility {{.Credibility}}%</span>{{end}}</li>
+{{- end}}
+</ol>
+</section>
+{{- end}}
+`))
+
+const style = `body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4rem; text-align: left; vertical-align: top; }
+.bar { display: inline-block; width: 6rem; height: 0.6rem; background: #eee; border-radius: 0.3rem; overflow: hidden; }
+.bar span { display: block; height: 100%; background: #2a7ae2; }
+sup.cite a { text-decoration: none; }
+.published, .credibility { color: #666; font-size: 0.85em; }
+.summary { background: #f6f8fa; padding: 0.5rem 1rem; border-radius: 0.5rem; }`
+
+func init() {
+	template.Must(templates.New("page").Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>` + style + `</style>
+</head>
+<body>
+{{template "content" .}}
+</body>
+</html>
+`))
+}
+
+// HTML renders a standalone HTML page with the summary, a findings table with
+// confidence bars, the report and a linked source list.
+type HTML struct{}
+
+func (HTML) Name() string      { return "html" }
+func (HTML) Extension() string { return "html" }
+
+func (HTML) Render(b artifacts.Bundle) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, "page", newDocument(b)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,167 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	orderedItem = regexp.MustCompile(`^\d+[.)]\s+`)
+	// inlinePattern matches, in order of precedence, code spans, links,
+	// citations such as [1] or [2, 3], and bold text.
+	inlinePattern = regexp.MustCompile("`([^`]+)`|\\[([^\\]]+)\\]\\((https?://[^)\\s]+)\\)|\\[(\\d+(?:\\s*,\\s*\\d+)*)\\]|\\*\\*([^*]+)\\*\\*")
+)
+
+// markdownToHTML converts the Markdown subset LLM reports use (ATX and setext
+// headings, bullet and numbered lists, pipe tables, paragraphs, bold, code
+// spans and links) to well-formed XHTML, so the output is valid in both the
+// HTML page and the EPUB. Citations such as [2] link to the #ref-2 anchor;
+// bracketed numbers past refs, the number of listed sources, are not
+// citations and stay text. Anything else is kept as escaped text.
+func markdownToHTML(md string, refs int) string {
+	var out strings.Builder
+	var para []string
+	list := ""
+
+	flushPara := func() {
+		if len(para) > 0 {
+			out.WriteString("<p>" + inline(strings.Join(para, " "), refs) + "</p>\n")
+			para = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			out.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			out.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			flushPara()
+			closeList()
+		case strings.HasPrefix(line, "#"):
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			if level > 6 || !strings.HasPrefix(line[level:], " ") {
+				para = append(para, line)
+				continue
+			}
+			flushPara()
+			closeList()
+			out.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", level, inline(strings.TrimSpace(line[level:]), refs), level))
+		case strings.HasPrefix(line, "|"):
+			flushPara()
+			closeList()
+			var rows []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, strings.TrimSpace(lines[i]))
+			}
+			i--
+			out.WriteString(tableToHTML(rows, refs))
+		case i+1 < len(lines) && isSetextUnderline(lines[i+1]) && len(para) == 0 && list == "":
+			level := 1
+			if strings.HasPrefix(strings.TrimSpace(lines[i+1]), "-") {
+				level = 2
+			}
+			out.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", level, inline(line, refs), level))
+			i++
+		case strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "+ "):
+			flushPara()
+			openList("ul")
+			out.WriteString("<li>" + inline(strings.TrimSpace(line[2:]), refs) + "</li>\n")
+		case orderedItem.MatchString(line):
+			flushPara()
+			openList("ol")
+			out.WriteString("<li>" + inline(orderedItem.ReplaceAllString(line, ""), refs) + "</li>\n")
+		default:
+			closeList()
+			para = append(para, line)
+		}
+	}
+	flushPara()
+	closeList()
+	return out.String()
+}
+
+// tableToHTML converts the rows of a pipe table. A delimiter row such as
+// |---|---| makes the rows above it the table head.
+func tableToHTML(rows []string, refs int) string {
+	var out strings.Builder
+	out.WriteString("<table>\n")
+	head := len(rows) > 1 && isDelimiterRow(rows[1])
+	for i, row := range rows {
+		if head && i == 1 {
+			continue
+		}
+		tag := "td"
+		if head && i == 0 {
+			tag = "th"
+		}
+		out.WriteString("<tr>")
+		for _, cell := range strings.Split(strings.Trim(row, "|"), "|") {
+			out.WriteString("<" + tag + ">" + inline(strings.TrimSpace(cell), refs) + "</" + tag + ">")
+		}
+		out.WriteString("</tr>\n")
+	}
+	out.WriteString("</table>\n")
+	return out.String()
+}
+
+func isDelimiterRow(row string) bool {
+	return strings.Trim(row, "|-: ") == "" && strings.Contains(row, "-")
+}
+
+func isSetextUnderline(line string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= 3 && (strings.Trim(line, "=") == "" || strings.Trim(line, "-") == "")
+}
+
+// inline escapes text and converts its inline Markdown. Citations link to
+// the first refs sources.
+func inline(text string, refs int) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range inlinePattern.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(html.EscapeString(text[last:m[0]]))
+		group := func(n int) string { return text[m[2*n]:m[2*n+1]] }
+		switch {
+		case m[2] >= 0:
+			out.WriteString("<code>" + html.EscapeString(group(1)) + "</code>")
+		case m[4] >= 0:
+			out.WriteString(`<a href="` + html.EscapeString(group(3)) + `">` + html.EscapeString(group(2)) + "</a>")
+		case m[8] >= 0:
+			var links []string
+			for _, n := range strings.Split(group(4), ",") {
+				n = strings.TrimSpace(n)
+				if i, _ := strconv.Atoi(n); i < 1 || i > refs {
+					links = nil
+					break
+				}
+				links = append(links, `<a href="#ref-`+n+`">`+n+"</a>")
+			}
+			if links == nil {
+				out.WriteString(html.EscapeString(text[m[0]:m[1]]))
+				break
+			}
+			out.WriteString(`<sup class="cite">[` + strings.Join(links, ", ") + "]</sup>")
+		case m[10] >= 0:
+			out.WriteString("<strong>" + html.EscapeString(group(5)) + "</strong>")
+		}
+		last = m[1]
+	}
+	out.WriteString(html.EscapeString(text[last:]))
+	return out.String()
+}
@@ -0,0 +1,148 @@
+// Package render turns a research artifacts bundle into reader-facing
+// documents: the Markdown report, a standalone HTML page and an EPUB.
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/user/research-assistant/internal/artifacts"
+)
+
+// Renderer converts a bundle into one document format.
+type Renderer interface {
+	// Name identifies the renderer in research options, e.g. "html".
+	Name() string
+	// Extension is the file extension of the rendered blob, without the dot.
+	Extension() string
+	Render(b artifacts.Bundle) ([]byte, error)
+}
+
+var renderers = map[string]Renderer{}
+
+// Register makes r selectable by name. It panics if the name is taken.
+func Register(r Renderer) {
+	if _, dup := renderers[r.Name()]; dup {
+		panic(fmt.Sprintf("render: renderer %q already registered", r.Name()))
+	}
+	renderers[r.Name()] = r
+}
+
+// Lookup returns the renderer registered under name.
+func Lookup(name string) (Renderer, bool) {
+	r, ok := renderers[name]
+	return r, ok
+}
+
+// Names returns the names of the registered renderers in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(Markdown{})
+	Register(HTML{})
+	Register(EPUB{})
+}
+
+// Markdown renders the report as stored in the bundle.
+type Markdown struct{}
+
+func (Markdown) Name() string      { return "markdown" }
+func (Markdown) Extension() string { return "md" }
+
+func (Markdown) Render(b artifacts.Bundle) ([]byte, error) {
+	return []byte(b.Report), nil
+}
+
+// document is the view of a bundle shared by the HTML and EPUB templates.
+type document struct {
+	Title    string
+	Language string
+	Summary  template.HTML
+	Body     template.HTML
+	Findings []finding
+	Sources  []source
+}
+
+type finding struct {
+	Text    string
+	Percent int
+	Verdict string
+}
+
+type source struct {
+	Number      int
+	Title       string
+	URL         string
+	Credibility int    // percent, 0 when the source was not scored
+	Published   string // publication date, when known
+}
+
+// newDocument prepares b for rendering. The report's title line and its
+// Markdown references section are dropped, since the templates render the
+// title and a linked source list themselves. The source list holds the
+// citations when there are any and every source otherwise.
+func newDocument(b artifacts.Bundle) document {
+	report := strings.TrimPrefix(b.Report, artifacts.ReportTitle)
+	if i := strings.LastIndex(report, artifacts.ReferencesHeading); i >= 0 {
+		report = report[:i]
+	}
+	refs := len(b.Citations)
+	if refs == 0 {
+		refs = len(b.Sources)
+	}
+	doc := document{
+		Title:    b.Topic,
+		Language: b.Language,
+		Summary:  template.HTML(markdownToHTML(b.Summary, refs)),
+		Body:     template.HTML(markdownToHTML(report, refs)),
+	}
+	if doc.Title == "" {
+		doc.Title = "Research Report"
+	}
+	if doc.Language == "" {
+		doc.Language = "en"
+	}
+	for _, f := range b.Structured.KeyFindings {
+		doc.Findings = append(doc.Findings, finding{Text: f.Finding, Percent: percent(f.Confidence), Verdict: f.Verdict})
+	}
+	if len(b.Citations) > 0 {
+		for _, c := range b.Citations {
+			doc.Sources = append(doc.Sources, source{Number: c.Number, Title: titleOr(c.Title, c.URL), URL: c.URL, Credibility: percent(c.Credibility), Published: dateOf(c.Published)})
+		}
+	} else {
+		for i, s := range b.Sources {
+			doc.Sources = append(doc.Sources, source{Number: i + 1, Title: titleOr(s.Title, s.URL), URL: s.URL, Credibility: percent(s.Credibility), Published: dateOf(s.Published)})
+		}
+	}
+	return doc
+}
+
+// percent converts a score in [0,1] to a whole percentage.
+func percent(score float64) int {
+	return min(100, max(0, int(score*100+0.5)))
+}
+
+// dateOf formats t as a date, or returns "" for the zero time.
+func dateOf(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
+
+func titleOr(title, url string) string {
+	if title != "" {
+		return title
+	}
+	return url
+}
@@ -0,0 +1,142 @@
+package render_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/user/research-assistant/internal/artifacts"
+	"github.com/user/research-assistant/internal/event"
+	"github.com/user/research-assistant/internal/render"
+)
+
+func testBundle() artifacts.Bundle {
+	return artifacts.Bundle{
+		Topic:    "Solar <power> & storage",
+		Language: "en",
+		Summary:  "Solar is **cheap**.",
+		Report: artifacts.ReportTitle + "## Costs\n\nPanels got cheaper [1] since [2024]. Storage too [1, 2].\n\n- one\n- two\n\n" +
+			artifacts.ReferencesHeading + "\n\n[1] A. https://a.example (credibility 0.75)\n[2] https://b.example\n",
+		Sources: []event.SearchSource{{URL: "https://a.example", Title: "A"}, {URL: "https://b.example"}},
+		Structured: event.StructuredResearch{
+			SessionID:   "s1",
+			KeyFindings: []event.StructuredFinding{{Finding: "Panels are cheap", Confidence: 0.83, Verdict: "supported"}},
+		},
+		Citations: []artifacts.Citation{{Number: 1, URL: "https://a.example", Title: "A", Credibility: 0.75}, {Number: 2, URL: "https://b.example"}},
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"markdown", "html", "epub"} {
+		if _, ok := render.Lookup(name); !ok {
+			t.Errorf("expected renderer %q to be registered", name)
+		}
+	}
+	if _, ok := render.Lookup("pdf"); ok {
+		t.Error("expected no pdf renderer")
+	}
+}
+
+func TestHTML_Render(t *testing.T) {
+	out, err := render.HTML{}.Render(testBundle())
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	page := string(out)
+	for _, want := range []string{
+		`<title>Solar &lt;power&gt; &amp; storage</title>`,
+		`<h2>Costs</h2>`,
+		`<sup class="cite">[<a href="#ref-1">1</a>, <a href="#ref-2">2</a>]</sup>`,
+		`</sup> since [2024].`,
+		`<li id="ref-1" value="1"><a href="https://a.example">A</a> <span class="credibility">credibility 75%</span></li>`,
+		`<li id="ref-2" value="2"><a href="https://b.example">https://b.example</a></li>`,
+		`style="width: 83%"`,
+		`<strong>cheap</strong>`,
+		`<li>one</li>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected page to contain %q", want)
+		}
+	}
+	if strings.Contains(page, "RESEARCH REPORT") || strings.Contains(page, artifacts.ReferencesHeading) {
+		t.Error("expected the Markdown title and references section to be replaced")
+	}
+}
+
+func TestEPUB_Render(t *testing.T) {
+	out, err := render.EPUB{Modified: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}.Render(testBundle())
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	if len(zr.File) == 0 || zr.File[0].Name != "mimetype" || zr.File[0].Method != zip.Store {
+		t.Fatal("expected an uncompressed mimetype entry first")
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	if files["mimetype"] != "application/epub+zip" {
+		t.Errorf("unexpected mimetype %q", files["mimetype"])
+	}
+	for _, name := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/report.xhtml"} {
+		content, ok := files[name]
+		if !ok {
+			t.Fatalf("missing %s", name)
+		}
+		// Every XML document in the book must be well-formed.
+		dec := xml.NewDecoder(strings.NewReader(content))
+		dec.Strict = true
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed: %v", name, err)
+			}
+		}
+	}
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{"urn:research-assistant:s1", "Solar &lt;power&gt; &amp; storage", "2026-01-02T03:04:05Z"} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("expected content.opf to contain %q", want)
+		}
+	}
+	if !strings.Contains(files["OEBPS/report.xhtml"], `id="ref-2"`) {
+		t.Error("expected the chapter to contain the source list")
+	}
+}
+
+func TestHTML_RendersTables(t *testing.T) {
+	b := testBundle()
+	b.Report = "## Comparison matrix\n\n| Criterion | Go | Rust |\n|---|---|---|\n| Speed | fast [1] | faster [2] |\n"
+	out, err := render.HTML{}.Render(b)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	page := string(out)
+	for _, want := range []string{
+		"<tr><th>Criterion</th><th>Go</th><th>Rust</th></tr>",
+		`<tr><td>Speed</td><td>fast <sup class="cite">[<a href="#ref-1">1</a>]</sup></td>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected page to contain %q", want)
+		}
+	}
+	if strings.Contains(page, "---") {
+		t.Error("expected the delimiter row to be dropped")
+	}
+}
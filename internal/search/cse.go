@@ -14,14 +14,37 @@ import (
 )
 
 type Options struct {
-	Safe string // off|medium|active
-	Num  int    // max results 1-10
+	Safe         string        // off|medium|active
+	Num          int           // max results 1-10
+	Language     string        // ISO 639-1 code, sets hl and lr
+	IncludeSites []string      // restricts results to these domains via site:
+	ExcludeSites []string      // drops results from these domains via -site:
+	MaxAge       time.Duration // restricts results to this recent past via dateRestrict
 }
 
 type ContentResult struct {
-	Title   string `json:"title"`
-	Link    string `json:"link"`
-	Snippet string `json:"snippet"`
+	Title   string  `json:"title"`
+	Link    string  `json:"link"`
+	Snippet string  `json:"snippet"`
+	Pagemap Pagemap `json:"pagemap"`
+}
+
+// Pagemap holds the structured data CSE extracted from a result page.
+type Pagemap struct {
+	Metatags []map[string]string `json:"metatags"`
+}
+
+// Metatags returns the result page's meta tags with lower-case names.
+func (r ContentResult) Metatags() map[string]string {
+	out := make(map[string]string)
+	for _, tags := range r.Pagemap.Metatags {
+		for k, v := range tags {
+			if k = strings.ToLower(k); out[k] == "" {
+				out[k] = v
+			}
+		}
+	}
+	return out
 }
 
 type ContentResponse struct {
@@ -35,22 +58,8 @@ func ContentWebSearch(ctx context.Context, apiKey, cx, query string, opts Option
 	if strings.TrimSpace(query) == "" {
 		return nil, errors.New(errors.CodeQueryInvalid, "search", "Search query is empty.", nil)
 	}
-	if opts.Num <= 0 || opts.Num > 10 {
-		opts.Num = 5
-	}
-
-	u, _ := url.Parse("https://customsearch.googleapis.com/customsearch/v1")
-	q := u.Query()
-	q.Set("key", apiKey)
-	q.Set("cx", cx)
-	q.Set("q", query)
-	q.Set("num", fmt.Sprintf("%d", opts.Num))
-	if opts.Safe != "" {
-		q.Set("safe", opts.Safe)
-	}
-	u.RawQuery = q.Encode()
 
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, buildURL(apiKey, cx, query, opts), nil)
 	httpClient := &http.Client{Timeout: 10 * time.Second}
 	resp, err := httpClient.Do(req)
 	if err != nil {
@@ -83,3 +92,50 @@ func ContentWebSearch(ctx context.Context, apiKey, cx, query string, opts Option
 
 	return sr.Items, nil
 }
+
+// buildURL assembles the CSE request URL. Domain filters are expressed as
+// site: operators in the query, since CSE accepts only one siteSearch value.
+func buildURL(apiKey, cx, query string, opts Options) string {
+	if opts.Num <= 0 || opts.Num > 10 {
+		opts.Num = 5
+	}
+	var terms []string
+	for i, site := range opts.IncludeSites {
+		if i > 0 {
+			terms = append(terms, "OR")
+		}
+		terms = append(terms, "site:"+site)
+	}
+	for _, site := range opts.ExcludeSites {
+		terms = append(terms, "-site:"+site)
+	}
+	if len(terms) > 0 {
+		query = query + " " + strings.Join(terms, " ")
+	}
+
+	u, _ := url.Parse("https://customsearch.googleapis.com/customsearch/v1")
+	q := u.Query()
+	q.Set("key", apiKey)
+	q.Set("cx", cx)
+	q.Set("q", query)
+	q.Set("num", fmt.Sprintf("%d", opts.Num))
+	if opts.Safe != "" {
+		q.Set("safe", opts.Safe)
+	}
+	if opts.Language != "" {
+		q.Set("hl", opts.Language)
+		q.Set("lr", "lang_"+opts.Language)
+	}
+	if opts.MaxAge > 0 {
+		q.Set("dateRestrict", DateRestrict(opts.MaxAge))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// DateRestrict expresses maxAge as a CSE dateRestrict value in whole days,
+// rounded up.
+func DateRestrict(maxAge time.Duration) string {
+	days := int((maxAge + 24*time.Hour - 1) / (24 * time.Hour))
+	return fmt.Sprintf("d%d", max(1, days))
+}
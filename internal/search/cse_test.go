@@ -2,8 +2,11 @@ package search
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/user/research-assistant/internal/config"
 )
@@ -28,3 +31,36 @@ func TestSearchWeb_Integration(t *testing.T) {
 		t.Fatal("CSE returned 0 results for a valid query")
 	}
 }
+
+func TestBuildURL_LanguageAndSites(t *testing.T) {
+	raw := buildURL("key", "cx", "go generics", Options{
+		Num:          4,
+		Language:     "de",
+		IncludeSites: []string{"go.dev", "golang.org"},
+		ExcludeSites: []string{"example.com"},
+		MaxAge:       36 * time.Hour,
+	})
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	q := u.Query()
+	if got, want := q.Get("q"), "go generics site:go.dev OR site:golang.org -site:example.com"; got != want {
+		t.Errorf("q = %q, want %q", got, want)
+	}
+	if q.Get("num") != "4" || q.Get("hl") != "de" || q.Get("lr") != "lang_de" || q.Get("dateRestrict") != "d2" {
+		t.Errorf("unexpected params: %v", q)
+	}
+}
+
+func TestContentResult_Metatags(t *testing.T) {
+	var r ContentResult
+	raw := `{"link": "https://a.example", "pagemap": {"metatags": [{"og:title": "A", "Article:Published_Time": "2024-03-05"}, {"og:title": "B"}]}}`
+	if err := json.Unmarshal([]byte(raw), &r); err != nil {
+		t.Fatal(err)
+	}
+	meta := r.Metatags()
+	if meta["article:published_time"] != "2024-03-05" || meta["og:title"] != "A" {
+		t.Errorf("unexpected metatags: %v", meta)
+	}
+}
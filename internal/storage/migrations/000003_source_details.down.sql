@@ -0,0 +1,3 @@
+-- migration/000003_source_details.down.sql
+-- See 000002: columns are left in place rather than rebuilding the table.
+SELECT 1;
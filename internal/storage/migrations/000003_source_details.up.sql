@@ -0,0 +1,5 @@
+-- migration/000003_source_details.up.sql
+-- One row per search hit, with the hit's title, rank and search provider.
+ALTER TABLE sources ADD COLUMN title TEXT;
+ALTER TABLE sources ADD COLUMN rank INTEGER;
+ALTER TABLE sources ADD COLUMN provider TEXT;
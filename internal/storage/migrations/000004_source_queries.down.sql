@@ -0,0 +1,3 @@
+-- migration/000004_source_queries.down.sql
+-- See 000002: columns are left in place rather than rebuilding the table.
+SELECT 1;
@@ -0,0 +1,3 @@
+-- migration/000004_source_queries.up.sql
+-- JSON array of every query that returned a deduplicated source.
+ALTER TABLE sources ADD COLUMN queries TEXT;
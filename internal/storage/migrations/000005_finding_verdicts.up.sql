@@ -0,0 +1,4 @@
+-- migration/000005_finding_verdicts.up.sql
+-- Outcome of the verify stage for each key finding.
+ALTER TABLE key_findings ADD COLUMN verdict TEXT;
+ALTER TABLE key_findings ADD COLUMN verdict_reason TEXT;
@@ -0,0 +1,2 @@
+-- migration/000006_checkpoints.down.sql
+DROP TABLE IF EXISTS checkpoints;
@@ -0,0 +1,8 @@
+-- migration/000006_checkpoints.up.sql
+-- Latest pipeline checkpoint per session, used to resume interrupted runs.
+CREATE TABLE IF NOT EXISTS checkpoints (
+    session_id  TEXT PRIMARY KEY REFERENCES research_sessions(id),
+    stage       TEXT NOT NULL, -- last stage that completed
+    state       TEXT NOT NULL, -- JSON snapshot of the pipeline state
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- migration/000007_token_usage.down.sql
+DROP TABLE IF EXISTS token_usage;
@@ -0,0 +1,13 @@
+-- migration/000007_token_usage.up.sql
+-- LLM token usage of each research session, per pipeline stage.
+CREATE TABLE IF NOT EXISTS token_usage (
+    session_id        TEXT NOT NULL REFERENCES research_sessions(id),
+    stage             TEXT NOT NULL,
+    calls             INTEGER NOT NULL,
+    prompt_tokens     INTEGER NOT NULL,
+    completion_tokens INTEGER NOT NULL,
+    total_tokens      INTEGER NOT NULL,
+    cost_usd          REAL NOT NULL,
+    estimated         INTEGER NOT NULL DEFAULT 0, -- 1 when counts were estimated from text length
+    PRIMARY KEY (session_id, stage)
+);
@@ -0,0 +1,2 @@
+-- migration/000008_prompt_versions.down.sql
+DROP TABLE IF EXISTS prompt_versions;
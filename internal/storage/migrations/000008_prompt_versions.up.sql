@@ -0,0 +1,8 @@
+-- migration/000008_prompt_versions.up.sql
+-- Version of each prompt template used by a research session.
+CREATE TABLE IF NOT EXISTS prompt_versions (
+    session_id TEXT NOT NULL REFERENCES research_sessions(id),
+    name       TEXT NOT NULL, -- template name, e.g. report
+    version    TEXT NOT NULL,
+    PRIMARY KEY (session_id, name)
+);
@@ -0,0 +1,3 @@
+-- migration/000009_session_language.up.sql
+-- ISO 639-1 language the session's report and summary are written in.
+ALTER TABLE research_sessions ADD COLUMN language TEXT;
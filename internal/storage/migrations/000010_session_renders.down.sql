@@ -0,0 +1,2 @@
+-- migration/000010_session_renders.down.sql
+DROP TABLE IF EXISTS session_renders;
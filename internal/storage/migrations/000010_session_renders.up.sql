@@ -0,0 +1,8 @@
+-- migration/000010_session_renders.up.sql
+-- Blob keys of the additional formats a session's report was rendered in.
+CREATE TABLE IF NOT EXISTS session_renders (
+    session_id TEXT NOT NULL REFERENCES research_sessions(id),
+    format     TEXT NOT NULL, -- renderer name, e.g. html
+    blob_key   TEXT NOT NULL,
+    PRIMARY KEY (session_id, format)
+);
@@ -0,0 +1,2 @@
+-- migration/000011_search_cache.down.sql
+DROP TABLE IF EXISTS search_cache;
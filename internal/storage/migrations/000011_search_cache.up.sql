@@ -0,0 +1,8 @@
+-- migration/000011_search_cache.up.sql
+-- Cached web search results, shared by all sessions.
+CREATE TABLE IF NOT EXISTS search_cache (
+    key        TEXT PRIMARY KEY, -- hash of the normalized query and provider options
+    results    TEXT NOT NULL,    -- JSON array of search results
+    stored_at  INTEGER NOT NULL, -- unix time in nanoseconds
+    used_at    INTEGER NOT NULL  -- unix time in nanoseconds of the last hit
+);
@@ -0,0 +1,3 @@
+-- migration/000012_source_cached.up.sql
+-- Whether a source came from the search cache rather than a live search.
+ALTER TABLE sources ADD COLUMN cached INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- migration/000013_source_credibility.up.sql
+-- Credibility score of a source, from 0 to 1.
+ALTER TABLE sources ADD COLUMN credibility REAL NOT NULL DEFAULT 0;
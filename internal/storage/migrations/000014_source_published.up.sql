@@ -0,0 +1,3 @@
+-- migration/000014_source_published.up.sql
+-- Publication date of a source as RFC 3339 text, NULL when unknown.
+ALTER TABLE sources ADD COLUMN published TEXT;
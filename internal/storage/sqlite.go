@@ -3,10 +3,12 @@ package storage
 import (
 	"database/sql"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/user/research-assistant/internal/event"
@@ -18,7 +20,57 @@ var baseSchema string
 //go:embed migrations/000002_add_summary.up.sql
 var addSummarySQL string
 
-var schemaSQL = baseSchema + "\n" + addSummarySQL
+//go:embed migrations/000003_source_details.up.sql
+var sourceDetailsSQL string
+
+//go:embed migrations/000004_source_queries.up.sql
+var sourceQueriesSQL string
+
+//go:embed migrations/000005_finding_verdicts.up.sql
+var findingVerdictsSQL string
+
+//go:embed migrations/000006_checkpoints.up.sql
+var checkpointsSQL string
+
+//go:embed migrations/000007_token_usage.up.sql
+var tokenUsageSQL string
+
+//go:embed migrations/000008_prompt_versions.up.sql
+var promptVersionsSQL string
+
+//go:embed migrations/000009_session_language.up.sql
+var sessionLanguageSQL string
+
+//go:embed migrations/000010_session_renders.up.sql
+var sessionRendersSQL string
+
+//go:embed migrations/000011_search_cache.up.sql
+var searchCacheSQL string
+
+//go:embed migrations/000012_source_cached.up.sql
+var sourceCachedSQL string
+
+//go:embed migrations/000013_source_credibility.up.sql
+var sourceCredibilitySQL string
+
+//go:embed migrations/000014_source_published.up.sql
+var sourcePublishedSQL string
+
+var schemaSQL = baseSchema + "\n" + addSummarySQL + "\n" + sourceDetailsSQL + "\n" + sourceQueriesSQL + "\n" + findingVerdictsSQL + "\n" + checkpointsSQL + "\n" + tokenUsageSQL + "\n" + promptVersionsSQL + "\n" + sessionLanguageSQL + "\n" + sessionRendersSQL + "\n" + searchCacheSQL + "\n" + sourceCachedSQL + "\n" + sourceCredibilitySQL + "\n" + sourcePublishedSQL
+
+// columnMigrations add columns to tables created by baseSchema, in order.
+var columnMigrations = []string{addSummarySQL, sourceDetailsSQL, sourceQueriesSQL, findingVerdictsSQL, sessionLanguageSQL, sourceCachedSQL, sourceCredibilitySQL, sourcePublishedSQL}
+
+// tableMigrations create tables added after baseSchema. They use
+// CREATE TABLE IF NOT EXISTS and are safe to run on every open.
+var tableMigrations = []string{checkpointsSQL, tokenUsageSQL, promptVersionsSQL, sessionRendersSQL, searchCacheSQL}
+
+// SessionRecord identifies a research session and its current status.
+type SessionRecord struct {
+	ID     string
+	Topic  string
+	Status string
+}
 
 // StructuredStorage defines the interface for storing structured research data
 type StructuredStorage interface {
@@ -31,6 +83,28 @@ type StructuredStorage interface {
 	GetSessionStatus(id string) (status string, errMsg string, err error)
 	GetSessionArtifacts(id string) (reportMDKey, reportJSONKey string, err error)
 	DeleteSession(id string) error
+
+	// SaveCheckpoint replaces the session's checkpoint with the state
+	// snapshot taken after stage completed.
+	SaveCheckpoint(sessionID, stage string, state []byte) error
+	// LoadCheckpoint returns the session's latest checkpoint, or an empty
+	// stage when there is none.
+	LoadCheckpoint(sessionID string) (stage string, state []byte, err error)
+	DeleteCheckpoint(sessionID string) error
+	// ListUnfinishedSessions returns sessions that never reached a terminal
+	// status (complete, failed or canceled), oldest first.
+	ListUnfinishedSessions() ([]SessionRecord, error)
+	// SaveTokenUsage replaces the session's per-stage LLM token usage.
+	SaveTokenUsage(sessionID string, usage []event.TokenUsage) error
+	// SavePromptVersions replaces the versions of the prompt templates used
+	// by the session, keyed by template name.
+	SavePromptVersions(sessionID string, versions map[string]string) error
+	// SetSessionLanguage records the ISO 639-1 language the session's report
+	// is written in.
+	SetSessionLanguage(id, language string) error
+	// SaveRenders replaces the blob keys of the session's rendered reports,
+	// keyed by renderer name.
+	SaveRenders(sessionID string, renders map[string]string) error
 }
 
 type SQLiteStore struct {
@@ -59,9 +133,19 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		return nil, fmt.Errorf("apply base schema: %w", err)
 	}
 
-	// Add summary column if it doesn't exist
-	if _, err := db.Exec(addSummarySQL); err != nil {
-		if !strings.Contains(err.Error(), "duplicate column name") {
+	for _, migration := range tableMigrations {
+		if _, err := db.Exec(migration); err != nil {
+			closeErr := db.Close()
+			if closeErr != nil {
+				return nil, fmt.Errorf("apply migration error: %v, close error: %v", err, closeErr)
+			}
+			return nil, fmt.Errorf("apply migration: %w", err)
+		}
+	}
+
+	// Add columns that don't exist yet
+	for _, migration := range columnMigrations {
+		if err := applyColumnMigration(db, migration); err != nil {
 			closeErr := db.Close()
 			if closeErr != nil {
 				return nil, fmt.Errorf("apply migration error: %v, close error: %v", err, closeErr)
@@ -73,6 +157,30 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 	return &SQLiteStore{db: db}, nil
 }
 
+// applyColumnMigration runs each statement of an ADD COLUMN migration on its
+// own, so columns that already exist are skipped without hiding the rest.
+func applyColumnMigration(db *sql.DB, migration string) error {
+	for _, stmt := range strings.Split(migration, ";") {
+		if isCommentOnly(stmt) {
+			continue
+		}
+		if _, err := db.Exec(stmt); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return err
+		}
+	}
+	return nil
+}
+
+func isCommentOnly(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *SQLiteStore) Close() error {
 	return s.db.Close()
 }
@@ -100,6 +208,14 @@ func (s *SQLiteStore) UpdateSessionStatus(id, status string, errMsg string) erro
 	return nil
 }
 
+// SetSessionLanguage records the language of the session's report.
+func (s *SQLiteStore) SetSessionLanguage(id, language string) error {
+	if _, err := s.db.Exec(`UPDATE research_sessions SET language = ? WHERE id = ?`, language, id); err != nil {
+		return fmt.Errorf("set session language: %w", err)
+	}
+	return nil
+}
+
 func (s *SQLiteStore) SaveFindings(sessionID string, findings []event.StructuredFinding) error {
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -112,7 +228,7 @@ func (s *SQLiteStore) SaveFindings(sessionID string, findings []event.Structured
 		}
 	}(tx)
 
-	stmt, err := tx.Prepare(`INSERT INTO key_findings (session_id, finding, confidence) VALUES (?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT INTO key_findings (session_id, finding, confidence, verdict, verdict_reason) VALUES (?, ?, ?, ?, ?)`)
 	if err != nil {
 		return err
 	}
@@ -124,7 +240,7 @@ func (s *SQLiteStore) SaveFindings(sessionID string, findings []event.Structured
 	}(stmt)
 
 	for _, f := range findings {
-		if _, err := stmt.Exec(sessionID, f.Finding, f.Confidence); err != nil {
+		if _, err := stmt.Exec(sessionID, f.Finding, f.Confidence, f.Verdict, f.VerdictReason); err != nil {
 			return fmt.Errorf("insert finding: %w", err)
 		}
 	}
@@ -176,7 +292,7 @@ func (s *SQLiteStore) SaveSources(sessionID string, sources []event.SearchSource
 		}
 	}(tx)
 
-	stmt, err := tx.Prepare(`INSERT INTO sources (session_id, query, url, snippet) VALUES (?, ?, ?, ?)`)
+	stmt, err := tx.Prepare(`INSERT INTO sources (session_id, query, url, snippet, title, rank, provider, queries, cached, credibility, published) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return err
 	}
@@ -188,7 +304,16 @@ func (s *SQLiteStore) SaveSources(sessionID string, sources []event.SearchSource
 	}(stmt)
 
 	for _, src := range sources {
-		if _, err := stmt.Exec(sessionID, src.Query, src.URL, src.Snippet); err != nil {
+		var queries any
+		if len(src.Queries) > 0 {
+			b, _ := json.Marshal(src.Queries)
+			queries = string(b)
+		}
+		var published any
+		if !src.Published.IsZero() {
+			published = src.Published.UTC().Format(time.RFC3339)
+		}
+		if _, err := stmt.Exec(sessionID, src.Query, src.URL, src.Snippet, src.Title, src.Rank, src.Provider, queries, src.Cached, src.Credibility, published); err != nil {
 			return fmt.Errorf("insert source: %w", err)
 		}
 	}
@@ -199,7 +324,7 @@ func (s *SQLiteStore) SaveSources(sessionID string, sources []event.SearchSource
 // GetKeyFindings retrieves all key findings for the given session.
 func (s *SQLiteStore) GetKeyFindings(sessionID string) ([]event.StructuredFinding, error) {
 	rows, err := s.db.Query(
-		`SELECT finding, confidence FROM key_findings WHERE session_id = ? ORDER BY id`,
+		`SELECT finding, confidence, COALESCE(verdict, ''), COALESCE(verdict_reason, '') FROM key_findings WHERE session_id = ? ORDER BY id`,
 		sessionID,
 	)
 	if err != nil {
@@ -215,7 +340,7 @@ func (s *SQLiteStore) GetKeyFindings(sessionID string) ([]event.StructuredFindin
 	var findings []event.StructuredFinding
 	for rows.Next() {
 		var f event.StructuredFinding
-		if err := rows.Scan(&f.Finding, &f.Confidence); err != nil {
+		if err := rows.Scan(&f.Finding, &f.Confidence, &f.Verdict, &f.VerdictReason); err != nil {
 			return nil, fmt.Errorf("scan finding: %w", err)
 		}
 		findings = append(findings, f)
@@ -226,7 +351,7 @@ func (s *SQLiteStore) GetKeyFindings(sessionID string) ([]event.StructuredFindin
 // GetSources retrieves all sources for the given session.
 func (s *SQLiteStore) GetSources(sessionID string) ([]event.SearchSource, error) {
 	rows, err := s.db.Query(
-		`SELECT query, url, COALESCE(snippet, '') FROM sources WHERE session_id = ? ORDER BY id`,
+		`SELECT query, url, COALESCE(snippet, ''), COALESCE(title, ''), COALESCE(rank, 0), COALESCE(provider, ''), COALESCE(queries, ''), cached, credibility, COALESCE(published, '') FROM sources WHERE session_id = ? ORDER BY id`,
 		sessionID,
 	)
 	if err != nil {
@@ -242,9 +367,22 @@ func (s *SQLiteStore) GetSources(sessionID string) ([]event.SearchSource, error)
 	var sources []event.SearchSource
 	for rows.Next() {
 		var src event.SearchSource
-		if err := rows.Scan(&src.Query, &src.URL, &src.Snippet); err != nil {
+		var queries, published string
+		if err := rows.Scan(&src.Query, &src.URL, &src.Snippet, &src.Title, &src.Rank, &src.Provider, &queries, &src.Cached, &src.Credibility, &published); err != nil {
 			return nil, fmt.Errorf("scan source: %w", err)
 		}
+		if published != "" {
+			t, err := time.Parse(time.RFC3339, published)
+			if err != nil {
+				return nil, fmt.Errorf("decode source published date: %w", err)
+			}
+			src.Published = t
+		}
+		if queries != "" {
+			if err := json.Unmarshal([]byte(queries), &src.Queries); err != nil {
+				return nil, fmt.Errorf("decode source queries: %w", err)
+			}
+		}
 		sources = append(sources, src)
 	}
 	return sources, rows.Err()
@@ -274,6 +412,17 @@ func (s *SQLiteStore) GetSessionStatus(id string) (string, string, error) {
 	return status, errMsg, nil
 }
 
+// GetSessionLanguage returns the language of the session's report, or "" when
+// none was recorded.
+func (s *SQLiteStore) GetSessionLanguage(id string) (string, error) {
+	var language string
+	err := s.db.QueryRow(`SELECT COALESCE(language, '') FROM research_sessions WHERE id = ?`, id).Scan(&language)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("get session language: %w", err)
+	}
+	return language, nil
+}
+
 func (s *SQLiteStore) GetSessionArtifacts(id string) (string, string, error) {
 	query := `SELECT COALESCE(report_md_key, ''), COALESCE(report_json_key, '') FROM research_sessions WHERE id = ?`
 	var md, json string
@@ -301,7 +450,7 @@ func (s *SQLiteStore) DeleteSession(id string) error {
 	}(tx)
 
 	// Delete from child tables (though CASCADE would be better if we had it in schema)
-	tables := []string{"key_findings", "open_questions", "sources"}
+	tables := []string{"key_findings", "open_questions", "sources", "checkpoints", "token_usage", "prompt_versions", "session_renders"}
 	for _, table := range tables {
 		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE session_id = ?", table), id); err != nil {
 			return fmt.Errorf("delete from %s: %w", table, err)
@@ -315,3 +464,275 @@ func (s *SQLiteStore) DeleteSession(id string) error {
 
 	return tx.Commit()
 }
+
+func (s *SQLiteStore) SaveCheckpoint(sessionID, stage string, state []byte) error {
+	query := `INSERT INTO checkpoints (session_id, stage, state) VALUES (?, ?, ?)
+              ON CONFLICT(session_id) DO UPDATE SET stage = excluded.stage, state = excluded.state, updated_at = CURRENT_TIMESTAMP`
+	if _, err := s.db.Exec(query, sessionID, stage, string(state)); err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) LoadCheckpoint(sessionID string) (string, []byte, error) {
+	query := `SELECT stage, state FROM checkpoints WHERE session_id = ?`
+	var stage, state string
+	err := s.db.QueryRow(query, sessionID).Scan(&stage, &state)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, nil
+		}
+		return "", nil, fmt.Errorf("load checkpoint: %w", err)
+	}
+	return stage, []byte(state), nil
+}
+
+func (s *SQLiteStore) DeleteCheckpoint(sessionID string) error {
+	if _, err := s.db.Exec(`DELETE FROM checkpoints WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListUnfinishedSessions() ([]SessionRecord, error) {
+	rows, err := s.db.Query(
+		`SELECT id, topic, status FROM research_sessions WHERE status NOT IN ('complete', 'failed', 'canceled') ORDER BY created_at, rowid`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query unfinished sessions: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+
+		}
+	}(rows)
+
+	var sessions []SessionRecord
+	for rows.Next() {
+		var r SessionRecord
+		if err := rows.Scan(&r.ID, &r.Topic, &r.Status); err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, r)
+	}
+	return sessions, rows.Err()
+}
+
+// SaveTokenUsage replaces the per-stage LLM token usage of the given session.
+func (s *SQLiteStore) SaveTokenUsage(sessionID string, usage []event.TokenUsage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func(tx *sql.Tx) {
+		err := tx.Rollback()
+		if err != nil {
+
+		}
+	}(tx)
+
+	if _, err := tx.Exec(`DELETE FROM token_usage WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("clear token usage: %w", err)
+	}
+	stmt, err := tx.Prepare(`INSERT INTO token_usage (session_id, stage, calls, prompt_tokens, completion_tokens, total_tokens, cost_usd, estimated) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer func(stmt *sql.Stmt) {
+		err := stmt.Close()
+		if err != nil {
+
+		}
+	}(stmt)
+
+	for _, u := range usage {
+		if _, err := stmt.Exec(sessionID, u.Stage, u.Calls, u.PromptTokens, u.CompletionTokens, u.TotalTokens, u.CostUSD, u.Estimated); err != nil {
+			return fmt.Errorf("insert token usage: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SavePromptVersions replaces the prompt template versions of the given session.
+func (s *SQLiteStore) SavePromptVersions(sessionID string, versions map[string]string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func(tx *sql.Tx) {
+		err := tx.Rollback()
+		if err != nil {
+
+		}
+	}(tx)
+
+	if _, err := tx.Exec(`DELETE FROM prompt_versions WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("clear prompt versions: %w", err)
+	}
+	for name, version := range versions {
+		if _, err := tx.Exec(`INSERT INTO prompt_versions (session_id, name, version) VALUES (?, ?, ?)`, sessionID, name, version); err != nil {
+			return fmt.Errorf("insert prompt version: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPromptVersions retrieves the prompt template versions of the given
+// session, keyed by template name.
+func (s *SQLiteStore) GetPromptVersions(sessionID string) (map[string]string, error) {
+	rows, err := s.db.Query(`SELECT name, version FROM prompt_versions WHERE session_id = ?`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query prompt_versions: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+
+		}
+	}(rows)
+
+	versions := make(map[string]string)
+	for rows.Next() {
+		var name, version string
+		if err := rows.Scan(&name, &version); err != nil {
+			return nil, fmt.Errorf("scan prompt version: %w", err)
+		}
+		versions[name] = version
+	}
+	return versions, rows.Err()
+}
+
+func (s *SQLiteStore) SaveRenders(sessionID string, renders map[string]string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func(tx *sql.Tx) {
+		err := tx.Rollback()
+		if err != nil {
+
+		}
+	}(tx)
+
+	if _, err := tx.Exec(`DELETE FROM session_renders WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("clear session renders: %w", err)
+	}
+	for format, key := range renders {
+		if _, err := tx.Exec(`INSERT INTO session_renders (session_id, format, blob_key) VALUES (?, ?, ?)`, sessionID, format, key); err != nil {
+			return fmt.Errorf("insert session render: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetSessionRenders retrieves the blob keys of the session's rendered
+// reports, keyed by renderer name.
+func (s *SQLiteStore) GetSessionRenders(sessionID string) (map[string]string, error) {
+	rows, err := s.db.Query(`SELECT format, blob_key FROM session_renders WHERE session_id = ?`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("query session_renders: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+
+		}
+	}(rows)
+
+	renders := make(map[string]string)
+	for rows.Next() {
+		var format, key string
+		if err := rows.Scan(&format, &key); err != nil {
+			return nil, fmt.Errorf("scan session render: %w", err)
+		}
+		renders[format] = key
+	}
+	return renders, rows.Err()
+}
+
+// GetTokenUsage retrieves the per-stage LLM token usage of the given session.
+func (s *SQLiteStore) GetTokenUsage(sessionID string) ([]event.TokenUsage, error) {
+	rows, err := s.db.Query(
+		`SELECT stage, calls, prompt_tokens, completion_tokens, total_tokens, cost_usd, estimated FROM token_usage WHERE session_id = ? ORDER BY rowid`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query token_usage: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+
+		}
+	}(rows)
+
+	var usage []event.TokenUsage
+	for rows.Next() {
+		var u event.TokenUsage
+		if err := rows.Scan(&u.Stage, &u.Calls, &u.PromptTokens, &u.CompletionTokens, &u.TotalTokens, &u.CostUSD, &u.Estimated); err != nil {
+			return nil, fmt.Errorf("scan token usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// GetCachedSearch returns the search results cached under key. Entries stored
+// longer than maxAge ago are misses. A hit marks the entry as recently used,
+// which protects it from eviction.
+func (s *SQLiteStore) GetCachedSearch(key string, maxAge time.Duration) ([]byte, bool, error) {
+	now := time.Now()
+	var results string
+	err := s.db.QueryRow(
+		`SELECT results FROM search_cache WHERE key = ? AND stored_at > ?`,
+		key, now.Add(-maxAge).UnixNano(),
+	).Scan(&results)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("query search cache: %w", err)
+	}
+	if _, err := s.db.Exec(`UPDATE search_cache SET used_at = ? WHERE key = ?`, now.UnixNano(), key); err != nil {
+		return nil, false, fmt.Errorf("touch search cache: %w", err)
+	}
+	return []byte(results), true, nil
+}
+
+// PutCachedSearch stores search results under key, replacing any earlier
+// entry. When maxEntries is positive, the least recently used entries beyond
+// it are evicted.
+func (s *SQLiteStore) PutCachedSearch(key string, results []byte, maxEntries int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func(tx *sql.Tx) {
+		err := tx.Rollback()
+		if err != nil {
+
+		}
+	}(tx)
+
+	now := time.Now().UnixNano()
+	if _, err := tx.Exec(
+		`INSERT INTO search_cache (key, results, stored_at, used_at) VALUES (?, ?, ?, ?)
+         ON CONFLICT(key) DO UPDATE SET results = excluded.results, stored_at = excluded.stored_at, used_at = excluded.used_at`,
+		key, string(results), now, now,
+	); err != nil {
+		return fmt.Errorf("insert search cache: %w", err)
+	}
+	if maxEntries > 0 {
+		if _, err := tx.Exec(
+			`DELETE FROM search_cache WHERE key NOT IN (SELECT key FROM search_cache ORDER BY used_at DESC LIMIT ?)`,
+			maxEntries,
+		); err != nil {
+			return fmt.Errorf("evict search cache: %w", err)
+		}
+	}
+	return tx.Commit()
+}
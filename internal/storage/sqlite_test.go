@@ -1,7 +1,10 @@
 package storage_test
 
 import (
+	"database/sql"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/user/research-assistant/internal/event"
 	"github.com/user/research-assistant/internal/storage"
@@ -27,7 +30,7 @@ func TestSQLiteStore_GetKeyFindings(t *testing.T) {
 	}
 
 	findings := []event.StructuredFinding{
-		{Finding: "F1", EvidenceURLs: []string{"http://a.com"}, Confidence: 0.9},
+		{Finding: "F1", EvidenceURLs: []string{"http://a.com"}, Confidence: 0.9, Verdict: "supported", VerdictReason: "stated on a.com"},
 		{Finding: "F2", EvidenceURLs: nil, Confidence: 0.5},
 	}
 	if err := s.SaveFindings(sessionID, findings); err != nil {
@@ -47,6 +50,9 @@ func TestSQLiteStore_GetKeyFindings(t *testing.T) {
 	if got[1].Confidence != 0.5 {
 		t.Errorf("finding[1] confidence: want 0.5, got %v", got[1].Confidence)
 	}
+	if got[0].Verdict != "supported" || got[0].VerdictReason != "stated on a.com" || got[1].Verdict != "" {
+		t.Errorf("verdicts not round-tripped: %+v", got)
+	}
 }
 
 func TestSQLiteStore_GetKeyFindings_Empty(t *testing.T) {
@@ -74,8 +80,8 @@ func TestSQLiteStore_GetSources(t *testing.T) {
 	}
 
 	sources := []event.SearchSource{
-		{Query: "q1", URL: "http://a.com", Snippet: "snippet a"},
-		{Query: "q2", URL: "http://b.com", Snippet: "snippet b"},
+		{Query: "q1", Queries: []string{"q1", "q4"}, URL: "http://a.com", Snippet: "snippet a", Title: "A", Rank: 1, Provider: "google_cse", Credibility: 0.75, Published: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+		{Query: "q2", URL: "http://b.com", Snippet: "snippet b", Cached: true},
 		{Query: "q3", URL: "http://c.com", Snippet: ""},
 	}
 	if err := s.SaveSources(sessionID, sources); err != nil {
@@ -92,6 +98,12 @@ func TestSQLiteStore_GetSources(t *testing.T) {
 	if got[0].URL != "http://a.com" {
 		t.Errorf("source[0].URL: want %q, got %q", "http://a.com", got[0].URL)
 	}
+	if got[0].Title != "A" || got[0].Rank != 1 || got[0].Provider != "google_cse" || len(got[0].Queries) != 2 || got[0].Credibility != 0.75 || !got[0].Published.Equal(sources[0].Published) || !got[1].Published.IsZero() {
+		t.Errorf("source[0] details not round-tripped: %+v", got[0])
+	}
+	if got[0].Cached || !got[1].Cached {
+		t.Errorf("cache flags not round-tripped: %v, %v", got[0].Cached, got[1].Cached)
+	}
 	if got[2].Snippet != "" {
 		t.Errorf("source[2].Snippet: want empty, got %q", got[2].Snippet)
 	}
@@ -130,3 +142,231 @@ func TestSQLiteStore_DeleteSession(t *testing.T) {
 		t.Errorf("expected findings to be deleted, but got %d", len(findings))
 	}
 }
+
+// TestNewSQLiteStore_MigratesExistingDatabase verifies that a database created
+// before the source detail and verdict columns existed is upgraded on open,
+// and that reopening an up-to-date database is harmless.
+func TestNewSQLiteStore_MigratesExistingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := raw.Exec(`CREATE TABLE sources (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id TEXT NOT NULL,
+		query TEXT NOT NULL,
+		url TEXT NOT NULL,
+		snippet TEXT
+	)`); err != nil {
+		t.Fatalf("create legacy table: %v", err)
+	}
+	if _, err := raw.Exec(`CREATE TABLE key_findings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id TEXT NOT NULL,
+		finding TEXT NOT NULL,
+		confidence REAL NOT NULL
+	)`); err != nil {
+		t.Fatalf("create legacy findings table: %v", err)
+	}
+	_ = raw.Close()
+
+	for i := 0; i < 2; i++ {
+		s, err := storage.NewSQLiteStore(path)
+		if err != nil {
+			t.Fatalf("NewSQLiteStore (open %d): %v", i+1, err)
+		}
+		if err := s.SaveSources("s1", []event.SearchSource{{Query: "q", URL: "http://a.com", Title: "A", Rank: 2}}); err != nil {
+			t.Fatalf("SaveSources after migration: %v", err)
+		}
+		if err := s.SaveFindings("s1", []event.StructuredFinding{{Finding: "F", Confidence: 0.2, Verdict: "unsupported"}}); err != nil {
+			t.Fatalf("SaveFindings after migration: %v", err)
+		}
+		_ = s.Close()
+	}
+}
+
+func TestSQLiteStore_Checkpoints(t *testing.T) {
+	s := newTestStore(t)
+	for _, id := range []string{"done", "running", "queued"} {
+		if err := s.CreateSession(id, "Topic "+id); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+	}
+	if err := s.MarkSessionComplete("done", "md", "json", "summary"); err != nil {
+		t.Fatalf("MarkSessionComplete: %v", err)
+	}
+	if err := s.UpdateSessionStatus("running", "structuring", ""); err != nil {
+		t.Fatalf("UpdateSessionStatus: %v", err)
+	}
+
+	if err := s.SaveCheckpoint("running", "search", []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("SaveCheckpoint: %v", err)
+	}
+	if err := s.SaveCheckpoint("running", "fetch", []byte(`{"a":2}`)); err != nil {
+		t.Fatalf("SaveCheckpoint (replace): %v", err)
+	}
+	stage, state, err := s.LoadCheckpoint("running")
+	if err != nil {
+		t.Fatalf("LoadCheckpoint: %v", err)
+	}
+	if stage != "fetch" || string(state) != `{"a":2}` {
+		t.Errorf("expected latest checkpoint, got %q %s", stage, state)
+	}
+
+	unfinished, err := s.ListUnfinishedSessions()
+	if err != nil {
+		t.Fatalf("ListUnfinishedSessions: %v", err)
+	}
+	if len(unfinished) != 2 || unfinished[0].ID != "running" || unfinished[0].Status != "structuring" || unfinished[1].ID != "queued" {
+		t.Errorf("unexpected unfinished sessions: %+v", unfinished)
+	}
+
+	if err := s.DeleteCheckpoint("running"); err != nil {
+		t.Fatalf("DeleteCheckpoint: %v", err)
+	}
+	if stage, _, err := s.LoadCheckpoint("running"); err != nil || stage != "" {
+		t.Errorf("expected no checkpoint after delete, got %q, %v", stage, err)
+	}
+}
+
+func TestSQLiteStore_TokenUsage(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.CreateSession("s1", "Topic"); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	if err := s.SaveTokenUsage("s1", []event.TokenUsage{{Stage: "queries", Calls: 1, PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}); err != nil {
+		t.Fatalf("SaveTokenUsage: %v", err)
+	}
+	usage := []event.TokenUsage{
+		{Stage: "queries", Calls: 1, PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, CostUSD: 0.25},
+		{Stage: "report", Calls: 2, PromptTokens: 300, CompletionTokens: 100, TotalTokens: 400, Estimated: true},
+	}
+	if err := s.SaveTokenUsage("s1", usage); err != nil {
+		t.Fatalf("SaveTokenUsage (replace): %v", err)
+	}
+
+	got, err := s.GetTokenUsage("s1")
+	if err != nil {
+		t.Fatalf("GetTokenUsage: %v", err)
+	}
+	if len(got) != 2 || got[0] != usage[0] || got[1] != usage[1] {
+		t.Errorf("expected %+v, got %+v", usage, got)
+	}
+
+	if err := s.DeleteSession("s1"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if got, _ := s.GetTokenUsage("s1"); len(got) != 0 {
+		t.Errorf("expected token usage to be deleted with the session, got %+v", got)
+	}
+}
+
+func TestSQLiteStore_PromptVersions(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.CreateSession("s1", "Topic"); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	if err := s.SavePromptVersions("s1", map[string]string{"report": "v1", "summary": "v1"}); err != nil {
+		t.Fatalf("SavePromptVersions: %v", err)
+	}
+	if err := s.SavePromptVersions("s1", map[string]string{"report": "v2"}); err != nil {
+		t.Fatalf("SavePromptVersions (replace): %v", err)
+	}
+	got, err := s.GetPromptVersions("s1")
+	if err != nil {
+		t.Fatalf("GetPromptVersions: %v", err)
+	}
+	if len(got) != 1 || got["report"] != "v2" {
+		t.Errorf("expected {report: v2}, got %v", got)
+	}
+
+	if err := s.DeleteSession("s1"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if got, _ := s.GetPromptVersions("s1"); len(got) != 0 {
+		t.Errorf("expected prompt versions to be deleted with the session, got %v", got)
+	}
+}
+
+func TestSQLiteStore_SessionLanguage(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.CreateSession("s1", "Topic"); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if got, err := s.GetSessionLanguage("s1"); err != nil || got != "" {
+		t.Errorf("expected no language before it is set, got %q, %v", got, err)
+	}
+	if err := s.SetSessionLanguage("s1", "de"); err != nil {
+		t.Fatalf("SetSessionLanguage: %v", err)
+	}
+	if got, err := s.GetSessionLanguage("s1"); err != nil || got != "de" {
+		t.Errorf("expected language de, got %q, %v", got, err)
+	}
+}
+
+func TestSQLiteStore_SessionRenders(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.CreateSession("s1", "Topic"); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	if err := s.SaveRenders("s1", map[string]string{"html": "report_1.html", "epub": "report_2.epub"}); err != nil {
+		t.Fatalf("SaveRenders: %v", err)
+	}
+	got, err := s.GetSessionRenders("s1")
+	if err != nil {
+		t.Fatalf("GetSessionRenders: %v", err)
+	}
+	if len(got) != 2 || got["html"] != "report_1.html" || got["epub"] != "report_2.epub" {
+		t.Errorf("unexpected renders: %v", got)
+	}
+
+	if err := s.DeleteSession("s1"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if got, _ := s.GetSessionRenders("s1"); len(got) != 0 {
+		t.Errorf("expected renders to be deleted with the session, got %v", got)
+	}
+}
+
+func TestSQLiteStore_SearchCache(t *testing.T) {
+	s := newTestStore(t)
+
+	if _, ok, err := s.GetCachedSearch("k1", time.Hour); err != nil || ok {
+		t.Fatalf("expected a miss on an empty cache, got %v, %v", ok, err)
+	}
+	if err := s.PutCachedSearch("k1", []byte(`[{"URL":"http://a.com"}]`), 0); err != nil {
+		t.Fatalf("PutCachedSearch: %v", err)
+	}
+	got, ok, err := s.GetCachedSearch("k1", time.Hour)
+	if err != nil || !ok || string(got) != `[{"URL":"http://a.com"}]` {
+		t.Fatalf("expected a hit, got %q, %v, %v", got, ok, err)
+	}
+	if _, ok, _ := s.GetCachedSearch("k1", 0); ok {
+		t.Error("expected entries older than the TTL to miss")
+	}
+}
+
+func TestSQLiteStore_SearchCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	s := newTestStore(t)
+	for _, key := range []string{"k1", "k2"} {
+		if err := s.PutCachedSearch(key, []byte("[]"), 2); err != nil {
+			t.Fatalf("PutCachedSearch(%s): %v", key, err)
+		}
+	}
+	// A hit on k1 makes k2 the least recently used entry.
+	if _, ok, _ := s.GetCachedSearch("k1", time.Hour); !ok {
+		t.Fatal("expected k1 to be cached")
+	}
+	if err := s.PutCachedSearch("k3", []byte("[]"), 2); err != nil {
+		t.Fatalf("PutCachedSearch(k3): %v", err)
+	}
+	for key, want := range map[string]bool{"k1": true, "k2": false, "k3": true} {
+		if _, ok, _ := s.GetCachedSearch(key, time.Hour); ok != want {
+			t.Errorf("%s cached: want %v, got %v", key, want, ok)
+		}
+	}
+}